@@ -14,6 +14,7 @@ require (
 	github.com/golang-migrate/migrate/v4 v4.17.0
 	golang.org/x/crypto v0.17.0
 	github.com/google/uuid v1.4.0
+	golang.org/x/image v0.18.0
 )
 
 require (
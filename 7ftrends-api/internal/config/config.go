@@ -10,13 +10,17 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Supabase SupabaseConfig `mapstructure:"supabase"`
-	Auth     AuthConfig     `mapstructure:"auth"`
-	Storage  StorageConfig  `mapstructure:"storage"`
-	AI       AIConfig       `mapstructure:"ai"`
-	Logger   LoggerConfig   `mapstructure:"logger"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Supabase   SupabaseConfig   `mapstructure:"supabase"`
+	Auth       AuthConfig       `mapstructure:"auth"`
+	Storage    StorageConfig    `mapstructure:"storage"`
+	AI         AIConfig         `mapstructure:"ai"`
+	Share      ShareConfig      `mapstructure:"share"`
+	Retention  RetentionConfig  `mapstructure:"retention"`
+	Provenance ProvenanceConfig `mapstructure:"provenance"`
+	Preflight  PreflightConfig  `mapstructure:"preflight"`
+	Logger     LoggerConfig     `mapstructure:"logger"`
 }
 
 // ServerConfig holds server configuration
@@ -60,13 +64,88 @@ type StorageConfig struct {
 	AllowedTypes    []string `mapstructure:"allowed_types"`
 	BucketName      string   `mapstructure:"bucket_name"`
 	CompressionQuality int   `mapstructure:"compression_quality"`
+	AllowedHosts    []string `mapstructure:"allowed_hosts"`
+	FetchTimeout    int      `mapstructure:"fetch_timeout"`
 }
 
 // AIConfig holds AI service configuration
 type AIConfig struct {
-	GeminiAPIKey string `mapstructure:"gemini_api_key"`
-	Endpoint     string `mapstructure:"endpoint"`
-	Timeout      int    `mapstructure:"timeout"`
+	Provider      string  `mapstructure:"provider"`
+	Model         string  `mapstructure:"model"`
+	GeminiAPIKey  string  `mapstructure:"gemini_api_key"`
+	Endpoint      string  `mapstructure:"endpoint"`
+	Timeout       int     `mapstructure:"timeout"`
+	Workers       int     `mapstructure:"workers"`
+	QueueSize     int     `mapstructure:"queue_size"`
+	JobTimeout    int     `mapstructure:"job_timeout"`
+	BatchWorkers  int     `mapstructure:"batch_workers"`
+	MinConfidence float64 `mapstructure:"min_confidence"`
+	// Retries for transient provider errors; delays are in milliseconds
+	MaxRetries     int `mapstructure:"max_retries"`
+	RetryBaseDelay int `mapstructure:"retry_base_delay"`
+	RetryMaxDelay  int `mapstructure:"retry_max_delay"`
+	// Consecutive failures that open the circuit, and seconds before a trial call
+	BreakerThreshold int `mapstructure:"breaker_threshold"`
+	BreakerCooldown  int `mapstructure:"breaker_cooldown"`
+	// MonthlyQuotas limits try-ons per calendar month by user role; -1 means unlimited
+	MonthlyQuotas map[string]int `mapstructure:"monthly_quotas"`
+	Prompts       PromptConfig   `mapstructure:"prompts"`
+	Tagging       TaggingConfig  `mapstructure:"tagging"`
+}
+
+// TaggingConfig controls background attribute extraction for wardrobe items.
+// The Gemini provider uses the AI API key, endpoint and timeout.
+type TaggingConfig struct {
+	Provider     string `mapstructure:"provider"` // gemini or stub; empty disables tagging
+	Model        string `mapstructure:"model"`
+	Workers      int    `mapstructure:"workers"`
+	BatchSize    int    `mapstructure:"batch_size"`    // items claimed per queue poll
+	PollInterval int    `mapstructure:"poll_interval"` // seconds between queue polls
+	MaxAttempts  int    `mapstructure:"max_attempts"`  // before an item is marked failed
+	JobTimeout   int    `mapstructure:"job_timeout"`   // seconds per item, after which it can be claimed again
+}
+
+// PromptConfig selects the try-on prompt templates. Each variant is read from
+// {dir}/tryon-{version}.tmpl and picked for a request in proportion to its weight.
+type PromptConfig struct {
+	Dir      string          `mapstructure:"dir"`
+	Variants []PromptVariant `mapstructure:"variants"`
+}
+
+// PromptVariant is one versioned prompt template and its share of traffic
+type PromptVariant struct {
+	Version string `mapstructure:"version"`
+	Weight  int    `mapstructure:"weight"`
+}
+
+// ShareConfig holds settings for public try-on share links
+type ShareConfig struct {
+	Secret  string `mapstructure:"secret"`   // HMAC key for share tokens
+	TTL     int    `mapstructure:"ttl"`      // default link lifetime in seconds
+	MaxTTL  int    `mapstructure:"max_ttl"`  // longest lifetime a user may request, in seconds
+	BaseURL string `mapstructure:"base_url"` // public API base, including any route prefix, for share URLs
+}
+
+// RetentionConfig controls the job that deletes old try-on results and their files
+type RetentionConfig struct {
+	Days      int  `mapstructure:"days"`       // age after which results are deleted; 0 disables the job
+	Interval  int  `mapstructure:"interval"`   // seconds between runs
+	BatchSize int  `mapstructure:"batch_size"` // rows listed per query
+	DryRun    bool `mapstructure:"dry_run"`    // only log what would be deleted
+}
+
+// ProvenanceConfig controls how composites are marked as AI-generated
+type ProvenanceConfig struct {
+	Watermark     bool   `mapstructure:"watermark"`      // stamp a visible label on composites
+	WatermarkText string `mapstructure:"watermark_text"` // label text
+	Secret        string `mapstructure:"secret"`         // HMAC key for sidecar manifests
+}
+
+// PreflightConfig sets the checks input images must pass before a try-on is generated.
+// The file size limit is storage.max_file_size.
+type PreflightConfig struct {
+	MinImageResolution int     `mapstructure:"min_image_resolution"` // minimum shortest side in pixels
+	MaxAspectRatio     float64 `mapstructure:"max_aspect_ratio"`     // maximum longest over shortest side
 }
 
 // LoggerConfig holds logger configuration
@@ -141,9 +220,60 @@ func setDefaults() {
 	viper.SetDefault("storage.max_file_size", 5242880) // 5MB
 	viper.SetDefault("storage.allowed_types", []string{"image/jpeg", "image/png", "image/webp"})
 	viper.SetDefault("storage.compression_quality", 80)
+	viper.SetDefault("storage.allowed_hosts", []string{})
+	viper.SetDefault("storage.fetch_timeout", 10)
 
 	// AI defaults
+	viper.SetDefault("ai.provider", "gemini")
+	viper.SetDefault("ai.model", "gemini-2.5-flash-image")
+	viper.SetDefault("ai.endpoint", "https://generativelanguage.googleapis.com/v1beta")
 	viper.SetDefault("ai.timeout", 30)
+	viper.SetDefault("ai.workers", 4)
+	viper.SetDefault("ai.queue_size", 100)
+	viper.SetDefault("ai.job_timeout", 120)
+	viper.SetDefault("ai.batch_workers", 3)
+	viper.SetDefault("ai.min_confidence", 0.5)
+	viper.SetDefault("ai.max_retries", 3)
+	viper.SetDefault("ai.retry_base_delay", 500)
+	viper.SetDefault("ai.retry_max_delay", 8000)
+	viper.SetDefault("ai.breaker_threshold", 5)
+	viper.SetDefault("ai.breaker_cooldown", 30)
+	viper.SetDefault("ai.monthly_quotas", map[string]int{
+		"user":      20,
+		"premium":   200,
+		"moderator": 200,
+		"admin":     -1,
+	})
+	viper.SetDefault("ai.prompts.dir", "./configs/prompts")
+	viper.SetDefault("ai.prompts.variants", []map[string]interface{}{
+		{"version": "v2", "weight": 100},
+	})
+	viper.SetDefault("ai.tagging.provider", "gemini")
+	viper.SetDefault("ai.tagging.model", "gemini-2.5-flash")
+	viper.SetDefault("ai.tagging.workers", 2)
+	viper.SetDefault("ai.tagging.batch_size", 10)
+	viper.SetDefault("ai.tagging.poll_interval", 30)
+	viper.SetDefault("ai.tagging.max_attempts", 5)
+	viper.SetDefault("ai.tagging.job_timeout", 60)
+
+	// Share defaults
+	viper.SetDefault("share.ttl", 604800)      // 7 days
+	viper.SetDefault("share.max_ttl", 2592000) // 30 days
+	viper.SetDefault("share.base_url", "")
+
+	// Retention defaults
+	viper.SetDefault("retention.days", 90)
+	viper.SetDefault("retention.interval", 3600) // 1 hour
+	viper.SetDefault("retention.batch_size", 500)
+	viper.SetDefault("retention.dry_run", false)
+
+	// Provenance defaults
+	viper.SetDefault("provenance.watermark", true)
+	viper.SetDefault("provenance.watermark_text", "AI generated")
+
+	// Preflight defaults
+	viper.SetDefault("preflight.min_image_resolution", 256)
+	viper.SetDefault("preflight.max_aspect_ratio", 3.0)
 
 	// Logger defaults
 	viper.SetDefault("logger.level", "info")
@@ -187,6 +317,19 @@ func overrideWithEnv(config *Config) {
 	if geminiKey := os.Getenv("GEMINI_API_KEY"); geminiKey != "" {
 		config.AI.GeminiAPIKey = geminiKey
 	}
+	if provider := os.Getenv("AI_PROVIDER"); provider != "" {
+		config.AI.Provider = provider
+	}
+
+	// Share link signing secret
+	if shareSecret := os.Getenv("SHARE_SECRET"); shareSecret != "" {
+		config.Share.Secret = shareSecret
+	}
+
+	// Provenance manifest signing secret
+	if provenanceSecret := os.Getenv("PROVENANCE_SECRET"); provenanceSecret != "" {
+		config.Provenance.Secret = provenanceSecret
+	}
 
 	// File size
 	if maxSize := os.Getenv("MAX_FILE_SIZE"); maxSize != "" {
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrTxUnsupported is returned by ExecTx when the queries are bound to a
+// connection that cannot start transactions
+var ErrTxUnsupported = errors.New("database connection does not support transactions")
+
+// TxBeginner is a connection that can start a transaction, such as *pgxpool.Pool.
+// A pgx.Tx also qualifies and starts a savepoint.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// ExecTx runs fn with queries bound to a new transaction. The transaction is
+// committed when fn returns nil and rolled back otherwise.
+func (q *Queries) ExecTx(ctx context.Context, fn func(*Queries) error) error {
+	beginner, ok := q.db.(TxBeginner)
+	if !ok {
+		return ErrTxUnsupported
+	}
+
+	tx, err := beginner.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback(ctx)
+
+	if err := fn(New(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
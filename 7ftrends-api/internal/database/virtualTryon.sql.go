@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -21,14 +22,53 @@ CREATE TABLE IF NOT EXISTS virtual_tryon_history (
   confidence FLOAT,
   status TEXT NOT NULL DEFAULT 'pending',
   processing_time BIGINT,
+  error_message TEXT,
+  input_width INT,
+  input_height INT,
+  output_width INT,
+  output_height INT,
+  output_mime_type TEXT,
+  wardrobe_item_ids JSONB NOT NULL DEFAULT '[]',
+  input_hash TEXT,
+  error_detail TEXT,
+  prompt_version TEXT,
+  batch_id UUID,
+  settings JSONB,
+  composite_sha256 TEXT,
+  base_photo_id UUID,
+  cached BOOLEAN NOT NULL DEFAULT FALSE,
+  preflight_issues JSONB,
   created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
   updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- Columns added after the initial release
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS error_message TEXT;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS input_width INT;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS input_height INT;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS output_width INT;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS output_height INT;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS output_mime_type TEXT;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS wardrobe_item_ids JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS input_hash TEXT;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS error_detail TEXT;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS prompt_version TEXT;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS batch_id UUID;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS settings JSONB;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS composite_sha256 TEXT;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS base_photo_id UUID;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS cached BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE virtual_tryon_history ADD COLUMN IF NOT EXISTS preflight_issues JSONB;
+
 -- Indexes for performance
 CREATE INDEX IF NOT EXISTS idx_virtual_tryon_history_user_id ON virtual_tryon_history(user_id);
 CREATE INDEX IF NOT EXISTS idx_virtual_tryon_history_status ON virtual_tryon_history(status);
 CREATE INDEX IF NOT EXISTS idx_virtual_tryon_history_created_at ON virtual_tryon_history(created_at);
+CREATE INDEX IF NOT EXISTS idx_virtual_tryon_history_input_hash ON virtual_tryon_history(user_id, input_hash);
+CREATE INDEX IF NOT EXISTS idx_virtual_tryon_history_batch_id ON virtual_tryon_history(batch_id);
+CREATE INDEX IF NOT EXISTS idx_virtual_tryon_history_base_photo_id ON virtual_tryon_history(base_photo_id);
+CREATE INDEX IF NOT EXISTS idx_virtual_tryon_history_composite_sha256 ON virtual_tryon_history(composite_sha256);
+CREATE INDEX IF NOT EXISTS idx_virtual_tryon_history_user_cursor ON virtual_tryon_history(user_id, created_at DESC, id DESC);
 
 -- RLS policies
 ALTER TABLE virtual_tryon_history ENABLE ROW LEVEL SECURITY;
@@ -59,14 +99,19 @@ const createVirtualTryonHistory = `-- name: CreateVirtualTryonHistory :one
 INSERT INTO virtual_tryon_history (
   id, user_id, user_image_url, garment_image_url, composite_image_url,
   instructions, position, fit, style, confidence, status,
-  processing_time, created_at
+  processing_time, created_at, wardrobe_item_ids, input_hash, prompt_version, batch_id,
+  settings, base_photo_id
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, COALESCE($14, '[]'::jsonb), $15, $16, $17,
+  $18, $19
 )
 RETURNING
   id, user_id, user_image_url, garment_image_url, composite_image_url,
   instructions, position, fit, style, confidence, status,
-  processing_time, created_at, updated_at
+  processing_time, error_message,
+  input_width, input_height, output_width, output_height, output_mime_type,
+  wardrobe_item_ids, input_hash, error_detail, prompt_version, batch_id, settings,
+  base_photo_id, cached, preflight_issues, created_at, updated_at
 `
 
 type CreateVirtualTryonHistoryParams struct {
@@ -83,6 +128,12 @@ type CreateVirtualTryonHistoryParams struct {
 	Status            string
 	ProcessingTime    pgtype.Int8
 	CreatedAt         time.Time
+	WardrobeItemIds   []byte
+	InputHash         pgtype.Text
+	PromptVersion     pgtype.Text
+	BatchID           pgtype.UUID
+	Settings          []byte
+	BasePhotoID       pgtype.UUID
 }
 
 func (q *Queries) CreateVirtualTryonHistory(ctx context.Context, arg CreateVirtualTryonHistoryParams) (CreateVirtualTryonHistoryRow, error) {
@@ -100,6 +151,12 @@ func (q *Queries) CreateVirtualTryonHistory(ctx context.Context, arg CreateVirtu
 		arg.Status,
 		arg.ProcessingTime,
 		arg.CreatedAt,
+		arg.WardrobeItemIds,
+		arg.InputHash,
+		arg.PromptVersion,
+		arg.BatchID,
+		arg.Settings,
+		arg.BasePhotoID,
 	)
 	var i CreateVirtualTryonHistoryRow
 	err := row.Scan(
@@ -115,31 +172,76 @@ func (q *Queries) CreateVirtualTryonHistory(ctx context.Context, arg CreateVirtu
 		&i.Confidence,
 		&i.Status,
 		&i.ProcessingTime,
+		&i.ErrorMessage,
+		&i.InputWidth,
+		&i.InputHeight,
+		&i.OutputWidth,
+		&i.OutputHeight,
+		&i.OutputMimeType,
+		&i.WardrobeItemIds,
+		&i.InputHash,
+		&i.ErrorDetail,
+		&i.PromptVersion,
+		&i.BatchID,
+		&i.Settings,
+		&i.BasePhotoID,
+		&i.Cached,
+		&i.PreflightIssues,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+// Filters are skipped when NULL. Rows are keyset-paginated on (created_at, id)
+// so new try-ons don't shift later pages.
 const getVirtualTryonHistory = `-- name: GetVirtualTryonHistory :many
 SELECT
   id, user_id, user_image_url, garment_image_url, composite_image_url,
   instructions, position, fit, style, confidence, status,
-  processing_time, created_at, updated_at
+  processing_time, error_message,
+  input_width, input_height, output_width, output_height, output_mime_type,
+  wardrobe_item_ids, input_hash, error_detail, prompt_version, batch_id, settings,
+  base_photo_id, cached, preflight_issues, created_at, updated_at
 FROM virtual_tryon_history
 WHERE user_id = $1
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+  AND ($2::text IS NULL OR status = $2)
+  AND ($3::text IS NULL OR style = $3)
+  AND ($4::text IS NULL OR position = $4)
+  AND ($5::timestamptz IS NULL OR created_at >= $5)
+  AND ($6::timestamptz IS NULL OR created_at < $6)
+  AND ($7::float8 IS NULL OR confidence >= $7)
+  AND ($8::timestamptz IS NULL OR (created_at, id) < ($8, $9::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $10
 `
 
 type GetVirtualTryonHistoryParams struct {
-	UserID uuid.UUID
-	Limit  int32
-	Offset int32
+	UserID          uuid.UUID
+	Status          pgtype.Text
+	Style           pgtype.Text
+	Position        pgtype.Text
+	CreatedFrom     pgtype.Timestamptz
+	CreatedTo       pgtype.Timestamptz
+	MinConfidence   pgtype.Float8
+	CursorCreatedAt pgtype.Timestamptz
+	CursorID        pgtype.UUID
+	Limit           int32
 }
 
 func (q *Queries) GetVirtualTryonHistory(ctx context.Context, arg GetVirtualTryonHistoryParams) ([]GetVirtualTryonHistoryRow, error) {
-	rows, err := q.db.Query(ctx, getVirtualTryonHistory, arg.UserID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, getVirtualTryonHistory,
+		arg.UserID,
+		arg.Status,
+		arg.Style,
+		arg.Position,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.MinConfidence,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -160,6 +262,21 @@ func (q *Queries) GetVirtualTryonHistory(ctx context.Context, arg GetVirtualTryo
 			&i.Confidence,
 			&i.Status,
 			&i.ProcessingTime,
+			&i.ErrorMessage,
+			&i.InputWidth,
+			&i.InputHeight,
+			&i.OutputWidth,
+			&i.OutputHeight,
+			&i.OutputMimeType,
+			&i.WardrobeItemIds,
+			&i.InputHash,
+			&i.ErrorDetail,
+			&i.PromptVersion,
+			&i.BatchID,
+			&i.Settings,
+			&i.BasePhotoID,
+			&i.Cached,
+			&i.PreflightIssues,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -177,7 +294,10 @@ const getVirtualTryonHistoryByID = `-- name: GetVirtualTryonHistoryByID :one
 SELECT
   id, user_id, user_image_url, garment_image_url, composite_image_url,
   instructions, position, fit, style, confidence, status,
-  processing_time, created_at, updated_at
+  processing_time, error_message,
+  input_width, input_height, output_width, output_height, output_mime_type,
+  wardrobe_item_ids, input_hash, error_detail, prompt_version, batch_id, settings,
+  base_photo_id, cached, preflight_issues, created_at, updated_at
 FROM virtual_tryon_history
 WHERE id = $1 AND user_id = $2
 `
@@ -203,6 +323,81 @@ func (q *Queries) GetVirtualTryonHistoryByID(ctx context.Context, arg GetVirtual
 		&i.Confidence,
 		&i.Status,
 		&i.ProcessingTime,
+		&i.ErrorMessage,
+		&i.InputWidth,
+		&i.InputHeight,
+		&i.OutputWidth,
+		&i.OutputHeight,
+		&i.OutputMimeType,
+		&i.WardrobeItemIds,
+		&i.InputHash,
+		&i.ErrorDetail,
+		&i.PromptVersion,
+		&i.BatchID,
+		&i.Settings,
+		&i.BasePhotoID,
+		&i.Cached,
+		&i.PreflightIssues,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+// Most recent completed try-on with identical normalized inputs
+const getCachedVirtualTryon = `-- name: GetCachedVirtualTryon :one
+SELECT
+  id, user_id, user_image_url, garment_image_url, composite_image_url,
+  instructions, position, fit, style, confidence, status,
+  processing_time, error_message,
+  input_width, input_height, output_width, output_height, output_mime_type,
+  wardrobe_item_ids, input_hash, error_detail, prompt_version, batch_id, settings,
+  base_photo_id, cached, preflight_issues, created_at, updated_at
+FROM virtual_tryon_history
+WHERE user_id = $1
+  AND input_hash = $2
+  AND status = 'completed'
+  AND composite_image_url IS NOT NULL
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type GetCachedVirtualTryonParams struct {
+	UserID    uuid.UUID
+	InputHash string
+}
+
+func (q *Queries) GetCachedVirtualTryon(ctx context.Context, arg GetCachedVirtualTryonParams) (GetVirtualTryonHistoryRow, error) {
+	row := q.db.QueryRow(ctx, getCachedVirtualTryon, arg.UserID, arg.InputHash)
+	var i GetVirtualTryonHistoryRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.UserImageUrl,
+		&i.GarmentImageUrl,
+		&i.CompositeImageUrl,
+		&i.Instructions,
+		&i.Position,
+		&i.Fit,
+		&i.Style,
+		&i.Confidence,
+		&i.Status,
+		&i.ProcessingTime,
+		&i.ErrorMessage,
+		&i.InputWidth,
+		&i.InputHeight,
+		&i.OutputWidth,
+		&i.OutputHeight,
+		&i.OutputMimeType,
+		&i.WardrobeItemIds,
+		&i.InputHash,
+		&i.ErrorDetail,
+		&i.PromptVersion,
+		&i.BatchID,
+		&i.Settings,
+		&i.BasePhotoID,
+		&i.Cached,
+		&i.PreflightIssues,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -231,12 +426,26 @@ UPDATE virtual_tryon_history SET
   confidence = COALESCE($4, confidence),
   status = COALESCE($5, status),
   processing_time = COALESCE($6, processing_time),
+  error_message = COALESCE($7, error_message),
+  input_width = COALESCE($8, input_width),
+  input_height = COALESCE($9, input_height),
+  output_width = COALESCE($10, output_width),
+  output_height = COALESCE($11, output_height),
+  output_mime_type = COALESCE($12, output_mime_type),
+  error_detail = COALESCE($13, error_detail),
+  composite_sha256 = COALESCE($14, composite_sha256),
+  input_hash = COALESCE($15, input_hash),
+  cached = COALESCE($16, cached),
+  preflight_issues = COALESCE($17, preflight_issues),
   updated_at = NOW()
 WHERE id = $1 AND user_id = $2
 RETURNING
   id, user_id, user_image_url, garment_image_url, composite_image_url,
   instructions, position, fit, style, confidence, status,
-  processing_time, created_at, updated_at
+  processing_time, error_message,
+  input_width, input_height, output_width, output_height, output_mime_type,
+  wardrobe_item_ids, input_hash, error_detail, prompt_version, batch_id, settings,
+  base_photo_id, cached, preflight_issues, created_at, updated_at
 `
 
 type UpdateVirtualTryonHistoryParams struct {
@@ -246,6 +455,17 @@ type UpdateVirtualTryonHistoryParams struct {
 	Confidence        pgtype.Float8
 	Status            string
 	ProcessingTime    pgtype.Int8
+	ErrorMessage      pgtype.Text
+	InputWidth        pgtype.Int4
+	InputHeight       pgtype.Int4
+	OutputWidth       pgtype.Int4
+	OutputHeight      pgtype.Int4
+	OutputMimeType    pgtype.Text
+	ErrorDetail       pgtype.Text
+	CompositeSha256   pgtype.Text
+	InputHash         pgtype.Text
+	Cached            pgtype.Bool
+	PreflightIssues   []byte
 }
 
 func (q *Queries) UpdateVirtualTryonHistory(ctx context.Context, arg UpdateVirtualTryonHistoryParams) (GetVirtualTryonHistoryRow, error) {
@@ -256,6 +476,17 @@ func (q *Queries) UpdateVirtualTryonHistory(ctx context.Context, arg UpdateVirtu
 		arg.Confidence,
 		arg.Status,
 		arg.ProcessingTime,
+		arg.ErrorMessage,
+		arg.InputWidth,
+		arg.InputHeight,
+		arg.OutputWidth,
+		arg.OutputHeight,
+		arg.OutputMimeType,
+		arg.ErrorDetail,
+		arg.CompositeSha256,
+		arg.InputHash,
+		arg.Cached,
+		arg.PreflightIssues,
 	)
 	var i GetVirtualTryonHistoryRow
 	err := row.Scan(
@@ -271,6 +502,21 @@ func (q *Queries) UpdateVirtualTryonHistory(ctx context.Context, arg UpdateVirtu
 		&i.Confidence,
 		&i.Status,
 		&i.ProcessingTime,
+		&i.ErrorMessage,
+		&i.InputWidth,
+		&i.InputHeight,
+		&i.OutputWidth,
+		&i.OutputHeight,
+		&i.OutputMimeType,
+		&i.WardrobeItemIds,
+		&i.InputHash,
+		&i.ErrorDetail,
+		&i.PromptVersion,
+		&i.BatchID,
+		&i.Settings,
+		&i.BasePhotoID,
+		&i.Cached,
+		&i.PreflightIssues,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -307,13 +553,230 @@ func (q *Queries) GetVirtualTryonStats(ctx context.Context, userID uuid.UUID) (G
 	return i, err
 }
 
-// Cleanup old records (for maintenance)
-const cleanupOldVirtualTryonHistory = `-- name: CleanupOldVirtualTryonHistory :exec
-DELETE FROM virtual_tryon_history
-WHERE created_at < NOW() - INTERVAL '90 days'
+// Success and confidence per prompt version, across all users when user_id is NULL
+const getVirtualTryonPromptStats = `-- name: GetVirtualTryonPromptStats :many
+SELECT
+  COALESCE(prompt_version, 'unversioned') as prompt_version,
+  COUNT(*) as total_edits,
+  COUNT(CASE WHEN status = 'completed' THEN 1 END) as successful_edits,
+  COUNT(CASE WHEN status = 'failed' THEN 1 END) as failed_edits,
+  COALESCE(AVG(confidence), 0) as avg_confidence,
+  COALESCE(AVG(CASE WHEN status = 'completed' THEN confidence END), 0) as avg_successful_confidence,
+  COALESCE(AVG(processing_time), 0) as average_processing_time
+FROM virtual_tryon_history
+WHERE ($1::uuid IS NULL OR user_id = $1)
+GROUP BY COALESCE(prompt_version, 'unversioned')
+ORDER BY prompt_version
 `
 
-func (q *Queries) CleanupOldVirtualTryonHistory(ctx context.Context) error {
-	_, err := q.db.Exec(ctx, cleanupOldVirtualTryonHistory)
-	return err
-}
\ No newline at end of file
+type GetVirtualTryonPromptStatsRow struct {
+	PromptVersion           string
+	TotalEdits              int64
+	SuccessfulEdits         int64
+	FailedEdits             int64
+	AvgConfidence           float64
+	AvgSuccessfulConfidence float64
+	AverageProcessingTime   float64
+}
+
+func (q *Queries) GetVirtualTryonPromptStats(ctx context.Context, userID pgtype.UUID) ([]GetVirtualTryonPromptStatsRow, error) {
+	rows, err := q.db.Query(ctx, getVirtualTryonPromptStats, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetVirtualTryonPromptStatsRow
+	for rows.Next() {
+		var i GetVirtualTryonPromptStatsRow
+		if err := rows.Scan(
+			&i.PromptVersion,
+			&i.TotalEdits,
+			&i.SuccessfulEdits,
+			&i.FailedEdits,
+			&i.AvgConfidence,
+			&i.AvgSuccessfulConfidence,
+			&i.AverageProcessingTime,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Fail jobs that were queued or running when a previous process exited
+// and give their reserved try-ons back to the month they were created in
+const failStaleVirtualTryonHistory = `-- name: FailStaleVirtualTryonHistory :one
+WITH failed AS (
+  UPDATE virtual_tryon_history SET
+    status = 'failed',
+    error_message = $2,
+    updated_at = NOW()
+  WHERE status IN ('pending', 'processing')
+    AND updated_at < $1
+  RETURNING user_id, created_at
+), released AS (
+  UPDATE virtual_tryon_usage u SET
+    used = GREATEST(u.used - f.count, 0),
+    updated_at = NOW()
+  FROM (
+    SELECT user_id, date_trunc('month', created_at AT TIME ZONE 'UTC')::date AS month, COUNT(*) AS count
+    FROM failed
+    GROUP BY 1, 2
+  ) f
+  WHERE u.user_id = f.user_id AND u.month = f.month
+)
+SELECT COUNT(*) FROM failed
+`
+
+type FailStaleVirtualTryonHistoryParams struct {
+	UpdatedBefore time.Time
+	ErrorMessage  string
+}
+
+func (q *Queries) FailStaleVirtualTryonHistory(ctx context.Context, arg FailStaleVirtualTryonHistoryParams) (int64, error) {
+	row := q.db.QueryRow(ctx, failStaleVirtualTryonHistory, arg.UpdatedBefore, arg.ErrorMessage)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+// Finished try-ons past the retention cutoff that have no active share link and
+// were never published
+const listExpiredVirtualTryonHistory = `-- name: ListExpiredVirtualTryonHistory :many
+SELECT h.id, h.user_id, h.composite_image_url
+FROM virtual_tryon_history h
+WHERE h.created_at < $1
+  AND h.status IN ('completed', 'failed')
+  AND NOT EXISTS (
+    SELECT 1 FROM virtual_tryon_shares s
+    WHERE s.history_id = h.id
+      AND s.revoked_at IS NULL
+      AND s.expires_at > NOW()
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM virtual_tryon_publications p
+    WHERE p.history_id = h.id
+  )
+ORDER BY h.created_at
+LIMIT $2
+`
+
+type ListExpiredVirtualTryonHistoryParams struct {
+	CreatedBefore time.Time
+	Limit         int32
+}
+
+type ListExpiredVirtualTryonHistoryRow struct {
+	ID                uuid.UUID
+	UserID            uuid.UUID
+	CompositeImageUrl pgtype.Text
+}
+
+func (q *Queries) ListExpiredVirtualTryonHistory(ctx context.Context, arg ListExpiredVirtualTryonHistoryParams) ([]ListExpiredVirtualTryonHistoryRow, error) {
+	rows, err := q.db.Query(ctx, listExpiredVirtualTryonHistory, arg.CreatedBefore, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListExpiredVirtualTryonHistoryRow
+	for rows.Next() {
+		var i ListExpiredVirtualTryonHistoryRow
+		if err := rows.Scan(&i.ID, &i.UserID, &i.CompositeImageUrl); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countExpiredVirtualTryonHistory = `-- name: CountExpiredVirtualTryonHistory :one
+SELECT COUNT(*)
+FROM virtual_tryon_history h
+WHERE h.created_at < $1
+  AND h.status IN ('completed', 'failed')
+  AND NOT EXISTS (
+    SELECT 1 FROM virtual_tryon_shares s
+    WHERE s.history_id = h.id
+      AND s.revoked_at IS NULL
+      AND s.expires_at > NOW()
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM virtual_tryon_publications p
+    WHERE p.history_id = h.id
+  )
+`
+
+func (q *Queries) CountExpiredVirtualTryonHistory(ctx context.Context, createdBefore time.Time) (int64, error) {
+	row := q.db.QueryRow(ctx, countExpiredVirtualTryonHistory, createdBefore)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+// Re-checks the retention conditions so a row shared since it was listed is kept
+const deleteExpiredVirtualTryonHistory = `-- name: DeleteExpiredVirtualTryonHistory :one
+DELETE FROM virtual_tryon_history h
+WHERE h.id = $1
+  AND h.created_at < $2
+  AND h.status IN ('completed', 'failed')
+  AND NOT EXISTS (
+    SELECT 1 FROM virtual_tryon_shares s
+    WHERE s.history_id = h.id
+      AND s.revoked_at IS NULL
+      AND s.expires_at > NOW()
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM virtual_tryon_publications p
+    WHERE p.history_id = h.id
+  )
+RETURNING h.composite_image_url
+`
+
+type DeleteExpiredVirtualTryonHistoryParams struct {
+	ID            uuid.UUID
+	CreatedBefore time.Time
+}
+
+func (q *Queries) DeleteExpiredVirtualTryonHistory(ctx context.Context, arg DeleteExpiredVirtualTryonHistoryParams) (pgtype.Text, error) {
+	row := q.db.QueryRow(ctx, deleteExpiredVirtualTryonHistory, arg.ID, arg.CreatedBefore)
+	var compositeImageUrl pgtype.Text
+	err := row.Scan(&compositeImageUrl)
+	return compositeImageUrl, err
+}
+
+// Completed try-on whose stored composite has the given SHA-256, for provenance checks
+const getVirtualTryonByCompositeHash = `-- name: GetVirtualTryonByCompositeHash :one
+SELECT id, composite_image_url, output_mime_type, created_at
+FROM virtual_tryon_history
+WHERE composite_sha256 = $1
+  AND status = 'completed'
+  AND composite_image_url IS NOT NULL
+ORDER BY created_at
+LIMIT 1
+`
+
+type GetVirtualTryonByCompositeHashRow struct {
+	ID                uuid.UUID
+	CompositeImageUrl string
+	OutputMimeType    pgtype.Text
+	CreatedAt         time.Time
+}
+
+func (q *Queries) GetVirtualTryonByCompositeHash(ctx context.Context, compositeSha256 string) (GetVirtualTryonByCompositeHashRow, error) {
+	row := q.db.QueryRow(ctx, getVirtualTryonByCompositeHash, compositeSha256)
+	var i GetVirtualTryonByCompositeHashRow
+	err := row.Scan(
+		&i.ID,
+		&i.CompositeImageUrl,
+		&i.OutputMimeType,
+		&i.CreatedAt,
+	)
+	return i, err
+}
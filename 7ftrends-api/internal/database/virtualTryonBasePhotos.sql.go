@@ -0,0 +1,348 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createVirtualTryonBasePhotosTable = `-- name: CreateVirtualTryonBasePhotosTable :exec
+CREATE TABLE IF NOT EXISTS virtual_tryon_base_photos (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL CHECK (kind IN ('front', 'full-body')),
+  image_url TEXT NOT NULL,
+  width INT NOT NULL,
+  height INT NOT NULL,
+  mime_type TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS virtual_tryon_body_profiles (
+  user_id UUID PRIMARY KEY REFERENCES auth.users(id) ON DELETE CASCADE,
+  height_cm FLOAT,
+  measurements JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_virtual_tryon_base_photos_user_id ON virtual_tryon_base_photos(user_id);
+
+-- RLS policies
+ALTER TABLE virtual_tryon_base_photos ENABLE ROW LEVEL SECURITY;
+ALTER TABLE virtual_tryon_body_profiles ENABLE ROW LEVEL SECURITY;
+
+-- Users can view their own base photos and body profile
+CREATE POLICY "Users can view own virtual tryon base photos" ON virtual_tryon_base_photos
+  FOR SELECT USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can view own virtual tryon body profile" ON virtual_tryon_body_profiles
+  FOR SELECT USING (auth.uid() = user_id);
+`
+
+func (q *Queries) CreateVirtualTryonBasePhotosTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createVirtualTryonBasePhotosTable)
+	return err
+}
+
+type VirtualTryonBasePhotoRow struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Kind      string
+	ImageUrl  string
+	Width     int32
+	Height    int32
+	MimeType  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+const countVirtualTryonBasePhotos = `-- name: CountVirtualTryonBasePhotos :one
+SELECT COUNT(*)
+FROM virtual_tryon_base_photos
+WHERE user_id = $1
+`
+
+func (q *Queries) CountVirtualTryonBasePhotos(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countVirtualTryonBasePhotos, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createVirtualTryonBasePhoto = `-- name: CreateVirtualTryonBasePhoto :one
+INSERT INTO virtual_tryon_base_photos (id, user_id, kind, image_url, width, height, mime_type)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, user_id, kind, image_url, width, height, mime_type, created_at, updated_at
+`
+
+type CreateVirtualTryonBasePhotoParams struct {
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	Kind     string
+	ImageUrl string
+	Width    int32
+	Height   int32
+	MimeType string
+}
+
+func (q *Queries) CreateVirtualTryonBasePhoto(ctx context.Context, arg CreateVirtualTryonBasePhotoParams) (VirtualTryonBasePhotoRow, error) {
+	row := q.db.QueryRow(ctx, createVirtualTryonBasePhoto,
+		arg.ID,
+		arg.UserID,
+		arg.Kind,
+		arg.ImageUrl,
+		arg.Width,
+		arg.Height,
+		arg.MimeType,
+	)
+	var i VirtualTryonBasePhotoRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Kind,
+		&i.ImageUrl,
+		&i.Width,
+		&i.Height,
+		&i.MimeType,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listVirtualTryonBasePhotos = `-- name: ListVirtualTryonBasePhotos :many
+SELECT id, user_id, kind, image_url, width, height, mime_type, created_at, updated_at
+FROM virtual_tryon_base_photos
+WHERE user_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListVirtualTryonBasePhotos(ctx context.Context, userID uuid.UUID) ([]VirtualTryonBasePhotoRow, error) {
+	rows, err := q.db.Query(ctx, listVirtualTryonBasePhotos, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []VirtualTryonBasePhotoRow
+	for rows.Next() {
+		var i VirtualTryonBasePhotoRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Kind,
+			&i.ImageUrl,
+			&i.Width,
+			&i.Height,
+			&i.MimeType,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVirtualTryonBasePhoto = `-- name: GetVirtualTryonBasePhoto :one
+SELECT id, user_id, kind, image_url, width, height, mime_type, created_at, updated_at
+FROM virtual_tryon_base_photos
+WHERE id = $1 AND user_id = $2
+`
+
+type GetVirtualTryonBasePhotoParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetVirtualTryonBasePhoto(ctx context.Context, arg GetVirtualTryonBasePhotoParams) (VirtualTryonBasePhotoRow, error) {
+	row := q.db.QueryRow(ctx, getVirtualTryonBasePhoto, arg.ID, arg.UserID)
+	var i VirtualTryonBasePhotoRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Kind,
+		&i.ImageUrl,
+		&i.Width,
+		&i.Height,
+		&i.MimeType,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const replaceVirtualTryonBasePhoto = `-- name: ReplaceVirtualTryonBasePhoto :one
+UPDATE virtual_tryon_base_photos SET
+  kind = $3,
+  image_url = $4,
+  width = $5,
+  height = $6,
+  mime_type = $7,
+  updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, kind, image_url, width, height, mime_type, created_at, updated_at
+`
+
+type ReplaceVirtualTryonBasePhotoParams struct {
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	Kind     string
+	ImageUrl string
+	Width    int32
+	Height   int32
+	MimeType string
+}
+
+func (q *Queries) ReplaceVirtualTryonBasePhoto(ctx context.Context, arg ReplaceVirtualTryonBasePhotoParams) (VirtualTryonBasePhotoRow, error) {
+	row := q.db.QueryRow(ctx, replaceVirtualTryonBasePhoto,
+		arg.ID,
+		arg.UserID,
+		arg.Kind,
+		arg.ImageUrl,
+		arg.Width,
+		arg.Height,
+		arg.MimeType,
+	)
+	var i VirtualTryonBasePhotoRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Kind,
+		&i.ImageUrl,
+		&i.Width,
+		&i.Height,
+		&i.MimeType,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteVirtualTryonBasePhoto = `-- name: DeleteVirtualTryonBasePhoto :one
+DELETE FROM virtual_tryon_base_photos
+WHERE id = $1 AND user_id = $2
+RETURNING image_url
+`
+
+type DeleteVirtualTryonBasePhotoParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteVirtualTryonBasePhoto(ctx context.Context, arg DeleteVirtualTryonBasePhotoParams) (string, error) {
+	row := q.db.QueryRow(ctx, deleteVirtualTryonBasePhoto, arg.ID, arg.UserID)
+	var imageUrl string
+	err := row.Scan(&imageUrl)
+	return imageUrl, err
+}
+
+// Purge every try-on generated from a base photo, returning the composites to remove.
+// Published composites are still used by their posts, so they are not returned.
+const deleteVirtualTryonHistoryByBasePhoto = `-- name: DeleteVirtualTryonHistoryByBasePhoto :many
+DELETE FROM virtual_tryon_history h
+WHERE h.base_photo_id = $1 AND h.user_id = $2
+RETURNING CASE
+  WHEN EXISTS (SELECT 1 FROM virtual_tryon_publications p WHERE p.history_id = h.id) THEN NULL
+  ELSE h.composite_image_url
+END
+`
+
+type DeleteVirtualTryonHistoryByBasePhotoParams struct {
+	BasePhotoID uuid.UUID
+	UserID      uuid.UUID
+}
+
+func (q *Queries) DeleteVirtualTryonHistoryByBasePhoto(ctx context.Context, arg DeleteVirtualTryonHistoryByBasePhotoParams) ([]pgtype.Text, error) {
+	rows, err := q.db.Query(ctx, deleteVirtualTryonHistoryByBasePhoto, arg.BasePhotoID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.Text
+	for rows.Next() {
+		var compositeImageUrl pgtype.Text
+		if err := rows.Scan(&compositeImageUrl); err != nil {
+			return nil, err
+		}
+		items = append(items, compositeImageUrl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type VirtualTryonBodyProfileRow struct {
+	UserID       uuid.UUID
+	HeightCm     pgtype.Float8
+	Measurements []byte
+	UpdatedAt    time.Time
+}
+
+const getVirtualTryonBodyProfile = `-- name: GetVirtualTryonBodyProfile :one
+SELECT user_id, height_cm, measurements, updated_at
+FROM virtual_tryon_body_profiles
+WHERE user_id = $1
+`
+
+func (q *Queries) GetVirtualTryonBodyProfile(ctx context.Context, userID uuid.UUID) (VirtualTryonBodyProfileRow, error) {
+	row := q.db.QueryRow(ctx, getVirtualTryonBodyProfile, userID)
+	var i VirtualTryonBodyProfileRow
+	err := row.Scan(
+		&i.UserID,
+		&i.HeightCm,
+		&i.Measurements,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertVirtualTryonBodyProfile = `-- name: UpsertVirtualTryonBodyProfile :one
+INSERT INTO virtual_tryon_body_profiles (user_id, height_cm, measurements)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE SET
+  height_cm = EXCLUDED.height_cm,
+  measurements = EXCLUDED.measurements,
+  updated_at = NOW()
+RETURNING user_id, height_cm, measurements, updated_at
+`
+
+type UpsertVirtualTryonBodyProfileParams struct {
+	UserID       uuid.UUID
+	HeightCm     pgtype.Float8
+	Measurements []byte
+}
+
+func (q *Queries) UpsertVirtualTryonBodyProfile(ctx context.Context, arg UpsertVirtualTryonBodyProfileParams) (VirtualTryonBodyProfileRow, error) {
+	row := q.db.QueryRow(ctx, upsertVirtualTryonBodyProfile, arg.UserID, arg.HeightCm, arg.Measurements)
+	var i VirtualTryonBodyProfileRow
+	err := row.Scan(
+		&i.UserID,
+		&i.HeightCm,
+		&i.Measurements,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteVirtualTryonBodyProfile = `-- name: DeleteVirtualTryonBodyProfile :execrows
+DELETE FROM virtual_tryon_body_profiles
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteVirtualTryonBodyProfile(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteVirtualTryonBodyProfile, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,216 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createVirtualTryonFeedbackTable = `-- name: CreateVirtualTryonFeedbackTable :exec
+CREATE TABLE IF NOT EXISTS virtual_tryon_feedback (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  history_id UUID NOT NULL UNIQUE REFERENCES virtual_tryon_history(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+  rating TEXT NOT NULL CHECK (rating IN ('up', 'down')),
+  reason TEXT CHECK (reason IN ('bad_fit', 'wrong_garment', 'distorted_face', 'lighting')),
+  comment TEXT,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_virtual_tryon_feedback_user_id ON virtual_tryon_feedback(user_id);
+
+-- RLS policies
+ALTER TABLE virtual_tryon_feedback ENABLE ROW LEVEL SECURITY;
+
+-- Users can view their own feedback
+CREATE POLICY "Users can view own virtual tryon feedback" ON virtual_tryon_feedback
+  FOR SELECT USING (auth.uid() = user_id);
+
+-- Users can update their own feedback
+CREATE POLICY "Users can update own virtual tryon feedback" ON virtual_tryon_feedback
+  FOR UPDATE USING (auth.uid() = user_id);
+`
+
+func (q *Queries) CreateVirtualTryonFeedbackTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createVirtualTryonFeedbackTable)
+	return err
+}
+
+// Only completed try-ons owned by the user can be rated; rating again replaces
+// the previous feedback
+const upsertVirtualTryonFeedback = `-- name: UpsertVirtualTryonFeedback :one
+INSERT INTO virtual_tryon_feedback (history_id, user_id, rating, reason, comment)
+SELECT h.id, h.user_id, $3, $4, $5
+FROM virtual_tryon_history h
+WHERE h.id = $1
+  AND h.user_id = $2
+  AND h.status = 'completed'
+ON CONFLICT (history_id) DO UPDATE SET
+  rating = EXCLUDED.rating,
+  reason = EXCLUDED.reason,
+  comment = EXCLUDED.comment,
+  updated_at = NOW()
+RETURNING id, history_id, user_id, rating, reason, comment, created_at, updated_at
+`
+
+type UpsertVirtualTryonFeedbackParams struct {
+	HistoryID uuid.UUID
+	UserID    uuid.UUID
+	Rating    string
+	Reason    pgtype.Text
+	Comment   pgtype.Text
+}
+
+type VirtualTryonFeedbackRow struct {
+	ID        uuid.UUID
+	HistoryID uuid.UUID
+	UserID    uuid.UUID
+	Rating    string
+	Reason    pgtype.Text
+	Comment   pgtype.Text
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpsertVirtualTryonFeedback(ctx context.Context, arg UpsertVirtualTryonFeedbackParams) (VirtualTryonFeedbackRow, error) {
+	row := q.db.QueryRow(ctx, upsertVirtualTryonFeedback,
+		arg.HistoryID,
+		arg.UserID,
+		arg.Rating,
+		arg.Reason,
+		arg.Comment,
+	)
+	var i VirtualTryonFeedbackRow
+	err := row.Scan(
+		&i.ID,
+		&i.HistoryID,
+		&i.UserID,
+		&i.Rating,
+		&i.Reason,
+		&i.Comment,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getVirtualTryonFeedback = `-- name: GetVirtualTryonFeedback :one
+SELECT id, history_id, user_id, rating, reason, comment, created_at, updated_at
+FROM virtual_tryon_feedback
+WHERE history_id = $1 AND user_id = $2
+`
+
+type GetVirtualTryonFeedbackParams struct {
+	HistoryID uuid.UUID
+	UserID    uuid.UUID
+}
+
+func (q *Queries) GetVirtualTryonFeedback(ctx context.Context, arg GetVirtualTryonFeedbackParams) (VirtualTryonFeedbackRow, error) {
+	row := q.db.QueryRow(ctx, getVirtualTryonFeedback, arg.HistoryID, arg.UserID)
+	var i VirtualTryonFeedbackRow
+	err := row.Scan(
+		&i.ID,
+		&i.HistoryID,
+		&i.UserID,
+		&i.Rating,
+		&i.Reason,
+		&i.Comment,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteVirtualTryonFeedback = `-- name: DeleteVirtualTryonFeedback :execrows
+DELETE FROM virtual_tryon_feedback
+WHERE history_id = $1 AND user_id = $2
+`
+
+type DeleteVirtualTryonFeedbackParams struct {
+	HistoryID uuid.UUID
+	UserID    uuid.UUID
+}
+
+func (q *Queries) DeleteVirtualTryonFeedback(ctx context.Context, arg DeleteVirtualTryonFeedbackParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteVirtualTryonFeedback, arg.HistoryID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// Ratings grouped separately by each try-on parameter; a NULL user ID
+// aggregates across all users
+const getVirtualTryonFeedbackStats = `-- name: GetVirtualTryonFeedbackStats :many
+SELECT
+  CASE
+    WHEN GROUPING(h.position) = 0 THEN 'position'
+    WHEN GROUPING(h.fit) = 0 THEN 'fit'
+    WHEN GROUPING(h.style) = 0 THEN 'style'
+    ELSE 'prompt_version'
+  END as dimension,
+  CASE
+    WHEN GROUPING(h.position) = 0 THEN h.position
+    WHEN GROUPING(h.fit) = 0 THEN h.fit
+    WHEN GROUPING(h.style) = 0 THEN h.style
+    ELSE COALESCE(h.prompt_version, 'unversioned')
+  END as value,
+  COUNT(*) as total_ratings,
+  COUNT(CASE WHEN f.rating = 'up' THEN 1 END) as thumbs_up,
+  COUNT(CASE WHEN f.rating = 'down' THEN 1 END) as thumbs_down,
+  COUNT(CASE WHEN f.reason = 'bad_fit' THEN 1 END) as bad_fit,
+  COUNT(CASE WHEN f.reason = 'wrong_garment' THEN 1 END) as wrong_garment,
+  COUNT(CASE WHEN f.reason = 'distorted_face' THEN 1 END) as distorted_face,
+  COUNT(CASE WHEN f.reason = 'lighting' THEN 1 END) as lighting
+FROM virtual_tryon_feedback f
+JOIN virtual_tryon_history h ON h.id = f.history_id
+WHERE ($1::uuid IS NULL OR f.user_id = $1)
+GROUP BY GROUPING SETS ((h.position), (h.fit), (h.style), (h.prompt_version))
+ORDER BY dimension, value
+`
+
+type GetVirtualTryonFeedbackStatsRow struct {
+	Dimension     string
+	Value         string
+	TotalRatings  int64
+	ThumbsUp      int64
+	ThumbsDown    int64
+	BadFit        int64
+	WrongGarment  int64
+	DistortedFace int64
+	Lighting      int64
+}
+
+func (q *Queries) GetVirtualTryonFeedbackStats(ctx context.Context, userID pgtype.UUID) ([]GetVirtualTryonFeedbackStatsRow, error) {
+	rows, err := q.db.Query(ctx, getVirtualTryonFeedbackStats, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetVirtualTryonFeedbackStatsRow
+	for rows.Next() {
+		var i GetVirtualTryonFeedbackStatsRow
+		if err := rows.Scan(
+			&i.Dimension,
+			&i.Value,
+			&i.TotalRatings,
+			&i.ThumbsUp,
+			&i.ThumbsDown,
+			&i.BadFit,
+			&i.WrongGarment,
+			&i.DistortedFace,
+			&i.Lighting,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,257 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Attribution from published posts and outfits back to the try-on they came from
+const createVirtualTryonPublicationsTable = `-- name: CreateVirtualTryonPublicationsTable :exec
+CREATE TABLE IF NOT EXISTS virtual_tryon_publications (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  history_id UUID NOT NULL REFERENCES virtual_tryon_history(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+  post_id UUID REFERENCES posts(id) ON DELETE CASCADE,
+  outfit_id UUID REFERENCES outfits(id) ON DELETE CASCADE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  CHECK (num_nonnulls(post_id, outfit_id) = 1)
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_virtual_tryon_publications_history_id ON virtual_tryon_publications(history_id);
+CREATE INDEX IF NOT EXISTS idx_virtual_tryon_publications_post_id ON virtual_tryon_publications(post_id);
+CREATE INDEX IF NOT EXISTS idx_virtual_tryon_publications_outfit_id ON virtual_tryon_publications(outfit_id);
+
+-- RLS policies
+ALTER TABLE virtual_tryon_publications ENABLE ROW LEVEL SECURITY;
+
+-- Users can view their own publications
+CREATE POLICY "Users can view own virtual tryon publications" ON virtual_tryon_publications
+  FOR SELECT USING (auth.uid() = user_id);
+`
+
+func (q *Queries) CreateVirtualTryonPublicationsTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createVirtualTryonPublicationsTable)
+	return err
+}
+
+const createPost = `-- name: CreatePost :one
+INSERT INTO posts (id, user_id, content, outfit_id, tags, status, visibility, published_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING created_at
+`
+
+type CreatePostParams struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Content     []byte
+	OutfitID    pgtype.UUID
+	Tags        []string
+	Status      string
+	Visibility  string
+	PublishedAt pgtype.Timestamptz
+}
+
+func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (time.Time, error) {
+	row := q.db.QueryRow(ctx, createPost,
+		arg.ID,
+		arg.UserID,
+		arg.Content,
+		arg.OutfitID,
+		arg.Tags,
+		arg.Status,
+		arg.Visibility,
+		arg.PublishedAt,
+	)
+	var createdAt time.Time
+	err := row.Scan(&createdAt)
+	return createdAt, err
+}
+
+const createPostMedia = `-- name: CreatePostMedia :exec
+INSERT INTO media (post_id, type, url, width, height, file_size, mime_type, position, caption, metadata)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+`
+
+type CreatePostMediaParams struct {
+	PostID   uuid.UUID
+	Type     string
+	URL      string
+	Width    int32
+	Height   int32
+	FileSize int64
+	MimeType string
+	Position int32
+	Caption  pgtype.Text
+	Metadata []byte
+}
+
+func (q *Queries) CreatePostMedia(ctx context.Context, arg CreatePostMediaParams) error {
+	_, err := q.db.Exec(ctx, createPostMedia,
+		arg.PostID,
+		arg.Type,
+		arg.URL,
+		arg.Width,
+		arg.Height,
+		arg.FileSize,
+		arg.MimeType,
+		arg.Position,
+		arg.Caption,
+		arg.Metadata,
+	)
+	return err
+}
+
+const createOutfit = `-- name: CreateOutfit :one
+INSERT INTO outfits (
+  id, user_id, name, description, occasion, season, style, tags,
+  images, primary_image, is_public, metadata
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING created_at
+`
+
+type CreateOutfitParams struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Name         string
+	Description  pgtype.Text
+	Occasion     string
+	Season       string
+	Style        string
+	Tags         []string
+	Images       []string
+	PrimaryImage string
+	IsPublic     bool
+	Metadata     []byte
+}
+
+func (q *Queries) CreateOutfit(ctx context.Context, arg CreateOutfitParams) (time.Time, error) {
+	row := q.db.QueryRow(ctx, createOutfit,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.Description,
+		arg.Occasion,
+		arg.Season,
+		arg.Style,
+		arg.Tags,
+		arg.Images,
+		arg.PrimaryImage,
+		arg.IsPublic,
+		arg.Metadata,
+	)
+	var createdAt time.Time
+	err := row.Scan(&createdAt)
+	return createdAt, err
+}
+
+// Only wardrobe items owned by the outfit's user are added
+const createOutfitItem = `-- name: CreateOutfitItem :execrows
+INSERT INTO outfit_items (outfit_id, wardrobe_id, position)
+SELECT $1, w.id, $4
+FROM wardrobe_items w
+WHERE w.id = $2 AND w.user_id = $3
+`
+
+type CreateOutfitItemParams struct {
+	OutfitID   uuid.UUID
+	WardrobeID uuid.UUID
+	UserID     uuid.UUID
+	Position   int32
+}
+
+func (q *Queries) CreateOutfitItem(ctx context.Context, arg CreateOutfitItemParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createOutfitItem,
+		arg.OutfitID,
+		arg.WardrobeID,
+		arg.UserID,
+		arg.Position,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const createVirtualTryonPublication = `-- name: CreateVirtualTryonPublication :one
+INSERT INTO virtual_tryon_publications (history_id, user_id, post_id, outfit_id)
+VALUES ($1, $2, $3, $4)
+RETURNING id, history_id, user_id, post_id, outfit_id, created_at
+`
+
+type CreateVirtualTryonPublicationParams struct {
+	HistoryID uuid.UUID
+	UserID    uuid.UUID
+	PostID    pgtype.UUID
+	OutfitID  pgtype.UUID
+}
+
+type VirtualTryonPublicationRow struct {
+	ID        uuid.UUID
+	HistoryID uuid.UUID
+	UserID    uuid.UUID
+	PostID    pgtype.UUID
+	OutfitID  pgtype.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) CreateVirtualTryonPublication(ctx context.Context, arg CreateVirtualTryonPublicationParams) (VirtualTryonPublicationRow, error) {
+	row := q.db.QueryRow(ctx, createVirtualTryonPublication,
+		arg.HistoryID,
+		arg.UserID,
+		arg.PostID,
+		arg.OutfitID,
+	)
+	var i VirtualTryonPublicationRow
+	err := row.Scan(
+		&i.ID,
+		&i.HistoryID,
+		&i.UserID,
+		&i.PostID,
+		&i.OutfitID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listVirtualTryonPublications = `-- name: ListVirtualTryonPublications :many
+SELECT id, history_id, user_id, post_id, outfit_id, created_at
+FROM virtual_tryon_publications
+WHERE history_id = $1 AND user_id = $2
+ORDER BY created_at DESC
+`
+
+type ListVirtualTryonPublicationsParams struct {
+	HistoryID uuid.UUID
+	UserID    uuid.UUID
+}
+
+func (q *Queries) ListVirtualTryonPublications(ctx context.Context, arg ListVirtualTryonPublicationsParams) ([]VirtualTryonPublicationRow, error) {
+	rows, err := q.db.Query(ctx, listVirtualTryonPublications, arg.HistoryID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []VirtualTryonPublicationRow
+	for rows.Next() {
+		var i VirtualTryonPublicationRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.HistoryID,
+			&i.UserID,
+			&i.PostID,
+			&i.OutfitID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
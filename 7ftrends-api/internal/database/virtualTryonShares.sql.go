@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createVirtualTryonSharesTable = `-- name: CreateVirtualTryonSharesTable :exec
+CREATE TABLE IF NOT EXISTS virtual_tryon_shares (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  history_id UUID NOT NULL REFERENCES virtual_tryon_history(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  revoked_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_virtual_tryon_shares_history_id ON virtual_tryon_shares(history_id);
+CREATE INDEX IF NOT EXISTS idx_virtual_tryon_shares_user_id ON virtual_tryon_shares(user_id);
+
+-- RLS policies
+ALTER TABLE virtual_tryon_shares ENABLE ROW LEVEL SECURITY;
+
+-- Users can view their own shares
+CREATE POLICY "Users can view own virtual tryon shares" ON virtual_tryon_shares
+  FOR SELECT USING (auth.uid() = user_id);
+
+-- Users can update their own shares
+CREATE POLICY "Users can update own virtual tryon shares" ON virtual_tryon_shares
+  FOR UPDATE USING (auth.uid() = user_id);
+`
+
+func (q *Queries) CreateVirtualTryonSharesTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createVirtualTryonSharesTable)
+	return err
+}
+
+// Only completed try-ons owned by the user can be shared
+const createVirtualTryonShare = `-- name: CreateVirtualTryonShare :one
+INSERT INTO virtual_tryon_shares (id, history_id, user_id, expires_at)
+SELECT $1, h.id, h.user_id, $4
+FROM virtual_tryon_history h
+WHERE h.id = $2
+  AND h.user_id = $3
+  AND h.status = 'completed'
+  AND h.composite_image_url IS NOT NULL
+RETURNING id, history_id, user_id, expires_at, revoked_at, created_at
+`
+
+type CreateVirtualTryonShareParams struct {
+	ID        uuid.UUID
+	HistoryID uuid.UUID
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+type VirtualTryonShareRow struct {
+	ID        uuid.UUID
+	HistoryID uuid.UUID
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	RevokedAt pgtype.Timestamptz
+	CreatedAt time.Time
+}
+
+func (q *Queries) CreateVirtualTryonShare(ctx context.Context, arg CreateVirtualTryonShareParams) (VirtualTryonShareRow, error) {
+	row := q.db.QueryRow(ctx, createVirtualTryonShare,
+		arg.ID,
+		arg.HistoryID,
+		arg.UserID,
+		arg.ExpiresAt,
+	)
+	var i VirtualTryonShareRow
+	err := row.Scan(
+		&i.ID,
+		&i.HistoryID,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const revokeVirtualTryonShares = `-- name: RevokeVirtualTryonShares :execrows
+UPDATE virtual_tryon_shares SET
+  revoked_at = NOW()
+WHERE history_id = $1
+  AND user_id = $2
+  AND revoked_at IS NULL
+`
+
+type RevokeVirtualTryonSharesParams struct {
+	HistoryID uuid.UUID
+	UserID    uuid.UUID
+}
+
+func (q *Queries) RevokeVirtualTryonShares(ctx context.Context, arg RevokeVirtualTryonSharesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeVirtualTryonShares, arg.HistoryID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// Resolve an unrevoked, unexpired share to the minimal public view of its try-on
+const getActiveVirtualTryonShare = `-- name: GetActiveVirtualTryonShare :one
+SELECT
+  s.id, s.expires_at,
+  h.composite_image_url, h.position, h.fit, h.style,
+  h.output_width, h.output_height, h.output_mime_type,
+  h.created_at
+FROM virtual_tryon_shares s
+JOIN virtual_tryon_history h ON h.id = s.history_id
+WHERE s.id = $1
+  AND s.revoked_at IS NULL
+  AND s.expires_at > NOW()
+  AND h.status = 'completed'
+  AND h.composite_image_url IS NOT NULL
+`
+
+type GetActiveVirtualTryonShareRow struct {
+	ID                uuid.UUID
+	ExpiresAt         time.Time
+	CompositeImageUrl string
+	Position          string
+	Fit               string
+	Style             string
+	OutputWidth       pgtype.Int4
+	OutputHeight      pgtype.Int4
+	OutputMimeType    pgtype.Text
+	CreatedAt         time.Time
+}
+
+func (q *Queries) GetActiveVirtualTryonShare(ctx context.Context, id uuid.UUID) (GetActiveVirtualTryonShareRow, error) {
+	row := q.db.QueryRow(ctx, getActiveVirtualTryonShare, id)
+	var i GetActiveVirtualTryonShareRow
+	err := row.Scan(
+		&i.ID,
+		&i.ExpiresAt,
+		&i.CompositeImageUrl,
+		&i.Position,
+		&i.Fit,
+		&i.Style,
+		&i.OutputWidth,
+		&i.OutputHeight,
+		&i.OutputMimeType,
+		&i.CreatedAt,
+	)
+	return i, err
+}
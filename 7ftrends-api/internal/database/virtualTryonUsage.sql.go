@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createVirtualTryonUsageTable = `-- name: CreateVirtualTryonUsageTable :exec
+CREATE TABLE IF NOT EXISTS virtual_tryon_usage (
+  user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+  month DATE NOT NULL,
+  used INT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  PRIMARY KEY (user_id, month)
+);
+
+-- RLS policies
+ALTER TABLE virtual_tryon_usage ENABLE ROW LEVEL SECURITY;
+
+-- Users can view their own usage
+CREATE POLICY "Users can view own virtual tryon usage" ON virtual_tryon_usage
+  FOR SELECT USING (auth.uid() = user_id);
+`
+
+func (q *Queries) CreateVirtualTryonUsageTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createVirtualTryonUsageTable)
+	return err
+}
+
+// Takes Count try-ons from the month's allowance. No row is returned when they do
+// not fit under UsageLimit; a negative limit is unlimited.
+const reserveVirtualTryonUsage = `-- name: ReserveVirtualTryonUsage :one
+INSERT INTO virtual_tryon_usage (user_id, month, used)
+SELECT $1, $2::date, $3::int
+WHERE $4::int < 0 OR $3::int <= $4::int
+ON CONFLICT (user_id, month) DO UPDATE SET
+  used = virtual_tryon_usage.used + EXCLUDED.used,
+  updated_at = NOW()
+WHERE $4::int < 0 OR virtual_tryon_usage.used + EXCLUDED.used <= $4::int
+RETURNING used
+`
+
+type ReserveVirtualTryonUsageParams struct {
+	UserID     uuid.UUID
+	Month      time.Time
+	Count      int32
+	UsageLimit int32
+}
+
+func (q *Queries) ReserveVirtualTryonUsage(ctx context.Context, arg ReserveVirtualTryonUsageParams) (int32, error) {
+	row := q.db.QueryRow(ctx, reserveVirtualTryonUsage, arg.UserID, arg.Month, arg.Count, arg.UsageLimit)
+	var used int32
+	err := row.Scan(&used)
+	return used, err
+}
+
+// Gives back reserved try-ons that did not produce a generation
+const releaseVirtualTryonUsage = `-- name: ReleaseVirtualTryonUsage :exec
+UPDATE virtual_tryon_usage SET
+  used = GREATEST(used - $3::int, 0),
+  updated_at = NOW()
+WHERE user_id = $1 AND month = $2::date
+`
+
+type ReleaseVirtualTryonUsageParams struct {
+	UserID uuid.UUID
+	Month  time.Time
+	Count  int32
+}
+
+func (q *Queries) ReleaseVirtualTryonUsage(ctx context.Context, arg ReleaseVirtualTryonUsageParams) error {
+	_, err := q.db.Exec(ctx, releaseVirtualTryonUsage, arg.UserID, arg.Month, arg.Count)
+	return err
+}
+
+const getVirtualTryonUsage = `-- name: GetVirtualTryonUsage :one
+SELECT used
+FROM virtual_tryon_usage
+WHERE user_id = $1 AND month = $2::date
+`
+
+type GetVirtualTryonUsageParams struct {
+	UserID uuid.UUID
+	Month  time.Time
+}
+
+func (q *Queries) GetVirtualTryonUsage(ctx context.Context, arg GetVirtualTryonUsageParams) (int32, error) {
+	row := q.db.QueryRow(ctx, getVirtualTryonUsage, arg.UserID, arg.Month)
+	var used int32
+	err := row.Scan(&used)
+	return used, err
+}
@@ -563,48 +563,3 @@ func (q *Queries) DeleteWardrobeItem(ctx context.Context, arg DeleteWardrobeItem
 	_, err := q.db.Exec(ctx, deleteWardrobeItem, arg.ID, arg.UserID)
 	return err
 }
-
-const getWardrobeStats = `-- name: GetWardrobeStats :one
-SELECT
-  COUNT(*) as total_items,
-  COUNT(CASE WHEN is_favorite = true THEN 1 END) as favorite_items,
-  COUNT(CASE WHEN is_available = true THEN 1 END) as available_items,
-  COUNT(CASE WHEN is_clean = true THEN 1 END) as clean_items,
-  SUM(wear_count) as total_wears,
-  AVG(quality_score) as avg_quality_score,
-  category,
-  COUNT(*) as count_by_category
-FROM wardrobe_items
-WHERE user_id = $1
-GROUP BY category
-ORDER BY count_by_category DESC
-`
-
-func (q *Queries) GetWardrobeStats(ctx context.Context, userID uuid.UUID) ([]GetWardrobeStatsRow, error) {
-	rows, err := q.db.Query(ctx, getWardrobeStats, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []GetWardrobeStatsRow
-	for rows.Next() {
-		var i GetWardrobeStatsRow
-		if err := rows.Scan(
-			&i.TotalItems,
-			&i.FavoriteItems,
-			&i.AvailableItems,
-			&i.CleanItems,
-			&i.TotalWears,
-			&i.AvgQualityScore,
-			&i.Category,
-			&i.CountByCategory,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
\ No newline at end of file
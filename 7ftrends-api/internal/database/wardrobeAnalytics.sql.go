@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Every item of the user with the number of wears logged since WornSince
+const listWardrobeItemsForAnalytics = `-- name: ListWardrobeItemsForAnalytics :many
+SELECT
+  i.id, i.user_id, i.name, i.category, i.subcategory, i.brand, i.color,
+  i.size, i.material, i.style, i.season, i.pattern, i.images, i.tags,
+  i.purchase_date, i.purchase_price, i.is_favorite, i.last_worn, i.wear_count,
+  i.condition, i.quality_score, i.sustainability_score, i.metadata,
+  i.created_at, i.updated_at,
+  (
+    SELECT COUNT(*) FROM wardrobe_wear_events e
+    WHERE e.item_id = i.id AND e.worn_on >= $2::date
+  ) AS recent_wears
+FROM wardrobe_items i
+WHERE i.user_id = $1
+ORDER BY i.created_at DESC, i.id
+`
+
+type ListWardrobeItemsForAnalyticsParams struct {
+	UserID    uuid.UUID
+	WornSince time.Time
+}
+
+type ListWardrobeItemsForAnalyticsRow struct {
+	ID                  uuid.UUID
+	UserID              uuid.UUID
+	Name                string
+	Category            string
+	Subcategory         pgtype.Text
+	Brand               pgtype.Text
+	Color               string
+	Size                pgtype.Text
+	Material            pgtype.Text
+	Style               pgtype.Text
+	Season              []byte
+	Pattern             pgtype.Text
+	Images              []byte
+	Tags                []byte
+	PurchaseDate        pgtype.Timestamptz
+	PurchasePrice       pgtype.Float8
+	IsFavorite          bool
+	LastWorn            pgtype.Timestamptz
+	WearCount           int32
+	Condition           string
+	QualityScore        int32
+	SustainabilityScore pgtype.Int4
+	Metadata            []byte
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	RecentWears         int64
+}
+
+func (q *Queries) ListWardrobeItemsForAnalytics(ctx context.Context, arg ListWardrobeItemsForAnalyticsParams) ([]ListWardrobeItemsForAnalyticsRow, error) {
+	rows, err := q.db.Query(ctx, listWardrobeItemsForAnalytics, arg.UserID, arg.WornSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWardrobeItemsForAnalyticsRow
+	for rows.Next() {
+		var i ListWardrobeItemsForAnalyticsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Category,
+			&i.Subcategory,
+			&i.Brand,
+			&i.Color,
+			&i.Size,
+			&i.Material,
+			&i.Style,
+			&i.Season,
+			&i.Pattern,
+			&i.Images,
+			&i.Tags,
+			&i.PurchaseDate,
+			&i.PurchasePrice,
+			&i.IsFavorite,
+			&i.LastWorn,
+			&i.WearCount,
+			&i.Condition,
+			&i.QualityScore,
+			&i.SustainabilityScore,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.RecentWears,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
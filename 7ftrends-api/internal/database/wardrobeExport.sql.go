@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Keyset pages in creation order, so an export never holds the whole wardrobe in memory
+const listWardrobeItemsForExport = `-- name: ListWardrobeItemsForExport :many
+SELECT
+  id, user_id, name, description, category, subcategory, brand, color,
+  secondary_colors, size, material, style, occasion, season, pattern,
+  images, tags, purchase_date, purchase_price, purchase_location,
+  care_instructions, is_favorite, is_available, is_clean, last_worn,
+  wear_count, condition, quality_score, sustainability_score, metadata,
+  ai_tags, ai_category, ai_colors, ai_occasions, ai_seasons, ai_style,
+  ai_materials, ai_confidence, ai_processed_at, ai_status, ai_error_message,
+  created_at, updated_at
+FROM wardrobe_items
+WHERE user_id = $1
+  AND ($2::timestamptz IS NULL OR (created_at, id) > ($2, $3::uuid))
+ORDER BY created_at, id
+LIMIT $4
+`
+
+type ListWardrobeItemsForExportParams struct {
+	UserID         uuid.UUID
+	AfterCreatedAt pgtype.Timestamptz
+	AfterID        pgtype.UUID
+	Limit          int32
+}
+
+func (q *Queries) ListWardrobeItemsForExport(ctx context.Context, arg ListWardrobeItemsForExportParams) ([]GetWardrobeItemsRow, error) {
+	rows, err := q.db.Query(ctx, listWardrobeItemsForExport,
+		arg.UserID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetWardrobeItemsRow
+	for rows.Next() {
+		var i GetWardrobeItemsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Description,
+			&i.Category,
+			&i.Subcategory,
+			&i.Brand,
+			&i.Color,
+			&i.SecondaryColors,
+			&i.Size,
+			&i.Material,
+			&i.Style,
+			&i.Occasion,
+			&i.Season,
+			&i.Pattern,
+			&i.Images,
+			&i.Tags,
+			&i.PurchaseDate,
+			&i.PurchasePrice,
+			&i.PurchaseLocation,
+			&i.CareInstructions,
+			&i.IsFavorite,
+			&i.IsAvailable,
+			&i.IsClean,
+			&i.LastWorn,
+			&i.WearCount,
+			&i.Condition,
+			&i.QualityScore,
+			&i.SustainabilityScore,
+			&i.Metadata,
+			&i.AiTags,
+			&i.AiCategory,
+			&i.AiColors,
+			&i.AiOccasions,
+			&i.AiSeasons,
+			&i.AiStyle,
+			&i.AiMaterials,
+			&i.AiConfidence,
+			&i.AiProcessedAt,
+			&i.AiStatus,
+			&i.AiErrorMessage,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const listWardrobeItemKeys = `-- name: ListWardrobeItemKeys :many
+SELECT name, category, color
+FROM wardrobe_items
+WHERE user_id = $1
+`
+
+type ListWardrobeItemKeysRow struct {
+	Name     string
+	Category string
+	Color    string
+}
+
+// ListWardrobeItemKeys returns the fields an import matches existing items on
+func (q *Queries) ListWardrobeItemKeys(ctx context.Context, userID uuid.UUID) ([]ListWardrobeItemKeysRow, error) {
+	rows, err := q.db.Query(ctx, listWardrobeItemKeys, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWardrobeItemKeysRow
+	for rows.Next() {
+		var i ListWardrobeItemKeysRow
+		if err := rows.Scan(&i.Name, &i.Category, &i.Color); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
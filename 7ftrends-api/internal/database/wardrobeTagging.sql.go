@@ -0,0 +1,324 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Columns that let the tagging worker use wardrobe_items as a durable queue.
+// ai_requested_at marks each request, so a result for an older request is discarded.
+const addWardrobeTaggingColumns = `-- name: AddWardrobeTaggingColumns :exec
+ALTER TABLE wardrobe_items ADD COLUMN IF NOT EXISTS ai_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE wardrobe_items ADD COLUMN IF NOT EXISTS ai_next_attempt_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE wardrobe_items ADD COLUMN IF NOT EXISTS ai_requested_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_wardrobe_items_ai_queue ON wardrobe_items(ai_next_attempt_at)
+  WHERE ai_status IN ('pending', 'processing');
+`
+
+func (q *Queries) AddWardrobeTaggingColumns(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, addWardrobeTaggingColumns)
+	return err
+}
+
+// Claim due items with images. Processing items whose lease ran out are claimed
+// again, so work lost with a stopped process is picked up.
+const claimWardrobeTaggingJobs = `-- name: ClaimWardrobeTaggingJobs :many
+UPDATE wardrobe_items w SET
+  ai_status = 'processing',
+  ai_attempts = w.ai_attempts + 1,
+  ai_next_attempt_at = $2
+FROM (
+  SELECT id FROM wardrobe_items
+  WHERE ai_status IN ('pending', 'processing')
+    -- Items created without images store JSON null, which has no array length
+    AND CASE WHEN jsonb_typeof(images) = 'array' THEN jsonb_array_length(images) ELSE 0 END > 0
+    AND COALESCE(ai_next_attempt_at, '-infinity') <= NOW()
+  ORDER BY ai_next_attempt_at NULLS FIRST, created_at
+  LIMIT $1
+  FOR UPDATE SKIP LOCKED
+) due
+WHERE w.id = due.id
+RETURNING w.id, w.user_id, w.images, w.ai_attempts, w.ai_requested_at
+`
+
+type ClaimWardrobeTaggingJobsParams struct {
+	Limit      int32
+	LeaseUntil time.Time
+}
+
+type ClaimWardrobeTaggingJobsRow struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Images        []byte
+	AiAttempts    int32
+	AiRequestedAt pgtype.Timestamptz
+}
+
+func (q *Queries) ClaimWardrobeTaggingJobs(ctx context.Context, arg ClaimWardrobeTaggingJobsParams) ([]ClaimWardrobeTaggingJobsRow, error) {
+	rows, err := q.db.Query(ctx, claimWardrobeTaggingJobs, arg.Limit, arg.LeaseUntil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimWardrobeTaggingJobsRow
+	for rows.Next() {
+		var i ClaimWardrobeTaggingJobsRow
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Images, &i.AiAttempts, &i.AiRequestedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeWardrobeTagging = `-- name: CompleteWardrobeTagging :execrows
+UPDATE wardrobe_items SET
+  ai_tags = $3,
+  ai_category = $4,
+  ai_colors = $5,
+  ai_occasions = $6,
+  ai_seasons = $7,
+  ai_style = $8,
+  ai_materials = $9,
+  ai_confidence = $10,
+  ai_status = 'completed',
+  ai_processed_at = NOW(),
+  ai_error_message = NULL,
+  ai_next_attempt_at = NULL
+WHERE id = $1 AND ai_status = 'processing' AND ai_requested_at IS NOT DISTINCT FROM $2
+`
+
+type CompleteWardrobeTaggingParams struct {
+	ID           uuid.UUID
+	RequestedAt  pgtype.Timestamptz
+	AiTags       []byte
+	AiCategory   pgtype.Text
+	AiColors     []byte
+	AiOccasions  []byte
+	AiSeasons    []byte
+	AiStyle      pgtype.Text
+	AiMaterials  []byte
+	AiConfidence pgtype.Float8
+}
+
+func (q *Queries) CompleteWardrobeTagging(ctx context.Context, arg CompleteWardrobeTaggingParams) (int64, error) {
+	result, err := q.db.Exec(ctx, completeWardrobeTagging,
+		arg.ID,
+		arg.RequestedAt,
+		arg.AiTags,
+		arg.AiCategory,
+		arg.AiColors,
+		arg.AiOccasions,
+		arg.AiSeasons,
+		arg.AiStyle,
+		arg.AiMaterials,
+		arg.AiConfidence,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// Put a failed item back in the queue until NextAttemptAt
+const retryWardrobeTagging = `-- name: RetryWardrobeTagging :execrows
+UPDATE wardrobe_items SET
+  ai_status = 'pending',
+  ai_error_message = $3,
+  ai_next_attempt_at = $4
+WHERE id = $1 AND ai_status = 'processing' AND ai_requested_at IS NOT DISTINCT FROM $2
+`
+
+type RetryWardrobeTaggingParams struct {
+	ID            uuid.UUID
+	RequestedAt   pgtype.Timestamptz
+	ErrorMessage  string
+	NextAttemptAt time.Time
+}
+
+func (q *Queries) RetryWardrobeTagging(ctx context.Context, arg RetryWardrobeTaggingParams) (int64, error) {
+	result, err := q.db.Exec(ctx, retryWardrobeTagging, arg.ID, arg.RequestedAt, arg.ErrorMessage, arg.NextAttemptAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const failWardrobeTagging = `-- name: FailWardrobeTagging :execrows
+UPDATE wardrobe_items SET
+  ai_status = 'failed',
+  ai_error_message = $3,
+  ai_processed_at = NOW(),
+  ai_next_attempt_at = NULL
+WHERE id = $1 AND ai_status = 'processing' AND ai_requested_at IS NOT DISTINCT FROM $2
+`
+
+type FailWardrobeTaggingParams struct {
+	ID           uuid.UUID
+	RequestedAt  pgtype.Timestamptz
+	ErrorMessage string
+}
+
+func (q *Queries) FailWardrobeTagging(ctx context.Context, arg FailWardrobeTaggingParams) (int64, error) {
+	result, err := q.db.Exec(ctx, failWardrobeTagging, arg.ID, arg.RequestedAt, arg.ErrorMessage)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// Queue one item for tagging, replacing any earlier request
+const queueWardrobeItemTagging = `-- name: QueueWardrobeItemTagging :execrows
+UPDATE wardrobe_items SET
+  ai_status = 'pending',
+  ai_attempts = 0,
+  ai_requested_at = NOW(),
+  ai_next_attempt_at = NULL,
+  ai_error_message = NULL
+WHERE id = $1 AND user_id = $2
+  AND CASE WHEN jsonb_typeof(images) = 'array' THEN jsonb_array_length(images) ELSE 0 END > 0
+`
+
+type QueueWardrobeItemTaggingParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) QueueWardrobeItemTagging(ctx context.Context, arg QueueWardrobeItemTaggingParams) (int64, error) {
+	result, err := q.db.Exec(ctx, queueWardrobeItemTagging, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// Queue a user's whole wardrobe, or only the items without a completed result
+const queueWardrobeTagging = `-- name: QueueWardrobeTagging :execrows
+UPDATE wardrobe_items SET
+  ai_status = 'pending',
+  ai_attempts = 0,
+  ai_requested_at = NOW(),
+  ai_next_attempt_at = NULL,
+  ai_error_message = NULL
+WHERE user_id = $1
+  AND CASE WHEN jsonb_typeof(images) = 'array' THEN jsonb_array_length(images) ELSE 0 END > 0
+  AND (NOT $2::bool OR ai_status IS DISTINCT FROM 'completed')
+`
+
+type QueueWardrobeTaggingParams struct {
+	UserID       uuid.UUID
+	OnlyUntagged bool
+}
+
+func (q *Queries) QueueWardrobeTagging(ctx context.Context, arg QueueWardrobeTaggingParams) (int64, error) {
+	result, err := q.db.Exec(ctx, queueWardrobeTagging, arg.UserID, arg.OnlyUntagged)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// Copy accepted AI suggestions into the primary fields; NULL leaves a field unchanged
+const acceptWardrobeAISuggestions = `-- name: AcceptWardrobeAISuggestions :one
+UPDATE wardrobe_items SET
+  category = COALESCE($3, category),
+  color = COALESCE($4, color),
+  secondary_colors = COALESCE($5, secondary_colors),
+  occasion = COALESCE($6, occasion),
+  season = COALESCE($7, season),
+  style = COALESCE($8, style),
+  material = COALESCE($9, material),
+  tags = COALESCE($10, tags),
+  updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING
+  id, user_id, name, description, category, subcategory, brand, color,
+  secondary_colors, size, material, style, occasion, season, pattern,
+  images, tags, purchase_date, purchase_price, purchase_location,
+  care_instructions, is_favorite, is_available, is_clean, last_worn,
+  wear_count, condition, quality_score, sustainability_score, metadata,
+  ai_tags, ai_category, ai_colors, ai_occasions, ai_seasons, ai_style,
+  ai_materials, ai_confidence, ai_processed_at, ai_status, ai_error_message,
+  created_at, updated_at
+`
+
+type AcceptWardrobeAISuggestionsParams struct {
+	ID              uuid.UUID
+	UserID          uuid.UUID
+	Category        pgtype.Text
+	Color           pgtype.Text
+	SecondaryColors []byte
+	Occasion        []byte
+	Season          []byte
+	Style           pgtype.Text
+	Material        pgtype.Text
+	Tags            []byte
+}
+
+func (q *Queries) AcceptWardrobeAISuggestions(ctx context.Context, arg AcceptWardrobeAISuggestionsParams) (GetWardrobeItemsRow, error) {
+	var i GetWardrobeItemsRow
+	err := q.db.QueryRow(ctx, acceptWardrobeAISuggestions,
+		arg.ID,
+		arg.UserID,
+		arg.Category,
+		arg.Color,
+		arg.SecondaryColors,
+		arg.Occasion,
+		arg.Season,
+		arg.Style,
+		arg.Material,
+		arg.Tags,
+	).Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Description,
+		&i.Category,
+		&i.Subcategory,
+		&i.Brand,
+		&i.Color,
+		&i.SecondaryColors,
+		&i.Size,
+		&i.Material,
+		&i.Style,
+		&i.Occasion,
+		&i.Season,
+		&i.Pattern,
+		&i.Images,
+		&i.Tags,
+		&i.PurchaseDate,
+		&i.PurchasePrice,
+		&i.PurchaseLocation,
+		&i.CareInstructions,
+		&i.IsFavorite,
+		&i.IsAvailable,
+		&i.IsClean,
+		&i.LastWorn,
+		&i.WearCount,
+		&i.Condition,
+		&i.QualityScore,
+		&i.SustainabilityScore,
+		&i.Metadata,
+		&i.AiTags,
+		&i.AiCategory,
+		&i.AiColors,
+		&i.AiOccasions,
+		&i.AiSeasons,
+		&i.AiStyle,
+		&i.AiMaterials,
+		&i.AiConfidence,
+		&i.AiProcessedAt,
+		&i.AiStatus,
+		&i.AiErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,231 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createWardrobeWearEventsTable = `-- name: CreateWardrobeWearEventsTable :exec
+CREATE TABLE IF NOT EXISTS wardrobe_wear_events (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+  item_id UUID NOT NULL REFERENCES wardrobe_items(id) ON DELETE CASCADE,
+  worn_on DATE NOT NULL,
+  photo_url TEXT,
+  outfit_id UUID,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  UNIQUE (item_id, worn_on)
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_wardrobe_wear_events_user_worn_on ON wardrobe_wear_events(user_id, worn_on);
+
+-- RLS policies
+ALTER TABLE wardrobe_wear_events ENABLE ROW LEVEL SECURITY;
+
+-- Users can view their own wear history
+CREATE POLICY "Users can view own wardrobe wear events" ON wardrobe_wear_events
+  FOR SELECT USING (auth.uid() = user_id);
+`
+
+func (q *Queries) CreateWardrobeWearEventsTable(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, createWardrobeWearEventsTable)
+	return err
+}
+
+type WardrobeWearEventRow struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	ItemID    uuid.UUID
+	WornOn    pgtype.Date
+	PhotoUrl  pgtype.Text
+	OutfitID  pgtype.UUID
+	CreatedAt time.Time
+}
+
+// An item is worn at most once per day. No row is returned for a repeat or for
+// an item the user does not own.
+const createWardrobeWearEvent = `-- name: CreateWardrobeWearEvent :one
+INSERT INTO wardrobe_wear_events (id, user_id, item_id, worn_on, photo_url, outfit_id)
+SELECT $1::uuid, user_id, id, $4::date, $5::text, $6::uuid
+FROM wardrobe_items
+WHERE id = $3 AND user_id = $2
+ON CONFLICT (item_id, worn_on) DO NOTHING
+RETURNING id, user_id, item_id, worn_on, photo_url, outfit_id, created_at
+`
+
+type CreateWardrobeWearEventParams struct {
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	ItemID   uuid.UUID
+	WornOn   pgtype.Date
+	PhotoUrl pgtype.Text
+	OutfitID pgtype.UUID
+}
+
+func (q *Queries) CreateWardrobeWearEvent(ctx context.Context, arg CreateWardrobeWearEventParams) (WardrobeWearEventRow, error) {
+	row := q.db.QueryRow(ctx, createWardrobeWearEvent,
+		arg.ID,
+		arg.UserID,
+		arg.ItemID,
+		arg.WornOn,
+		arg.PhotoUrl,
+		arg.OutfitID,
+	)
+	var i WardrobeWearEventRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ItemID,
+		&i.WornOn,
+		&i.PhotoUrl,
+		&i.OutfitID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+type WardrobeItemWearRow struct {
+	ID        uuid.UUID
+	WearCount int32
+	LastWorn  pgtype.Timestamptz
+}
+
+// Count a new wear; last_worn only moves forward, so back-dated wears keep it
+const recordWardrobeItemWear = `-- name: RecordWardrobeItemWear :one
+UPDATE wardrobe_items SET
+  wear_count = wear_count + 1,
+  last_worn = GREATEST(last_worn, $3::date::timestamptz),
+  updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, wear_count, last_worn
+`
+
+type RecordWardrobeItemWearParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	WornOn pgtype.Date
+}
+
+func (q *Queries) RecordWardrobeItemWear(ctx context.Context, arg RecordWardrobeItemWearParams) (WardrobeItemWearRow, error) {
+	row := q.db.QueryRow(ctx, recordWardrobeItemWear, arg.ID, arg.UserID, arg.WornOn)
+	var i WardrobeItemWearRow
+	err := row.Scan(&i.ID, &i.WearCount, &i.LastWorn)
+	return i, err
+}
+
+const deleteWardrobeWearEvent = `-- name: DeleteWardrobeWearEvent :one
+DELETE FROM wardrobe_wear_events
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, item_id, worn_on, photo_url, outfit_id, created_at
+`
+
+type DeleteWardrobeWearEventParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteWardrobeWearEvent(ctx context.Context, arg DeleteWardrobeWearEventParams) (WardrobeWearEventRow, error) {
+	row := q.db.QueryRow(ctx, deleteWardrobeWearEvent, arg.ID, arg.UserID)
+	var i WardrobeWearEventRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ItemID,
+		&i.WornOn,
+		&i.PhotoUrl,
+		&i.OutfitID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+// Recompute the counters after the wear on WornOn was deleted. wear_count is decremented
+// rather than counted, so wears entered by hand before logging existed are kept, and
+// last_worn falls back to the latest remaining wear only if the deleted one set it.
+const removeWardrobeItemWear = `-- name: RemoveWardrobeItemWear :one
+UPDATE wardrobe_items SET
+  wear_count = GREATEST(wear_count - 1, 0),
+  last_worn = CASE
+    WHEN last_worn >= ($3::date + 1)::timestamptz THEN last_worn
+    ELSE (SELECT MAX(worn_on)::timestamptz FROM wardrobe_wear_events WHERE item_id = $1)
+  END,
+  updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, wear_count, last_worn
+`
+
+type RemoveWardrobeItemWearParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	WornOn pgtype.Date
+}
+
+func (q *Queries) RemoveWardrobeItemWear(ctx context.Context, arg RemoveWardrobeItemWearParams) (WardrobeItemWearRow, error) {
+	row := q.db.QueryRow(ctx, removeWardrobeItemWear, arg.ID, arg.UserID, arg.WornOn)
+	var i WardrobeItemWearRow
+	err := row.Scan(&i.ID, &i.WearCount, &i.LastWorn)
+	return i, err
+}
+
+const listWardrobeWearCalendar = `-- name: ListWardrobeWearCalendar :many
+SELECT
+  e.id, e.item_id, e.worn_on, e.photo_url, e.outfit_id,
+  i.name, i.category, i.images
+FROM wardrobe_wear_events e
+JOIN wardrobe_items i ON i.id = e.item_id
+WHERE e.user_id = $1
+  AND e.worn_on BETWEEN $2 AND $3
+  AND ($4::uuid IS NULL OR e.item_id = $4)
+ORDER BY e.worn_on, e.created_at, e.id
+`
+
+type ListWardrobeWearCalendarParams struct {
+	UserID uuid.UUID
+	From   pgtype.Date
+	To     pgtype.Date
+	ItemID pgtype.UUID
+}
+
+type ListWardrobeWearCalendarRow struct {
+	ID       uuid.UUID
+	ItemID   uuid.UUID
+	WornOn   pgtype.Date
+	PhotoUrl pgtype.Text
+	OutfitID pgtype.UUID
+	Name     string
+	Category string
+	Images   []byte
+}
+
+func (q *Queries) ListWardrobeWearCalendar(ctx context.Context, arg ListWardrobeWearCalendarParams) ([]ListWardrobeWearCalendarRow, error) {
+	rows, err := q.db.Query(ctx, listWardrobeWearCalendar, arg.UserID, arg.From, arg.To, arg.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWardrobeWearCalendarRow
+	for rows.Next() {
+		var i ListWardrobeWearCalendarRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ItemID,
+			&i.WornOn,
+			&i.PhotoUrl,
+			&i.OutfitID,
+			&i.Name,
+			&i.Category,
+			&i.Images,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
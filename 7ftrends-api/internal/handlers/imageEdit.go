@@ -4,72 +4,167 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/config"
 	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/models"
+	"github.com/your-org/7ftrends-api/internal/services"
 	"github.com/your-org/7ftrends-api/internal/utils"
 )
 
 type ImageEditHandler struct {
-	db        *database.Queries
-	uploadsDir string
-	geminiKey  string
+	db            *database.Queries
+	uploadsDir    string
+	generator     services.ImageGenerator
+	prompts       *services.PromptSet
+	fetcher       *services.ImageFetcher
+	maxDimension  int
+	quality       int
+	minConfidence float64
+	monthlyQuotas map[string]int
+	shares        *services.ShareSigner
+	shareTTL      time.Duration
+	shareMaxTTL   time.Duration
+	shareBaseURL  string
+	watermark     bool
+	watermarkText string
+	provenance    *services.ProvenanceSigner
+	preflight     services.PreflightRules
+	jobs          chan tryOnJob
+	queueMu       sync.RWMutex // guards sends to jobs against Close
+	queueClosed   bool
+	jobTimeout    time.Duration
+	batchWorkers  int
+	stop          chan struct{}
+	workers       sync.WaitGroup
 }
 
-func NewImageEditHandler(db *database.Queries) *ImageEditHandler {
+func NewImageEditHandler(db *database.Queries, cfg *config.Config) *ImageEditHandler {
 	uploadsDir := os.Getenv("UPLOADS_DIR")
 	if uploadsDir == "" {
 		uploadsDir = "./uploads"
 	}
 
-	geminiKey := os.Getenv("GEMINI_API_KEY")
-	if geminiKey == "" {
-		log.Println("⚠️ GEMINI_API_KEY not set in environment variables")
+	generator, err := services.NewImageGenerator(cfg.AI)
+	if err != nil {
+		log.Printf("⚠️ Image generation provider unavailable: %v", err)
+	}
+
+	prompts, err := services.LoadPromptSet(cfg.AI.Prompts)
+	if err != nil {
+		log.Printf("⚠️ Try-on prompt templates unavailable: %v", err)
 	}
 
-	return &ImageEditHandler{
-		db:        db,
-		uploadsDir: uploadsDir,
-		geminiKey:  geminiKey,
+	shares, err := services.NewShareSigner(cfg.Share.Secret)
+	if err != nil {
+		log.Printf("⚠️ Try-on sharing unavailable: %v", err)
 	}
+
+	provenance, err := services.NewProvenanceSigner(cfg.Provenance.Secret)
+	if err != nil {
+		log.Printf("⚠️ Signed provenance manifests unavailable: %v", err)
+	}
+
+	// Input images are checked against the fetch size limit as well
+	preflight := services.PreflightRules{
+		MinImageResolution: cfg.Preflight.MinImageResolution,
+		MaxAspectRatio:     cfg.Preflight.MaxAspectRatio,
+		MaxFileSize:        cfg.Storage.MaxFileSize,
+	}
+
+	h := &ImageEditHandler{
+		db:            db,
+		uploadsDir:    uploadsDir,
+		generator:     generator,
+		prompts:       prompts,
+		fetcher:       services.NewImageFetcher(cfg.Storage, cfg.Supabase),
+		maxDimension:  models.MaxImageDimension,
+		quality:       cfg.Storage.CompressionQuality,
+		minConfidence: cfg.AI.MinConfidence,
+		monthlyQuotas: cfg.AI.MonthlyQuotas,
+		shares:        shares,
+		shareTTL:      time.Duration(cfg.Share.TTL) * time.Second,
+		shareMaxTTL:   time.Duration(cfg.Share.MaxTTL) * time.Second,
+		shareBaseURL:  cfg.Share.BaseURL,
+		watermark:     cfg.Provenance.Watermark,
+		watermarkText: cfg.Provenance.WatermarkText,
+		provenance:    provenance,
+		preflight:     preflight,
+		jobs:          make(chan tryOnJob, cfg.AI.QueueSize),
+		jobTimeout:    time.Duration(cfg.AI.JobTimeout) * time.Second,
+		batchWorkers:  cfg.AI.BatchWorkers,
+		stop:          make(chan struct{}),
+	}
+
+	h.recoverStaleJobs()
+	h.startWorkers(cfg.AI.Workers)
+	h.startRetention(cfg.Retention)
+
+	return h
 }
 
 // EditImageRequest represents a virtual try-on request
 type EditImageRequest struct {
-	UserImage      string `json:"userImage" validate:"required"`      // Base64 or URL
-	GarmentImage   string `json:"garmentImage" validate:"required"`   // Base64 or URL
-	Instructions   string `json:"instructions"`                        // Custom overlay instructions
-	Position       string `json:"position" validate:"omitempty,oneof=upper-body lower-body full-body accessory"`
-	Fit            string `json:"fit" validate:"omitempty,oneof=snug regular loose"`
-	Style          string `json:"style" validate:"omitempty,oneof=realistic stylized enhanced"`
+	UserImage       string                `json:"userImage" validate:"required_without=BasePhotoID,excluded_with=BasePhotoID"`            // Base64 or URL
+	BasePhotoID     *uuid.UUID            `json:"basePhotoId,omitempty"`                                                                  // A stored base photo instead of userImage
+	GarmentImage    string                `json:"garmentImage" validate:"required_without=WardrobeItemIDs,excluded_with=WardrobeItemIDs"` // Base64 or URL
+	WardrobeItemIDs []uuid.UUID           `json:"wardrobeItemIds" validate:"omitempty,max=5,unique"`                                      // Layered try-on of the user's own wardrobe items
+	Instructions    string                `json:"instructions"`                                                                           // Custom overlay instructions
+	Position        string                `json:"position" validate:"omitempty,oneof=upper-body lower-body full-body accessory"`
+	Fit             string                `json:"fit" validate:"omitempty,oneof=snug regular loose"`
+	Style           string                `json:"style" validate:"omitempty,oneof=realistic stylized enhanced"`
+	Force           bool                  `json:"force"`              // Skip the result cache and always generate
+	Settings        *models.TryOnSettings `json:"settings,omitempty"` // Background, lighting, filters and output quality
 }
 
 // EditImageResponse represents the response from image editing
 type EditImageResponse struct {
-	Success           bool    `json:"success"`
-	CompositeImageURL string  `json:"compositeImageUrl,omitempty"`
-	EditedImageURL    string  `json:"editedImageUrl,omitempty"`
-	Confidence        float64 `json:"confidence,omitempty"`
-	ProcessingTime    int64   `json:"processingTime,omitempty"`
-	Error             string  `json:"error,omitempty"`
-	Details           struct {
-		ModelUsed             string   `json:"modelUsed"`
-		InputDimensions      Dimensions `json:"inputDimensions"`
-		OutputDimensions     Dimensions `json:"outputDimensions"`
-		AppliedInstructions  []string `json:"appliedInstructions"`
-	} `json:"details,omitempty"`
+	Success           bool             `json:"success"`
+	HistoryID         *uuid.UUID       `json:"historyId,omitempty"`
+	Cached            bool             `json:"cached"`
+	CompositeImageURL string           `json:"compositeImageUrl,omitempty"`
+	EditedImageURL    string           `json:"editedImageUrl,omitempty"`
+	Confidence        float64          `json:"confidence,omitempty"`
+	ProcessingTime    int64            `json:"processingTime,omitempty"`
+	Error             string           `json:"error,omitempty"`
+	Details           EditImageDetails `json:"details,omitempty"`
+	// PreflightIssues are the field-level reasons images failed the preflight checks
+	PreflightIssues []utils.ValidationError `json:"preflightIssues,omitempty"`
+	// ErrorDetail is the underlying failure, kept in logs and history but never returned
+	ErrorDetail string `json:"-"`
+	// CompositeSHA256 identifies the stored file for provenance checks
+	CompositeSHA256 string `json:"-"`
+}
+
+// EditImageDetails describes how a try-on image was produced
+type EditImageDetails struct {
+	ModelUsed           string     `json:"modelUsed"`
+	InputDimensions     Dimensions `json:"inputDimensions"`
+	OutputDimensions    Dimensions `json:"outputDimensions"`
+	OutputMimeType      string     `json:"outputMimeType"`
+	AppliedInstructions []string   `json:"appliedInstructions"`
+}
+
+// EditImageJob is returned when a try-on has been queued for processing
+type EditImageJob struct {
+	ID        uuid.UUID `json:"id"`
+	Status    string    `json:"status"`
+	StatusURL string    `json:"statusUrl"`
 }
 
 type Dimensions struct {
@@ -79,81 +174,43 @@ type Dimensions struct {
 
 // VirtualTryonHistory represents a virtual try-on history record
 type VirtualTryonHistory struct {
-	ID                uuid.UUID `json:"id"`
-	UserID            uuid.UUID `json:"userId"`
-	UserImageUrl      string    `json:"userImageUrl"`
-	GarmentImageUrl   string    `json:"garmentImageUrl"`
-	CompositeImageUrl *string   `json:"compositeImageUrl,omitempty"`
-	Instructions      string    `json:"instructions"`
-	Position          string    `json:"position"`
-	Fit               string    `json:"fit"`
-	Style             string    `json:"style"`
-	Confidence        *float64  `json:"confidence,omitempty"`
-	Status            string    `json:"status"`
-	ProcessingTime    *int64    `json:"processingTime,omitempty"`
-	CreatedAt         time.Time `json:"createdAt"`
-}
-
-// Gemini API request structure
-type GeminiEditRequest struct {
-	Contents []struct {
-		Parts []struct {
-			Text       string `json:"text,omitempty"`
-			InlineData  struct {
-				MimeType string `json:"mimeType"`
-				Data     string `json:"data"`
-			} `json:"inline_data,omitempty"`
-		} `json:"parts"`
-	} `json:"contents"`
-	GenerationConfig struct {
-		Temperature     float64 `json:"temperature"`
-		TopK            int     `json:"topK"`
-		TopP            float64 `json:"topP"`
-		MaxOutputTokens int     `json:"maxOutputTokens"`
-		ResponseMimeType string  `json:"responseMimeType"`
-		ResponseSchema   struct {
-			Type       string `json:"type"`
-			Properties struct {
-				Success struct {
-					Type string `json:"type"`
-				} `json:"success"`
-				Confidence struct {
-					Type    string  `json:"type"`
-					Minimum float64 `json:"minimum"`
-					Maximum float64 `json:"maximum"`
-				} `json:"confidence"`
-				AppliedInstructions struct {
-					Type string `json:"type"`
-					Items struct {
-						Type string `json:"type"`
-					} `json:"items"`
-				} `json:"appliedInstructions"`
-			} `json:"properties"`
-		} `json:"responseSchema"`
-	} `json:"generationConfig"`
-}
-
-// Gemini API response structure
-type GeminiEditResponse struct {
-	Candidates []struct {
-		Content struct {
-			Parts []struct {
-				Text       string `json:"text,omitempty"`
-				InlineData  struct {
-					MimeType string `json:"mimeType"`
-					Data     string `json:"data"`
-				} `json:"inline_data,omitempty"`
-			} `json:"parts"`
-		} `json:"content"`
-	} `json:"candidates"`
-}
-
-// EditImageWithGemini processes a virtual try-on request using Gemini 2.5 Flash Image API
+	ID                uuid.UUID               `json:"id"`
+	UserID            uuid.UUID               `json:"userId"`
+	UserImageUrl      string                  `json:"userImageUrl"`
+	GarmentImageUrl   string                  `json:"garmentImageUrl"`
+	CompositeImageUrl *string                 `json:"compositeImageUrl,omitempty"`
+	Instructions      string                  `json:"instructions"`
+	Position          string                  `json:"position"`
+	Fit               string                  `json:"fit"`
+	Style             string                  `json:"style"`
+	Confidence        *float64                `json:"confidence,omitempty"`
+	Status            string                  `json:"status"`
+	Error             *string                 `json:"error,omitempty"`
+	PreflightIssues   []utils.ValidationError `json:"preflightIssues,omitempty"` // why the images failed the preflight checks
+	ProcessingTime    *int64                  `json:"processingTime,omitempty"`
+	InputDimensions   *Dimensions             `json:"inputDimensions,omitempty"`
+	OutputDimensions  *Dimensions             `json:"outputDimensions,omitempty"`
+	OutputMimeType    *string                 `json:"outputMimeType,omitempty"`
+	WardrobeItemIDs   []uuid.UUID             `json:"wardrobeItemIds,omitempty"`
+	Settings          *models.TryOnSettings   `json:"settings,omitempty"`
+	PromptVersion     *string                 `json:"promptVersion,omitempty"`
+	BatchID           *uuid.UUID              `json:"batchId,omitempty"`
+	BasePhotoID       *uuid.UUID              `json:"basePhotoId,omitempty"`
+	Cached            bool                    `json:"cached"` // completed from an earlier identical try-on
+	CreatedAt         time.Time               `json:"createdAt"`
+	UpdatedAt         time.Time               `json:"updatedAt"`
+}
+
+// EditImageWithGemini queues a virtual try-on request for the configured image generator.
+// It responds with 202 and the history row ID; clients poll GET /image-edit/history/{id}
+// until the status is completed or failed. The worker checks the result cache, so a
+// repeated request completes quickly and the row reports cached. Images failing the
+// preflight checks fail the job, and the row lists why in preflightIssues.
 func (h *ImageEditHandler) EditImageWithGemini(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	userID := auth.GetUserID(ctx)
 
-	if h.geminiKey == "" {
+	if h.generator == nil || h.prompts == nil {
 		utils.RespondWithError(w, http.StatusServiceUnavailable, "Image editing service not available")
 		return
 	}
@@ -169,8 +226,51 @@ func (h *ImageEditHandler) EditImageWithGemini(w http.ResponseWriter, r *http.Re
 		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if req.Settings != nil {
+		if err := req.Settings.Validate(); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	req.Settings = normalizeSettings(req.Settings)
+
+	// Resolve the garments to apply, from a single image or the user's wardrobe
+	garments, err := h.resolveGarments(ctx, userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, errWardrobeItemNotFound):
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, errWardrobeItemNoImage):
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Printf("Error resolving garments: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to load wardrobe items")
+		}
+		return
+	}
+
+	// Reject image sources we will not fetch before queueing any work
+	if req.BasePhotoID != nil {
+		photoURL, ok := h.useBasePhoto(w, r, userID, *req.BasePhotoID)
+		if !ok {
+			return
+		}
+		req.UserImage = photoURL
+	} else if err := h.fetcher.ValidateSource(req.UserImage); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid user image: %v", err))
+		return
+	}
+	for _, garment := range garments {
+		if err := h.fetcher.ValidateSource(garment.Image); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid garment image: %v", err))
+			return
+		}
+	}
 
 	// Set default values
+	if len(req.WardrobeItemIDs) > 0 {
+		req.Position = sessionPosition(garments)
+	}
 	if req.Position == "" {
 		req.Position = "full-body"
 	}
@@ -181,61 +281,133 @@ func (h *ImageEditHandler) EditImageWithGemini(w http.ResponseWriter, r *http.Re
 		req.Style = "realistic"
 	}
 
-	startTime := time.Now()
+	// Pick a prompt variant and render this request's instructions with it
+	promptVersion := h.prompts.Choose(promptKey(userID, req, garments))
+	instructions, err := h.generateOverlayInstructions(promptVersion, req, garments)
+	if err != nil {
+		log.Printf("Error rendering try-on prompt: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to prepare try-on instructions")
+		return
+	}
 
-	// Process the image editing request
-	result, err := h.processImageEdit(ctx, userID, req)
+	// The worker fetches the images and checks the cache, so nothing is downloaded
+	// before the request is accepted
+	inputs, err := h.newTryOnInputs(req, promptVersion, instructions)
 	if err != nil {
-		log.Printf("Error processing image edit: %v", err)
-		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Image editing failed: %v", err))
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Failed to process %v", err))
 		return
 	}
 
-	// Save to history
-	historyID, err := h.saveEditHistory(ctx, userID, req, result)
+	// Fail fast while the provider is down instead of queueing doomed work
+	if !services.GeneratorAvailable(h.generator) {
+		utils.RespondWithJSON(w, http.StatusServiceUnavailable, utils.ErrorResponse(utils.NewAPIError(
+			utils.ErrServiceUnavailable, "SERVICE_UNAVAILABLE",
+			"Image editing service is temporarily unavailable, please try again later",
+			http.StatusServiceUnavailable)))
+		return
+	}
+
+	// Reserve the try-on in the same transaction that records the pending job, so
+	// concurrent requests cannot both take the last one of the month
+	role := userRole(ctx)
+	month := services.QuotaMonth(time.Now())
+	var historyID uuid.UUID
+	err = h.db.ExecTx(ctx, func(q *database.Queries) error {
+		if err := h.reserveQuota(ctx, q, userID, role, month, 1); err != nil {
+			return err
+		}
+		var err error
+		historyID, err = h.createPendingHistory(ctx, q, userID, req, garments, inputs, uuid.Nil)
+		return err
+	})
 	if err != nil {
-		log.Printf("Error saving edit history: %v", err)
-		// Don't fail the request if history saving fails
+		if errors.Is(err, errQuotaExceeded) {
+			h.respondOverQuota(w, ctx, userID, role)
+			return
+		}
+		log.Printf("Error creating edit history: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to queue image edit")
+		return
 	}
 
-	// Log successful editing
-	log.Printf("✅ Virtual try-on completed for user %s, history ID: %s", userID, historyID)
+	job := tryOnJob{HistoryID: historyID, UserID: userID, QuotaMonth: month, Request: req, Garments: garments, Inputs: inputs}
+	if err := h.enqueueTryOnJob(job); err != nil {
+		log.Printf("Error queueing image edit %s: %v", historyID, err)
+		h.updateJobStatus(ctx, job, database.UpdateVirtualTryonHistoryParams{
+			Status:       TryOnStatusFailed,
+			ErrorMessage: pgtype.Text{String: "Try-on service is busy, please try again", Valid: true},
+		})
+		h.releaseQuota(userID, month, 1)
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "Image editing service is busy, please try again shortly")
+		return
+	}
 
-	utils.RespondWithJSON(w, http.StatusOK, result)
+	statusURL := fmt.Sprintf("%s/history/%s", strings.TrimSuffix(r.URL.Path, "/edit"), historyID)
+	w.Header().Set("Location", statusURL)
+	utils.RespondWithJSON(w, http.StatusAccepted, EditImageJob{
+		ID:        historyID,
+		Status:    TryOnStatusPending,
+		StatusURL: statusURL,
+	})
 }
 
 // processImageEdit handles the actual image editing logic
-func (h *ImageEditHandler) processImageEdit(ctx context.Context, userID uuid.UUID, req EditImageRequest) (EditImageResponse, error) {
+func (h *ImageEditHandler) processImageEdit(ctx context.Context, userID, historyID uuid.UUID, inputs *tryOnInputs) (EditImageResponse, error) {
 	startTime := time.Now()
+	userImage := inputs.UserImage
 
-	// Convert images to base64 if they're URLs
-	userImageBase64, err := h.convertToBase64(req.UserImage)
-	if err != nil {
-		return EditImageResponse{Success: false, Error: fmt.Sprintf("Failed to process user image: %v", err)}, nil
+	// Garment images follow the person photo in layer order
+	images := []services.InputImage{{MimeType: userImage.MimeType, Data: userImage.Data}}
+	for _, garmentImage := range inputs.Garments {
+		images = append(images, services.InputImage{MimeType: garmentImage.MimeType, Data: garmentImage.Data})
 	}
 
-	garmentImageBase64, err := h.convertToBase64(req.GarmentImage)
+	// Call the configured image generation provider
+	result, err := h.generator.Generate(ctx, services.GenerateRequest{
+		Instructions: inputs.Instructions,
+		Images:       images,
+	})
 	if err != nil {
-		return EditImageResponse{Success: false, Error: fmt.Sprintf("Failed to process garment image: %v", err)}, nil
+		if errors.Is(err, services.ErrCircuitOpen) {
+			return failedEdit("Image editing service is temporarily unavailable, please try again later", err), nil
+		}
+		return failedEdit("Image generation failed, please try again", err), nil
+	}
+
+	details := EditImageDetails{
+		ModelUsed:       result.Model,
+		InputDimensions: Dimensions{Width: userImage.Width, Height: userImage.Height},
 	}
 
-	// Generate contextual instructions
-	instructions := h.generateOverlayInstructions(req)
+	// Check the model's own assessment before keeping the image
+	report, err := services.ParseTryOnReport(result.Text)
+	if err != nil {
+		return failedEdit("Model returned an invalid result", err), nil
+	}
+	details.AppliedInstructions = report.AppliedInstructions
+	if reason := h.rejectionReason(report); reason != "" {
+		return EditImageResponse{
+			Success:    false,
+			Confidence: report.Confidence,
+			Error:      reason,
+			Details:    details,
+		}, nil
+	}
 
-	// Call Gemini 2.5 Flash Image API
-	geminiResponse, err := h.callGeminiAPI(userImageBase64, garmentImageBase64, instructions)
+	// Re-encode the generated image so the stored file matches its MIME type
+	composite, err := services.NormalizeImage(result.Image, inputs.MaxDimension, inputs.Quality)
 	if err != nil {
-		return EditImageResponse{Success: false, Error: fmt.Sprintf("Gemini API error: %v", err)}, nil
+		return failedEdit("Failed to process generated image", err), nil
 	}
 
-	// Extract edited image
-	editedImageBase64 := h.extractEditedImageFromResponse(geminiResponse)
-	if editedImageBase64 == "" {
-		return EditImageResponse{Success: false, Error: "Failed to extract edited image from API response"}, nil
+	// Mark the composite as AI-generated before it is stored or returned
+	composite, manifest, err := h.applyProvenance(composite, historyID, result.Model, inputs.Quality)
+	if err != nil {
+		return failedEdit("Failed to process generated image", err), nil
 	}
 
 	// Upload composite image to storage
-	compositeImageURL, err := h.uploadCompositeImage(ctx, userID, editedImageBase64)
+	compositeImageURL, err := h.uploadCompositeImage(ctx, userID, composite, manifest)
 	if err != nil {
 		log.Printf("Warning: Failed to upload composite image: %v", err)
 		// Continue without storage URL
@@ -244,344 +416,290 @@ func (h *ImageEditHandler) processImageEdit(ctx context.Context, userID uuid.UUI
 
 	processingTime := time.Since(startTime).Milliseconds()
 
-	// Extract confidence from response
-	confidence := h.extractConfidenceFromResponse(geminiResponse)
+	details.OutputDimensions = Dimensions{Width: composite.Width, Height: composite.Height}
+	details.OutputMimeType = composite.MimeType
 
-	return EditImageResponse{
+	response := EditImageResponse{
 		Success:           true,
 		CompositeImageURL: compositeImageURL,
-		EditedImageURL:    fmt.Sprintf("data:image/jpeg;base64,%s", editedImageBase64),
-		Confidence:        confidence,
+		EditedImageURL:    fmt.Sprintf("data:%s;base64,%s", composite.MimeType, utils.EncodeBase64(composite.Data)),
+		Confidence:        report.Confidence,
 		ProcessingTime:    processingTime,
-		Details: struct {
-			ModelUsed             string   `json:"modelUsed"`
-			InputDimensions      Dimensions `json:"inputDimensions"`
-			OutputDimensions     Dimensions `json:"outputDimensions"`
-			AppliedInstructions  []string `json:"appliedInstructions"`
-		}{
-			ModelUsed:             "gemini-2.5-flash-image",
-			InputDimensions:      Dimensions{}, // Could be extracted from images
-			OutputDimensions:     Dimensions{}, // Could be extracted from response
-			AppliedInstructions:  []string{instructions},
-		},
-	}, nil
-}
-
-// convertToBase64 converts image URL or base64 to base64 string
-func (h *ImageEditHandler) convertToBase64(imageSource string) (string, error) {
-	// If already base64 (starts with data:), extract the base64 part
-	if strings.HasPrefix(imageSource, "data:") {
-		parts := strings.Split(imageSource, ",")
-		if len(parts) != 2 {
-			return "", fmt.Errorf("invalid base64 data URL format")
-		}
-		return parts[1], nil
+		Details:           details,
 	}
+	if compositeImageURL != "" {
+		response.CompositeSHA256 = services.ImageSHA256(composite.Data)
+	}
+	return response, nil
+}
 
-	// If it's a URL, download and convert
-	if strings.HasPrefix(imageSource, "http://") || strings.HasPrefix(imageSource, "https://") {
-		resp, err := http.Get(imageSource)
-		if err != nil {
-			return "", fmt.Errorf("failed to download image: %v", err)
-		}
-		defer resp.Body.Close()
+// failedEdit builds a failed result with a client-safe message, keeping the
+// underlying error for logs and the history row
+func failedEdit(message string, err error) EditImageResponse {
+	return EditImageResponse{Success: false, Error: message, ErrorDetail: err.Error()}
+}
+
+// rejectionReason returns why a model report should fail the try-on, or "" to accept it
+func (h *ImageEditHandler) rejectionReason(report *services.TryOnReport) string {
+	if !report.Success {
+		return "Model reported the garment could not be applied"
+	}
+	if report.Confidence < h.minConfidence {
+		return fmt.Sprintf("Result confidence %.2f is below the minimum of %.2f", report.Confidence, h.minConfidence)
+	}
+	return ""
+}
+
+// tryOnInputs are the normalized images and prompt for one generation
+type tryOnInputs struct {
+	UserImage     *services.ProcessedImage
+	Garments      []*services.ProcessedImage
+	Instructions  string
+	PromptVersion string
+	Settings      []byte // stored form of the request settings, nil without settings
+	MaxDimension  int    // output size and JPEG quality from the settings quality
+	Quality       int
+	Hash          string // empty until the images are loaded
+}
+
+// newTryOnInputs prepares the prompt and output settings of a try-on before any
+// image is loaded
+func (h *ImageEditHandler) newTryOnInputs(req EditImageRequest, promptVersion, instructions string) (*tryOnInputs, error) {
+	settings, err := encodeSettings(req.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("settings: %w", err)
+	}
+
+	inputs := &tryOnInputs{
+		Instructions:  instructions,
+		PromptVersion: promptVersion,
+		Settings:      settings,
+	}
+	inputs.MaxDimension, inputs.Quality = h.outputParams(req.Settings)
+	return inputs, nil
+}
 
-		if resp.StatusCode != http.StatusOK {
-			return "", fmt.Errorf("image download failed with status: %d", resp.StatusCode)
+// loadTryOnImages loads and normalizes every input image of a queued try-on and
+// derives its cache key. A user image already in inputs, such as one a batch
+// shares across its garments, is not loaded again.
+func (h *ImageEditHandler) loadTryOnImages(ctx context.Context, inputs *tryOnInputs, req EditImageRequest, garments []services.GarmentLayer) error {
+	userImage := inputs.UserImage
+	if userImage == nil {
+		var err error
+		if userImage, err = h.loadUserImage(ctx, req.UserImage, req.BasePhotoID); err != nil {
+			return fmt.Errorf("user image: %w", inputField(err, "userImage"))
 		}
+	}
+
+	return h.loadGarmentImages(ctx, inputs, userImage, req, garments)
+}
 
-		// Read the image data
-		imageData, err := io.ReadAll(resp.Body)
+// loadGarmentImages adds the user image and the normalized garment images to
+// inputs, and hashes them so identical try-ons share a cache key
+func (h *ImageEditHandler) loadGarmentImages(ctx context.Context, inputs *tryOnInputs, userImage *services.ProcessedImage, req EditImageRequest, garments []services.GarmentLayer) error {
+	inputs.UserImage = userImage
+	hashed := [][]byte{userImage.Data}
+	for _, garment := range garments {
+		garmentImage, err := h.loadInputImage(ctx, garment.Image)
 		if err != nil {
-			return "", fmt.Errorf("failed to read image data: %v", err)
+			return fmt.Errorf("garment image: %w", inputField(err, garmentField(garment, req.WardrobeItemIDs)))
 		}
+		inputs.Garments = append(inputs.Garments, garmentImage)
+		hashed = append(hashed, garmentImage.Data)
+	}
 
-		// Convert to base64
-		return utils.EncodeBase64(imageData), nil
-	}
-
-	return "", fmt.Errorf("unsupported image source format")
-}
-
-// generateOverlayInstructions creates contextual instructions for garment overlay
-func (h *ImageEditHandler) generateOverlayInstructions(req EditImageRequest) string {
-	baseInstructions := "Create a realistic virtual try-on image by overlaying the garment onto the user photo. Ensure natural fitting, proper shadows, and realistic blending."
-
-	positionInstructions := map[string]string{
-		"upper-body": "Focus on upper body placement. Ensure proper alignment with shoulders, chest, and arms.",
-		"lower-body": "Focus on lower body placement. Ensure proper alignment with waist, hips, and legs.",
-		"full-body":  "Place garment on appropriate body section with full-body visibility.",
-		"accessory":  "Position accessory naturally on the user (hat on head, bag in hand, watch on wrist, etc.).",
-	}
-
-	fitInstructions := map[string]string{
-		"snug":   "Apply with close fit to body, showing natural contours.",
-		"regular": "Apply with standard fit, neither too tight nor too loose.",
-		"loose":  "Apply with relaxed fit, showing natural draping and movement.",
-	}
-
-	styleInstructions := map[string]string{
-		"realistic":  "Create photorealistic result with accurate lighting, shadows, and textures.",
-		"stylized":   "Apply artistic enhancement while maintaining recognizable features.",
-		"enhanced":   "Improve overall appearance with subtle enhancements to lighting and colors.",
-	}
-
-	instructions := baseInstructions
-
-	if posInstr, exists := positionInstructions[req.Position]; exists {
-		instructions += " " + posInstr
-	}
-
-	if fitInstr, exists := fitInstructions[req.Fit]; exists {
-		instructions += " " + fitInstr
-	}
-
-	if styleInstr, exists := styleInstructions[req.Style]; exists {
-		instructions += " " + styleInstr
-	}
-
-	if req.Instructions != "" {
-		instructions += " Additional requirements: " + req.Instructions
-	}
-
-	return instructions
-}
-
-// callGeminiAPI makes the actual API call to Gemini 2.5 Flash Image API
-func (h *ImageEditHandler) callGeminiAPI(userImageBase64, garmentImageBase64, instructions string) (*GeminiEditResponse, error) {
-	requestBody := GeminiEditRequest{
-		Contents: []struct {
-			Parts []struct {
-				Text      string `json:"text,omitempty"`
-				InlineData struct {
-					MimeType string `json:"mimeType"`
-					Data     string `json:"data"`
-				} `json:"inline_data,omitempty"`
-			} `json:"parts"`
-		}{
-			{
-				Parts: []struct {
-					Text      string `json:"text,omitempty"`
-					InlineData struct {
-						MimeType string `json:"mimeType"`
-						Data     string `json:"data"`
-					} `json:"inline_data,omitempty"`
-				}{
-					{Text: instructions},
-					{
-						InlineData: struct {
-							MimeType string `json:"mimeType"`
-							Data     string `json:"data"`
-						}{
-							MimeType: "image/jpeg",
-							Data:     userImageBase64,
-						},
-					},
-					{
-						InlineData: struct {
-							MimeType string `json:"mimeType"`
-							Data     string `json:"data"`
-						}{
-							MimeType: "image/jpeg",
-							Data:     garmentImageBase64,
-						},
-					},
-				},
-			},
-		},
-		GenerationConfig: struct {
-			Temperature     float64 `json:"temperature"`
-			TopK            int     `json:"topK"`
-			TopP            float64 `json:"topP"`
-			MaxOutputTokens int     `json:"maxOutputTokens"`
-			ResponseMimeType string  `json:"responseMimeType"`
-			ResponseSchema   struct {
-				Type       string `json:"type"`
-				Properties struct {
-					Success struct {
-						Type string `json:"type"`
-					} `json:"success"`
-					Confidence struct {
-						Type    string  `json:"type"`
-						Minimum float64 `json:"minimum"`
-						Maximum float64 `json:"maximum"`
-					} `json:"confidence"`
-					AppliedInstructions struct {
-						Type string `json:"type"`
-						Items struct {
-							Type string `json:"type"`
-						} `json:"items"`
-					} `json:"appliedInstructions"`
-				} `json:"properties"`
-			} `json:"responseSchema"`
-		}{
-			Temperature:     0.1,
-			TopK:            32,
-			TopP:            0.95,
-			MaxOutputTokens: 1024,
-			ResponseMimeType: "application/json",
-			ResponseSchema: struct {
-				Type       string `json:"type"`
-				Properties struct {
-					Success struct {
-						Type string `json:"type"`
-					} `json:"success"`
-					Confidence struct {
-						Type    string  `json:"type"`
-						Minimum float64 `json:"minimum"`
-						Maximum float64 `json:"maximum"`
-					} `json:"confidence"`
-					AppliedInstructions struct {
-						Type string `json:"type"`
-						Items struct {
-							Type string `json:"type"`
-						} `json:"items"`
-					} `json:"appliedInstructions"`
-				} `json:"properties"`
-			}{
-				Type: "object",
-				Properties: struct {
-					Success struct {
-						Type string `json:"type"`
-					} `json:"success"`
-					Confidence struct {
-						Type    string  `json:"type"`
-						Minimum float64 `json:"minimum"`
-						Maximum float64 `json:"maximum"`
-					} `json:"confidence"`
-					AppliedInstructions struct {
-						Type string `json:"type"`
-						Items struct {
-							Type string `json:"type"`
-						} `json:"items"`
-					} `json:"appliedInstructions"`
-				},
-			},
-		},
-	}
-
-	// Convert request to JSON
-	requestJSON, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	inputs.Hash = services.TryOnCacheKey(inputs.Instructions, req.Position, req.Fit, req.Style, string(inputs.Settings), hashed...)
+	return nil
+}
+
+// cachedEditResponse builds an edit response from a previously completed try-on
+func cachedEditResponse(item database.GetVirtualTryonHistoryRow) EditImageResponse {
+	historyItem := convertHistoryRow(item)
+
+	response := EditImageResponse{
+		Success:           true,
+		HistoryID:         &historyItem.ID,
+		Cached:            true,
+		CompositeImageURL: item.CompositeImageUrl.String,
+	}
+	if historyItem.Confidence != nil {
+		response.Confidence = *historyItem.Confidence
+	}
+	if historyItem.InputDimensions != nil {
+		response.Details.InputDimensions = *historyItem.InputDimensions
+	}
+	if historyItem.OutputDimensions != nil {
+		response.Details.OutputDimensions = *historyItem.OutputDimensions
+	}
+	if historyItem.OutputMimeType != nil {
+		response.Details.OutputMimeType = *historyItem.OutputMimeType
 	}
 
-	// Make HTTP request to Gemini API
-	resp, err := http.Post(
-		fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash-image:edit?key=%s", h.geminiKey),
-		"application/json",
-		strings.NewReader(string(requestJSON)),
-	)
+	return response
+}
+
+// loadInputImage fetches an input image, runs the preflight checks and normalizes
+// its format, orientation and size
+func (h *ImageEditHandler) loadInputImage(ctx context.Context, source string) (*services.ProcessedImage, error) {
+	fetched, err := h.fetcher.Fetch(ctx, source)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request to Gemini API: %v", err)
+		if errors.Is(err, services.ErrImageTooLarge) {
+			return nil, services.FileTooLargeError(h.preflight.MaxFileSize)
+		}
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Gemini API error: status=%d, body=%s", resp.StatusCode, string(body))
+	if err := h.preflightImage(fetched.Data); err != nil {
+		return nil, err
 	}
 
-	// Parse response
-	var geminiResponse GeminiEditResponse
-	if err := json.NewDecoder(resp.Body).Decode(&geminiResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse Gemini response: %v", err)
+	normalized, err := services.NormalizeImage(fetched.Data, h.maxDimension, h.quality)
+	if errors.Is(err, services.ErrUndecodableImage) {
+		// The header was readable but the image data is not, e.g. a truncated upload
+		return nil, services.UndecodableImageError()
 	}
+	return normalized, err
+}
 
-	return &geminiResponse, nil
+// promptKey identifies a try-on by its user and image sources without fetching
+// anything, so the same request always renders with the same prompt version
+func promptKey(userID uuid.UUID, req EditImageRequest, garments []services.GarmentLayer) string {
+	parts := []string{userID.String(), req.UserImage}
+	if req.BasePhotoID != nil {
+		parts[1] = req.BasePhotoID.String()
+	}
+	for _, garment := range garments {
+		parts = append(parts, garment.Image)
+	}
+	return strings.Join(parts, "\x00")
 }
 
-// extractEditedImageFromResponse extracts the edited image from Gemini response
-func (h *ImageEditHandler) extractEditedImageFromResponse(response *GeminiEditResponse) string {
-	for _, candidate := range response.Candidates {
-		for _, part := range candidate.Content.Parts {
-			if part.InlineData.Data != "" && strings.HasPrefix(part.InlineData.MimeType, "image/") {
-				return part.InlineData.Data
-			}
+// generateOverlayInstructions renders the prompt template for version with the
+// request's position, fit, style and garment layers
+func (h *ImageEditHandler) generateOverlayInstructions(version string, req EditImageRequest, garments []services.GarmentLayer) (string, error) {
+	data := services.PromptData{
+		Position:     req.Position,
+		Fit:          req.Fit,
+		Style:        req.Style,
+		Settings:     promptSettings(req.Settings),
+		Instructions: req.Instructions,
+	}
+
+	if len(garments) > 1 {
+		for i, garment := range garments {
+			data.Layers = append(data.Layers, services.PromptLayer{
+				Number:   i + 1,
+				Category: garment.Category,
+				Position: garment.Position,
+			})
 		}
 	}
-	return ""
-}
 
-// extractConfidenceFromResponse extracts confidence score from Gemini response
-func (h *ImageEditHandler) extractConfidenceFromResponse(response *GeminiEditResponse) float64 {
-	// This would need to be parsed from the text response if Gemini provides confidence
-	// For now, return a default confidence
-	return 0.85
+	return h.prompts.Render(version, data)
 }
 
-// uploadCompositeImage saves the composite image to storage
-func (h *ImageEditHandler) uploadCompositeImage(ctx context.Context, userID uuid.UUID, base64Image string) (string, error) {
+// uploadCompositeImage saves the composite image to storage, with its signed
+// provenance manifest alongside when there is one
+func (h *ImageEditHandler) uploadCompositeImage(ctx context.Context, userID uuid.UUID, composite *services.ProcessedImage, manifest *services.ProvenanceManifest) (string, error) {
 	// Create user-specific directory
 	userDir := filepath.Join(h.uploadsDir, "virtual-tryon", userID.String())
 	if err := os.MkdirAll(userDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create upload directory: %v", err)
 	}
 
-	// Generate unique filename
-	filename := fmt.Sprintf("%d-composite.jpg", time.Now().Unix())
+	// Random filename so composites cannot be guessed from the user ID and time
+	filename := uuid.New().String() + services.ImageExtension(composite.MimeType)
 	filePath := filepath.Join(userDir, filename)
 
-	// Decode base64
-	imageData, err := utils.DecodeBase64(base64Image)
-	if err != nil {
-		return "", fmt.Errorf("failed to decode base64 image: %v", err)
-	}
-
 	// Write file
-	if err := os.WriteFile(filePath, imageData, 0644); err != nil {
+	if err := os.WriteFile(filePath, composite.Data, 0644); err != nil {
 		return "", fmt.Errorf("failed to write image file: %v", err)
 	}
 
+	if manifest != nil {
+		manifestJSON, err := json.Marshal(manifest)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode provenance manifest: %v", err)
+		}
+		if err := os.WriteFile(filePath+manifestSuffix, manifestJSON, 0644); err != nil {
+			return "", fmt.Errorf("failed to write provenance manifest: %v", err)
+		}
+	}
+
 	// Return public URL (this would be configured based on your setup)
 	return fmt.Sprintf("/uploads/virtual-tryon/%s/%s", userID.String(), filename), nil
 }
 
-// saveEditHistory saves the edit attempt to database
-func (h *ImageEditHandler) saveEditHistory(ctx context.Context, userID uuid.UUID, req EditImageRequest, result EditImageResponse) (uuid.UUID, error) {
-	historyID := uuid.New()
-	now := time.Now()
-
-	var compositeImageURL pgtype.Text
-	if result.CompositeImageURL != "" {
-		compositeImageURL = pgtype.Text{String: result.CompositeImageURL, Valid: true}
+// linkCompositeImage stores an existing composite under a new name, with its
+// provenance manifest when there is one, so each history row owns its file and
+// retention can delete one without breaking the other
+func (h *ImageEditHandler) linkCompositeImage(userID uuid.UUID, compositeURL string) (string, error) {
+	source, err := h.uploadFilePath(compositeURL)
+	if err != nil {
+		return "", err
 	}
 
-	var confidence pgtype.Float8
-	if result.Success {
-		confidence = pgtype.Float8{Float64: result.Confidence, Valid: true}
+	filename := uuid.New().String() + filepath.Ext(source)
+	target := filepath.Join(h.uploadsDir, "virtual-tryon", userID.String(), filename)
+	if err := os.Link(source, target); err != nil {
+		return "", fmt.Errorf("failed to link composite: %v", err)
 	}
-
-	var processingTime pgtype.Int8
-	if result.ProcessingTime > 0 {
-		processingTime = pgtype.Int8{Int64: result.ProcessingTime, Valid: true}
+	if err := os.Link(source+manifestSuffix, target+manifestSuffix); err != nil && !os.IsNotExist(err) {
+		os.Remove(target)
+		return "", fmt.Errorf("failed to link provenance manifest: %v", err)
 	}
 
-	params := database.CreateVirtualTryonHistoryParams{
-		ID:                historyID,
-		UserID:            userID,
-		UserImageUrl:      req.UserImage,
-		GarmentImageUrl:   req.GarmentImage,
-		CompositeImageUrl: compositeImageURL,
-		Instructions:      req.Instructions,
-		Position:          req.Position,
-		Fit:               req.Fit,
-		Style:             req.Style,
-		Confidence:        confidence,
-		Status:            map[bool]string{true: "completed", false: "failed"}[result.Success],
-		ProcessingTime:    processingTime,
-		CreatedAt:         now,
+	return fmt.Sprintf("/uploads/virtual-tryon/%s/%s", userID.String(), filename), nil
+}
+
+// createPendingHistory records a queued try-on in the database
+// batchID links the rows of a batch try-on and is uuid.Nil otherwise.
+func (h *ImageEditHandler) createPendingHistory(ctx context.Context, q *database.Queries, userID uuid.UUID, req EditImageRequest, garments []services.GarmentLayer, inputs *tryOnInputs, batchID uuid.UUID) (uuid.UUID, error) {
+	historyID := uuid.New()
+
+	// A layered try-on is one session listing every applied wardrobe item
+	var wardrobeItemIDs []byte
+	if len(req.WardrobeItemIDs) > 0 {
+		itemIDs := make([]uuid.UUID, len(garments))
+		for i, garment := range garments {
+			itemIDs[i] = garment.ItemID
+		}
+		var err error
+		if wardrobeItemIDs, err = json.Marshal(itemIDs); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to encode wardrobe item IDs: %v", err)
+		}
 	}
 
-	err := h.db.CreateVirtualTryonHistory(ctx, params)
-	if err != nil {
+	params := database.CreateVirtualTryonHistoryParams{
+		ID:              historyID,
+		UserID:          userID,
+		UserImageUrl:    req.UserImage,
+		GarmentImageUrl: garments[0].Image,
+		Instructions:    req.Instructions,
+		Position:        req.Position,
+		Fit:             req.Fit,
+		Style:           req.Style,
+		Status:          TryOnStatusPending,
+		CreatedAt:       time.Now(),
+		WardrobeItemIds: wardrobeItemIDs,
+		InputHash:       pgtype.Text{String: inputs.Hash, Valid: inputs.Hash != ""},
+		PromptVersion:   pgtype.Text{String: inputs.PromptVersion, Valid: true},
+		BatchID:         pgtype.UUID{Bytes: batchID, Valid: batchID != uuid.Nil},
+		Settings:        inputs.Settings,
+	}
+	if req.BasePhotoID != nil {
+		params.BasePhotoID = pgtype.UUID{Bytes: *req.BasePhotoID, Valid: true}
+	}
+
+	if _, err := q.CreateVirtualTryonHistory(ctx, params); err != nil {
 		return uuid.Nil, fmt.Errorf("failed to save edit history: %v", err)
 	}
 
 	return historyID, nil
 }
 
-// GetEditHistory retrieves user's virtual try-on history
+// GetEditHistory retrieves user's virtual try-on history, newest first. It
+// supports status, style, position, from/to and minConfidence filters, and pages
+// with the opaque nextCursor from the previous response.
 func (h *ImageEditHandler) GetEditHistory(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	userID := auth.GetUserID(ctx)
@@ -593,51 +711,75 @@ func (h *ImageEditHandler) GetEditHistory(w http.ResponseWriter, r *http.Request
 		limit = 20
 	}
 
-	offsetStr := r.URL.Query().Get("offset")
-	offset, _ := strconv.Atoi(offsetStr)
-	if offset < 0 {
-		offset = 0
+	// Fetch one extra row to tell whether another page follows
+	params, err := parseHistoryFilters(r.URL.Query(), userID, limit+1)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	// Fetch history from database
-	history, err := h.db.GetVirtualTryonHistory(ctx, database.GetVirtualTryonHistoryParams{
-		UserID: userID,
-		Limit:  int32(limit),
-		Offset: int32(offset),
-	})
+	history, err := h.db.GetVirtualTryonHistory(ctx, params)
 	if err != nil {
 		log.Printf("Error getting edit history: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve edit history")
 		return
 	}
 
+	hasMore := len(history) > limit
+	if hasMore {
+		history = history[:limit]
+	}
+
 	// Convert to response format
 	historyItems := make([]VirtualTryonHistory, len(history))
 	for i, item := range history {
-		historyItems[i] = VirtualTryonHistory{
-			ID:                item.ID,
-			UserID:            item.UserID,
-			UserImageUrl:      item.UserImageUrl,
-			GarmentImageUrl:   item.GarmentImageUrl,
-			CompositeImageUrl: utils.StringPtr(item.CompositeImageUrl.String),
-			Instructions:      item.Instructions,
-			Position:          item.Position,
-			Fit:               item.Fit,
-			Style:             item.Style,
-			Confidence:        utils.Float64Ptr(item.Confidence.Float64),
-			Status:            item.Status,
-			ProcessingTime:    utils.Int64Ptr(item.ProcessingTime.Int64),
-			CreatedAt:         item.CreatedAt,
-		}
+		historyItems[i] = convertHistoryRow(item)
+	}
+
+	var nextCursor *string
+	if hasMore {
+		last := history[len(history)-1]
+		cursor := encodeHistoryCursor(last.CreatedAt, last.ID)
+		nextCursor = &cursor
 	}
 
 	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"history": historyItems,
-		"limit":   limit,
-		"offset":  offset,
+		"history":    historyItems,
+		"limit":      limit,
+		"nextCursor": nextCursor,
+		"hasMore":    hasMore,
 	})
 }
 
+// GetEditHistoryItem retrieves a single virtual try-on, used to poll job status
+func (h *ImageEditHandler) GetEditHistoryItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	historyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid history ID")
+		return
+	}
+
+	item, err := h.db.GetVirtualTryonHistoryByID(ctx, database.GetVirtualTryonHistoryByIDParams{
+		ID:     historyID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "History item not found")
+			return
+		}
+		log.Printf("Error getting edit history item: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve history item")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, convertHistoryRow(item))
+}
+
 // DeleteEditHistory deletes a virtual try-on history item
 func (h *ImageEditHandler) DeleteEditHistory(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -668,6 +810,12 @@ func (h *ImageEditHandler) DeleteEditHistory(w http.ResponseWriter, r *http.Requ
 	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "History item deleted successfully"})
 }
 
+// UsageStatsResponse is the user's usage statistics with their ratings
+type UsageStatsResponse struct {
+	database.GetVirtualTryonStatsRow
+	Ratings RatingStats `json:"ratings"`
+}
+
 // GetUsageStats retrieves usage statistics for image editing
 func (h *ImageEditHandler) GetUsageStats(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -680,17 +828,174 @@ func (h *ImageEditHandler) GetUsageStats(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	ratings, err := h.ratingStats(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+	if err != nil {
+		log.Printf("Error getting rating stats: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve usage statistics")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, UsageStatsResponse{
+		GetVirtualTryonStatsRow: stats,
+		Ratings:                 ratings,
+	})
+}
+
+// PromptVersionStats compares try-on outcomes for one prompt version
+type PromptVersionStats struct {
+	PromptVersion           string  `json:"promptVersion"`
+	TotalEdits              int64   `json:"totalEdits"`
+	SuccessfulEdits         int64   `json:"successfulEdits"`
+	FailedEdits             int64   `json:"failedEdits"`
+	SuccessRate             float64 `json:"successRate"`
+	AvgConfidence           float64 `json:"avgConfidence"`
+	AvgSuccessfulConfidence float64 `json:"avgSuccessfulConfidence"`
+	AverageProcessingTime   float64 `json:"averageProcessingTime"`
+}
+
+// GetPromptStats compares success and confidence across prompt versions for the
+// current user, or for all users with ?scope=all (admins only)
+func (h *ImageEditHandler) GetPromptStats(w http.ResponseWriter, r *http.Request) {
+	filter, ok := statsScope(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.GetVirtualTryonPromptStats(r.Context(), filter)
+	if err != nil {
+		log.Printf("Error getting prompt stats: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve prompt statistics")
+		return
+	}
+
+	stats := make([]PromptVersionStats, len(rows))
+	for i, row := range rows {
+		stats[i] = PromptVersionStats{
+			PromptVersion:           row.PromptVersion,
+			TotalEdits:              row.TotalEdits,
+			SuccessfulEdits:         row.SuccessfulEdits,
+			FailedEdits:             row.FailedEdits,
+			AvgConfidence:           row.AvgConfidence,
+			AvgSuccessfulConfidence: row.AvgSuccessfulConfidence,
+			AverageProcessingTime:   row.AverageProcessingTime,
+		}
+		// Success rate over finished try-ons only
+		if finished := row.SuccessfulEdits + row.FailedEdits; finished > 0 {
+			stats[i].SuccessRate = float64(row.SuccessfulEdits) / float64(finished)
+		}
+	}
+
 	utils.RespondWithJSON(w, http.StatusOK, stats)
 }
 
+// convertHistoryRow converts a database history row to its response format
+func convertHistoryRow(item database.GetVirtualTryonHistoryRow) VirtualTryonHistory {
+	historyItem := VirtualTryonHistory{
+		ID:              item.ID,
+		UserID:          item.UserID,
+		UserImageUrl:    item.UserImageUrl,
+		GarmentImageUrl: item.GarmentImageUrl,
+		Instructions:    item.Instructions,
+		Position:        item.Position,
+		Fit:             item.Fit,
+		Style:           item.Style,
+		Status:          item.Status,
+		Cached:          item.Cached,
+		CreatedAt:       item.CreatedAt,
+		UpdatedAt:       item.UpdatedAt,
+	}
+
+	if item.CompositeImageUrl.Valid {
+		historyItem.CompositeImageUrl = &item.CompositeImageUrl.String
+	}
+	if item.Confidence.Valid {
+		historyItem.Confidence = &item.Confidence.Float64
+	}
+	if item.ProcessingTime.Valid {
+		historyItem.ProcessingTime = &item.ProcessingTime.Int64
+	}
+	if item.ErrorMessage.Valid {
+		historyItem.Error = &item.ErrorMessage.String
+	}
+	if item.InputWidth.Valid && item.InputHeight.Valid {
+		historyItem.InputDimensions = &Dimensions{Width: int(item.InputWidth.Int32), Height: int(item.InputHeight.Int32)}
+	}
+	if item.OutputWidth.Valid && item.OutputHeight.Valid {
+		historyItem.OutputDimensions = &Dimensions{Width: int(item.OutputWidth.Int32), Height: int(item.OutputHeight.Int32)}
+	}
+	if item.OutputMimeType.Valid {
+		historyItem.OutputMimeType = &item.OutputMimeType.String
+	}
+	if item.PromptVersion.Valid {
+		historyItem.PromptVersion = &item.PromptVersion.String
+	}
+	if len(item.Settings) > 0 {
+		var settings models.TryOnSettings
+		if err := json.Unmarshal(item.Settings, &settings); err != nil {
+			log.Printf("Error parsing try-on settings: %v", err)
+		} else {
+			historyItem.Settings = &settings
+		}
+	}
+	if item.BatchID.Valid {
+		batchID := uuid.UUID(item.BatchID.Bytes)
+		historyItem.BatchID = &batchID
+	}
+	if item.BasePhotoID.Valid {
+		basePhotoID := uuid.UUID(item.BasePhotoID.Bytes)
+		historyItem.BasePhotoID = &basePhotoID
+	}
+	if len(item.WardrobeItemIds) > 0 {
+		if err := json.Unmarshal(item.WardrobeItemIds, &historyItem.WardrobeItemIDs); err != nil {
+			log.Printf("Error parsing wardrobe item IDs: %v", err)
+		}
+	}
+	if len(item.PreflightIssues) > 0 {
+		if err := json.Unmarshal(item.PreflightIssues, &historyItem.PreflightIssues); err != nil {
+			log.Printf("Error parsing preflight issues: %v", err)
+		}
+	}
+
+	return historyItem
+}
+
 // RegisterRoutes registers image editing routes
 func (h *ImageEditHandler) RegisterRoutes(r chi.Router) {
 	r.Route("/image-edit", func(r chi.Router) {
 		r.Post("/edit", h.EditImageWithGemini)
+		r.Post("/batch", h.BatchEditImages)
 		r.Get("/history", h.GetEditHistory)
 		r.Get("/stats", h.GetUsageStats)
+		r.Get("/stats/prompts", h.GetPromptStats)
+		r.Get("/stats/ratings", h.GetRatingStats)
+		r.Get("/quota", h.GetQuota)
+		r.Get("/base-photos", h.ListBasePhotos)
+		r.Post("/base-photos", h.CreateBasePhoto)
+		r.Put("/base-photos/{photoId}", h.ReplaceBasePhoto)
+		r.Delete("/base-photos/{photoId}", h.DeleteBasePhoto)
+		r.Get("/body-profile", h.GetBodyProfile)
+		r.Put("/body-profile", h.UpdateBodyProfile)
+		r.Delete("/body-profile", h.DeleteBodyProfile)
 		r.Route("/history/{id}", func(r chi.Router) {
+			r.Get("/", h.GetEditHistoryItem)
 			r.Delete("/", h.DeleteEditHistory)
+			r.Post("/share", h.ShareTryOn)
+			r.Delete("/share", h.RevokeTryOnShares)
+			r.Get("/feedback", h.GetTryOnFeedback)
+			r.Put("/feedback", h.RateTryOn)
+			r.Delete("/feedback", h.DeleteTryOnFeedback)
+			r.Get("/publications", h.GetTryOnPublications)
+			r.Post("/publish", h.PublishTryOn)
 		})
 	})
+}
+
+// RegisterPublicRoutes registers the unauthenticated shared try-on and provenance routes.
+// Mount these outside the authentication middleware.
+func (h *ImageEditHandler) RegisterPublicRoutes(r chi.Router) {
+	r.Route("/shared/tryon/{token}", func(r chi.Router) {
+		r.Get("/", h.GetSharedTryOn)
+		r.Get("/image", h.GetSharedTryOnImage)
+	})
+	r.Post("/provenance/verify", h.VerifyProvenance)
 }
\ No newline at end of file
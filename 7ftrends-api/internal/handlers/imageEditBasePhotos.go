@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/services"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// maxBasePhotos is how many base photos a user can keep
+const maxBasePhotos = 5
+
+// CreateBasePhotoRequest registers a reusable person photo for try-ons
+type CreateBasePhotoRequest struct {
+	Image string `json:"image" validate:"required"` // Base64 or URL
+	Kind  string `json:"kind" validate:"required,oneof=front full-body"`
+}
+
+// ReplaceBasePhotoRequest swaps the image of a base photo, optionally changing its kind
+type ReplaceBasePhotoRequest struct {
+	Image string `json:"image" validate:"required"` // Base64 or URL
+	Kind  string `json:"kind" validate:"omitempty,oneof=front full-body"`
+}
+
+// BasePhotoResponse is a stored base photo
+type BasePhotoResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Kind       string     `json:"kind"`
+	ImageURL   string     `json:"imageUrl"`
+	Dimensions Dimensions `json:"dimensions"`
+	MimeType   string     `json:"mimeType"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// BodyMeasurements are optional body measurements in centimeters
+type BodyMeasurements struct {
+	ChestCm    *float64 `json:"chestCm,omitempty" validate:"omitempty,gt=0,lt=300"`
+	WaistCm    *float64 `json:"waistCm,omitempty" validate:"omitempty,gt=0,lt=300"`
+	HipsCm     *float64 `json:"hipsCm,omitempty" validate:"omitempty,gt=0,lt=300"`
+	InseamCm   *float64 `json:"inseamCm,omitempty" validate:"omitempty,gt=0,lt=150"`
+	ShoulderCm *float64 `json:"shoulderCm,omitempty" validate:"omitempty,gt=0,lt=100"`
+}
+
+// BodyProfile is the user's height and measurements
+type BodyProfile struct {
+	HeightCm     *float64         `json:"heightCm,omitempty" validate:"omitempty,gt=50,lt=275"`
+	Measurements BodyMeasurements `json:"measurements"`
+	UpdatedAt    *time.Time       `json:"updatedAt,omitempty"`
+}
+
+// ListBasePhotos lists the user's base photos, newest first
+func (h *ImageEditHandler) ListBasePhotos(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	rows, err := h.db.ListVirtualTryonBasePhotos(ctx, userID)
+	if err != nil {
+		log.Printf("Error listing base photos: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve base photos")
+		return
+	}
+
+	photos := make([]BasePhotoResponse, len(rows))
+	for i, row := range rows {
+		photos[i] = convertBasePhotoRow(row)
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, photos)
+}
+
+// CreateBasePhoto stores a normalized copy of a person photo for later try-ons
+func (h *ImageEditHandler) CreateBasePhoto(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	var req CreateBasePhotoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	count, err := h.db.CountVirtualTryonBasePhotos(ctx, userID)
+	if err != nil {
+		log.Printf("Error counting base photos: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save base photo")
+		return
+	}
+	if count >= maxBasePhotos {
+		utils.RespondWithError(w, http.StatusConflict,
+			fmt.Sprintf("You can keep at most %d base photos, delete one first", maxBasePhotos))
+		return
+	}
+
+	photo, photoURL, ok := h.storeBasePhoto(w, ctx, userID, req.Image)
+	if !ok {
+		return
+	}
+
+	row, err := h.db.CreateVirtualTryonBasePhoto(ctx, database.CreateVirtualTryonBasePhotoParams{
+		ID:       uuid.New(),
+		UserID:   userID,
+		Kind:     req.Kind,
+		ImageUrl: photoURL,
+		Width:    int32(photo.Width),
+		Height:   int32(photo.Height),
+		MimeType: photo.MimeType,
+	})
+	if err != nil {
+		log.Printf("Error saving base photo: %v", err)
+		h.removeUpload(photoURL)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save base photo")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, convertBasePhotoRow(row))
+}
+
+// ReplaceBasePhoto swaps a base photo's image. Earlier try-ons keep their results.
+func (h *ImageEditHandler) ReplaceBasePhoto(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	photoID, err := uuid.Parse(chi.URLParam(r, "photoId"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid base photo ID")
+		return
+	}
+
+	var req ReplaceBasePhotoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	existing, err := h.db.GetVirtualTryonBasePhoto(ctx, database.GetVirtualTryonBasePhotoParams{
+		ID:     photoID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "Base photo not found")
+			return
+		}
+		log.Printf("Error getting base photo: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to replace base photo")
+		return
+	}
+
+	kind := req.Kind
+	if kind == "" {
+		kind = existing.Kind
+	}
+
+	photo, photoURL, ok := h.storeBasePhoto(w, ctx, userID, req.Image)
+	if !ok {
+		return
+	}
+
+	row, err := h.db.ReplaceVirtualTryonBasePhoto(ctx, database.ReplaceVirtualTryonBasePhotoParams{
+		ID:       photoID,
+		UserID:   userID,
+		Kind:     kind,
+		ImageUrl: photoURL,
+		Width:    int32(photo.Width),
+		Height:   int32(photo.Height),
+		MimeType: photo.MimeType,
+	})
+	if err != nil {
+		h.removeUpload(photoURL)
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "Base photo not found")
+			return
+		}
+		log.Printf("Error replacing base photo: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to replace base photo")
+		return
+	}
+	h.removeUpload(existing.ImageUrl)
+
+	utils.RespondWithJSON(w, http.StatusOK, convertBasePhotoRow(row))
+}
+
+// DeleteBasePhoto deletes a base photo together with every try-on made from it
+func (h *ImageEditHandler) DeleteBasePhoto(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	photoID, err := uuid.Parse(chi.URLParam(r, "photoId"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid base photo ID")
+		return
+	}
+
+	var photoURL string
+	var compositeURLs []pgtype.Text
+	err = h.db.ExecTx(ctx, func(q *database.Queries) error {
+		var err error
+		compositeURLs, err = q.DeleteVirtualTryonHistoryByBasePhoto(ctx, database.DeleteVirtualTryonHistoryByBasePhotoParams{
+			BasePhotoID: photoID,
+			UserID:      userID,
+		})
+		if err != nil {
+			return err
+		}
+		photoURL, err = q.DeleteVirtualTryonBasePhoto(ctx, database.DeleteVirtualTryonBasePhotoParams{
+			ID:     photoID,
+			UserID: userID,
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "Base photo not found")
+			return
+		}
+		log.Printf("Error deleting base photo: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete base photo")
+		return
+	}
+
+	// Files go only after the rows, so a failed delete never leaves rows without files
+	h.removeUpload(photoURL)
+	for _, compositeURL := range compositeURLs {
+		if compositeURL.Valid {
+			h.removeUpload(compositeURL.String)
+			h.removeUpload(compositeURL.String + manifestSuffix)
+		}
+	}
+
+	log.Printf("🗑️ Base photo %s deleted by user %s with %d try-ons", photoID, userID, len(compositeURLs))
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":       "Base photo deleted successfully",
+		"deletedTryOns": len(compositeURLs),
+	})
+}
+
+// GetBodyProfile returns the user's height and measurements
+func (h *ImageEditHandler) GetBodyProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	row, err := h.db.GetVirtualTryonBodyProfile(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Nothing recorded yet
+			utils.RespondWithJSON(w, http.StatusOK, BodyProfile{})
+			return
+		}
+		log.Printf("Error getting body profile: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve body profile")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, convertBodyProfileRow(row))
+}
+
+// UpdateBodyProfile replaces the user's height and measurements
+func (h *ImageEditHandler) UpdateBodyProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	var req BodyProfile
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	measurements, err := json.Marshal(req.Measurements)
+	if err != nil {
+		log.Printf("Error encoding body measurements: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save body profile")
+		return
+	}
+
+	params := database.UpsertVirtualTryonBodyProfileParams{
+		UserID:       userID,
+		Measurements: measurements,
+	}
+	if req.HeightCm != nil {
+		params.HeightCm = pgtype.Float8{Float64: *req.HeightCm, Valid: true}
+	}
+
+	row, err := h.db.UpsertVirtualTryonBodyProfile(ctx, params)
+	if err != nil {
+		log.Printf("Error saving body profile: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save body profile")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, convertBodyProfileRow(row))
+}
+
+// DeleteBodyProfile removes the user's height and measurements
+func (h *ImageEditHandler) DeleteBodyProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	if _, err := h.db.DeleteVirtualTryonBodyProfile(ctx, userID); err != nil {
+		log.Printf("Error deleting body profile: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete body profile")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Body profile deleted successfully"})
+}
+
+// useBasePhoto returns the stored URL of one of the user's base photos for a try-on.
+// It responds and returns false when the photo cannot be used.
+func (h *ImageEditHandler) useBasePhoto(w http.ResponseWriter, r *http.Request, userID, photoID uuid.UUID) (string, bool) {
+	photo, err := h.db.GetVirtualTryonBasePhoto(r.Context(), database.GetVirtualTryonBasePhotoParams{
+		ID:     photoID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "Base photo not found")
+			return "", false
+		}
+		log.Printf("Error getting base photo: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to load base photo")
+		return "", false
+	}
+	return photo.ImageUrl, true
+}
+
+// loadUserImage loads the person photo for a try-on. Base photos are read from
+// storage as they were normalized on upload; other sources are fetched.
+func (h *ImageEditHandler) loadUserImage(ctx context.Context, source string, basePhotoID *uuid.UUID) (*services.ProcessedImage, error) {
+	if basePhotoID == nil {
+		return h.loadInputImage(ctx, source)
+	}
+
+	filePath, err := h.uploadFilePath(source)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base photo: %v", err)
+	}
+	return services.StoredImage(data)
+}
+
+// storeBasePhoto fetches, normalizes and saves a base photo image. It responds and
+// returns false on failure.
+func (h *ImageEditHandler) storeBasePhoto(w http.ResponseWriter, ctx context.Context, userID uuid.UUID, source string) (*services.ProcessedImage, string, bool) {
+	if err := h.fetcher.ValidateSource(source); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid image: %v", err))
+		return nil, "", false
+	}
+	photo, err := h.loadInputImage(ctx, source)
+	if err != nil {
+		if respondPreflightFailed(w, inputField(err, "image")) {
+			return nil, "", false
+		}
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Failed to process image: %v", err))
+		return nil, "", false
+	}
+
+	userDir := filepath.Join(h.uploadsDir, "base-photos", userID.String())
+	if err := os.MkdirAll(userDir, 0755); err != nil {
+		log.Printf("Error creating base photo directory: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save base photo")
+		return nil, "", false
+	}
+
+	// Random filename so photos cannot be guessed from the user ID
+	filename := uuid.New().String() + services.ImageExtension(photo.MimeType)
+	if err := os.WriteFile(filepath.Join(userDir, filename), photo.Data, 0644); err != nil {
+		log.Printf("Error writing base photo: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save base photo")
+		return nil, "", false
+	}
+
+	return photo, fmt.Sprintf("/uploads/base-photos/%s/%s", userID.String(), filename), true
+}
+
+// removeUpload deletes a stored upload, logging rather than failing the request
+func (h *ImageEditHandler) removeUpload(uploadURL string) {
+	filePath, err := h.uploadFilePath(uploadURL)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error removing %s: %v", filePath, err)
+	}
+}
+
+// convertBasePhotoRow converts a database base photo row to its response format
+func convertBasePhotoRow(row database.VirtualTryonBasePhotoRow) BasePhotoResponse {
+	return BasePhotoResponse{
+		ID:         row.ID,
+		Kind:       row.Kind,
+		ImageURL:   row.ImageUrl,
+		Dimensions: Dimensions{Width: int(row.Width), Height: int(row.Height)},
+		MimeType:   row.MimeType,
+		CreatedAt:  row.CreatedAt,
+		UpdatedAt:  row.UpdatedAt,
+	}
+}
+
+// convertBodyProfileRow converts a database body profile row to its response format
+func convertBodyProfileRow(row database.VirtualTryonBodyProfileRow) BodyProfile {
+	profile := BodyProfile{UpdatedAt: &row.UpdatedAt}
+	if row.HeightCm.Valid {
+		profile.HeightCm = &row.HeightCm.Float64
+	}
+	if err := json.Unmarshal(row.Measurements, &profile.Measurements); err != nil {
+		log.Printf("Error parsing body measurements: %v", err)
+	}
+	return profile
+}
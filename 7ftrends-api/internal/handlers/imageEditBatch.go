@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/models"
+	"github.com/your-org/7ftrends-api/internal/services"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// Batch stream event types
+const (
+	BatchEventStarted = "started"
+	BatchEventResult  = "result"
+	BatchEventDone    = "done"
+)
+
+// batchHeartbeatInterval keeps idle event streams open through proxies
+const batchHeartbeatInterval = 15 * time.Second
+
+// BatchEditRequest tries one user photo against several garments, each as its own try-on
+type BatchEditRequest struct {
+	UserImage       string                `json:"userImage" validate:"required_without=BasePhotoID,excluded_with=BasePhotoID"`                                  // Base64 or URL
+	BasePhotoID     *uuid.UUID            `json:"basePhotoId,omitempty"`                                                                                        // A stored base photo instead of userImage
+	GarmentImages   []string              `json:"garmentImages" validate:"required_without=WardrobeItemIDs,excluded_with=WardrobeItemIDs,max=10,dive,required"` // Base64 or URL
+	WardrobeItemIDs []uuid.UUID           `json:"wardrobeItemIds" validate:"omitempty,max=10,unique"`                                                           // The user's own wardrobe items
+	Instructions    string                `json:"instructions"`
+	Position        string                `json:"position" validate:"omitempty,oneof=upper-body lower-body full-body accessory"`
+	Fit             string                `json:"fit" validate:"omitempty,oneof=snug regular loose"`
+	Style           string                `json:"style" validate:"omitempty,oneof=realistic stylized enhanced"`
+	Force           bool                  `json:"force"`
+	Settings        *models.TryOnSettings `json:"settings,omitempty"`
+}
+
+// BatchItemResult is the outcome of one garment in a batch
+type BatchItemResult struct {
+	Index          int        `json:"index"`
+	WardrobeItemID *uuid.UUID `json:"wardrobeItemId,omitempty"`
+	EditImageResponse
+}
+
+// BatchSummary closes a batch stream
+type BatchSummary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// BatchEvent is one message in a batch stream
+type BatchEvent struct {
+	Type    string           `json:"type"`
+	BatchID uuid.UUID        `json:"batchId"`
+	Total   int              `json:"total,omitempty"`
+	Result  *BatchItemResult `json:"result,omitempty"`
+	Summary *BatchSummary    `json:"summary,omitempty"`
+}
+
+// BatchEditImages runs one try-on per garment against the same user photo and
+// streams each result as it completes. Clients asking for text/event-stream
+// receive Server-Sent Events; everyone else receives NDJSON.
+func (h *ImageEditHandler) BatchEditImages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	if h.generator == nil || h.prompts == nil {
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "Image editing service not available")
+		return
+	}
+
+	var req BatchEditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if err := utils.ValidateStruct(req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Settings != nil {
+		if err := req.Settings.Validate(); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	req.Settings = normalizeSettings(req.Settings)
+
+	// Resolve every garment before any work starts
+	garments := make([]services.GarmentLayer, 0, len(req.GarmentImages)+len(req.WardrobeItemIDs))
+	for _, image := range req.GarmentImages {
+		garments = append(garments, services.GarmentLayer{Position: req.Position, Image: image})
+	}
+	for _, itemID := range req.WardrobeItemIDs {
+		garment, err := h.loadWardrobeGarment(ctx, userID, itemID)
+		if err != nil {
+			switch {
+			case errors.Is(err, errWardrobeItemNotFound):
+				utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			case errors.Is(err, errWardrobeItemNoImage):
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			default:
+				log.Printf("Error resolving garments: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, "Failed to load wardrobe items")
+			}
+			return
+		}
+		garments = append(garments, garment)
+	}
+
+	// Reject image sources we will not fetch before doing any work
+	if req.BasePhotoID != nil {
+		photoURL, ok := h.useBasePhoto(w, r, userID, *req.BasePhotoID)
+		if !ok {
+			return
+		}
+		req.UserImage = photoURL
+	} else if err := h.fetcher.ValidateSource(req.UserImage); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid user image: %v", err))
+		return
+	}
+	for i, garment := range garments {
+		if err := h.fetcher.ValidateSource(garment.Image); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid garment image %d: %v", i, err))
+			return
+		}
+	}
+
+	// Set default values
+	if req.Position == "" {
+		req.Position = "full-body"
+	}
+	if req.Fit == "" {
+		req.Fit = "regular"
+	}
+	if req.Style == "" {
+		req.Style = "realistic"
+	}
+
+	// Fail fast while the provider is down
+	if !services.GeneratorAvailable(h.generator) {
+		utils.RespondWithJSON(w, http.StatusServiceUnavailable, utils.ErrorResponse(utils.NewAPIError(
+			utils.ErrServiceUnavailable, "SERVICE_UNAVAILABLE",
+			"Image editing service is temporarily unavailable, please try again later",
+			http.StatusServiceUnavailable)))
+		return
+	}
+
+	// The whole batch must fit in the remaining monthly quota. Each item that does
+	// not produce a new generation gives its try-on back.
+	role := userRole(ctx)
+	month := services.QuotaMonth(time.Now())
+	if err := h.reserveQuota(ctx, h.db, userID, role, month, len(garments)); err != nil {
+		if errors.Is(err, errQuotaExceeded) {
+			h.respondOverQuota(w, ctx, userID, role)
+			return
+		}
+		log.Printf("Error reserving try-on quota: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check try-on quota")
+		return
+	}
+
+	// Load the user photo once for every garment
+	userImage, err := h.loadUserImage(ctx, req.UserImage, req.BasePhotoID)
+	if err != nil {
+		h.releaseQuota(userID, month, len(garments))
+		if respondPreflightFailed(w, inputField(err, "userImage")) {
+			return
+		}
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Failed to process user image: %v", err))
+		return
+	}
+
+	batchID := uuid.New()
+	stream := newBatchStream(w, strings.Contains(r.Header.Get("Accept"), "text/event-stream"), h.jobTimeout)
+	stream.send(BatchEvent{Type: BatchEventStarted, BatchID: batchID, Total: len(garments)})
+
+	log.Printf("📦 Batch try-on %s started for user %s with %d garments", batchID, userID, len(garments))
+
+	// Fan out with bounded concurrency and stream results in completion order
+	workers := h.batchWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+	results := make(chan BatchItemResult)
+	slots := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, garment := range garments {
+		wg.Add(1)
+		go func(index int, garment services.GarmentLayer) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results <- h.runBatchItem(ctx, userID, batchID, month, index, req, garment, userImage)
+		}(i, garment)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	summary := BatchSummary{Total: len(garments)}
+	heartbeat := time.NewTicker(batchHeartbeatInterval)
+	defer heartbeat.Stop()
+	for pending := len(garments); pending > 0; {
+		select {
+		case result := <-results:
+			pending--
+			if result.Success {
+				summary.Succeeded++
+			} else {
+				summary.Failed++
+			}
+			stream.send(BatchEvent{Type: BatchEventResult, BatchID: batchID, Result: &result})
+		case <-heartbeat.C:
+			stream.heartbeat()
+		}
+	}
+
+	stream.send(BatchEvent{Type: BatchEventDone, BatchID: batchID, Summary: &summary})
+
+	log.Printf("📦 Batch try-on %s finished for user %s: %d succeeded, %d failed", batchID, userID, summary.Succeeded, summary.Failed)
+}
+
+// runBatchItem runs the try-on for one garment of a batch and records it in its own history row
+func (h *ImageEditHandler) runBatchItem(ctx context.Context, userID, batchID uuid.UUID, month time.Time, index int, batch BatchEditRequest, garment services.GarmentLayer, userImage *services.ProcessedImage) BatchItemResult {
+	result := BatchItemResult{Index: index}
+
+	// The item's reserved try-on is given back unless it reaches executeTryOn,
+	// which then releases it for cache hits and failures
+	executed := false
+	defer func() {
+		if !executed {
+			h.releaseQuota(userID, month, 1)
+		}
+	}()
+
+	req := EditImageRequest{
+		UserImage:    batch.UserImage,
+		BasePhotoID:  batch.BasePhotoID,
+		GarmentImage: garment.Image,
+		Instructions: batch.Instructions,
+		Position:     batch.Position,
+		Fit:          batch.Fit,
+		Style:        batch.Style,
+		Force:        batch.Force,
+		Settings:     batch.Settings,
+	}
+	if garment.ItemID != uuid.Nil {
+		itemID := garment.ItemID
+		result.WardrobeItemID = &itemID
+		req.WardrobeItemIDs = []uuid.UUID{itemID}
+		req.Position = garment.Position
+	}
+	garments := []services.GarmentLayer{garment}
+
+	promptVersion := h.prompts.Choose(promptKey(userID, req, garments))
+	instructions, err := h.generateOverlayInstructions(promptVersion, req, garments)
+	if err != nil {
+		result.EditImageResponse = failedEdit("Failed to prepare try-on instructions", err)
+		return result
+	}
+
+	// executeTryOn loads the garment and checks the cache, reusing the shared user image
+	inputs, err := h.newTryOnInputs(req, promptVersion, instructions)
+	if err != nil {
+		result.EditImageResponse = failedEdit("Invalid try-on settings", err)
+		return result
+	}
+	inputs.UserImage = userImage
+
+	historyID, err := h.createPendingHistory(ctx, h.db, userID, req, garments, inputs, batchID)
+	if err != nil {
+		log.Printf("Error creating edit history: %v", err)
+		result.EditImageResponse = failedEdit("Failed to record try-on", err)
+		return result
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, h.jobTimeout)
+	defer cancel()
+
+	executed = true
+	result.EditImageResponse = h.executeTryOn(jobCtx, tryOnJob{
+		HistoryID:  historyID,
+		UserID:     userID,
+		QuotaMonth: month,
+		Request:    req,
+		Garments:   garments,
+		Inputs:     inputs,
+	})
+	result.HistoryID = &historyID
+	// The stored composite is enough; keep the stream small
+	if result.CompositeImageURL != "" {
+		result.EditedImageURL = ""
+	}
+	return result
+}
+
+// batchStream writes batch events as Server-Sent Events or NDJSON, flushing each
+// event and extending the write deadline so long batches outlive the server's
+// write timeout
+type batchStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	sse        bool
+	timeout    time.Duration
+	failed     bool
+}
+
+// newBatchStream writes the stream headers
+func newBatchStream(w http.ResponseWriter, sse bool, jobTimeout time.Duration) *batchStream {
+	stream := &batchStream{
+		w:          w,
+		controller: http.NewResponseController(w),
+		sse:        sse,
+		timeout:    jobTimeout + batchHeartbeatInterval,
+	}
+
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	stream.extendDeadline()
+	w.WriteHeader(http.StatusOK)
+	return stream
+}
+
+// send writes one event. After a write fails the client is gone and later
+// events are dropped.
+func (s *batchStream) send(event BatchEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error encoding batch event: %v", err)
+		return
+	}
+
+	if s.sse {
+		s.write(fmt.Sprintf("event: %s\ndata: %s\n\n", event.Type, data))
+	} else {
+		s.write(string(data) + "\n")
+	}
+}
+
+// heartbeat keeps an idle stream open; NDJSON has no comment syntax, so only
+// event streams receive one
+func (s *batchStream) heartbeat() {
+	if s.sse {
+		s.write(": keep-alive\n\n")
+	}
+}
+
+func (s *batchStream) write(chunk string) {
+	if s.failed {
+		return
+	}
+	s.extendDeadline()
+	if _, err := fmt.Fprint(s.w, chunk); err != nil {
+		s.failed = true
+		return
+	}
+	if err := s.controller.Flush(); err != nil {
+		s.failed = true
+	}
+}
+
+// extendDeadline allows the next event up to one job timeout to arrive
+func (s *batchStream) extendDeadline() {
+	if err := s.controller.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Error extending batch stream deadline: %v", err)
+	}
+}
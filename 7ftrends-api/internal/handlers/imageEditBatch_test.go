@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/your-org/7ftrends-api/internal/config"
+	"github.com/your-org/7ftrends-api/internal/services"
+)
+
+func TestRunBatchItemCacheHit(t *testing.T) {
+	db := newFakeDB()
+	db.rows["CreateVirtualTryonHistory"] = fakeRow{}
+	db.rows["UpdateVirtualTryonHistory"] = fakeRow{}
+	h := newTestImageEditHandler(t, db)
+	prompts, err := services.LoadPromptSet(config.PromptConfig{
+		Dir:      "../../configs/prompts",
+		Variants: []config.PromptVariant{{Version: "v1", Weight: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.prompts = prompts
+
+	userID, batchID := uuid.New(), uuid.New()
+	earlierURL := writeEarlierResult(t, h, userID)
+	db.rows["GetCachedVirtualTryon"] = fakeRow{values: historyRowValues(uuid.New(), userID, earlierURL)}
+
+	source := testImageDataURL(t)
+	userImage, err := h.loadInputImage(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := BatchEditRequest{UserImage: source, Position: "full-body", Fit: "regular", Style: "realistic"}
+	month := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	result := h.runBatchItem(context.Background(), userID, batchID, month, 0, batch, services.GarmentLayer{Position: "full-body", Image: source}, userImage)
+
+	if !result.Success || !result.Cached {
+		t.Fatalf("result success = %v, cached = %v, want a cache hit (error %q)", result.Success, result.Cached, result.Error)
+	}
+
+	// The hit gets its own row in the batch, not the earlier try-on's
+	created := db.called("CreateVirtualTryonHistory")
+	if len(created) != 1 {
+		t.Fatalf("created %d history rows, want 1", len(created))
+	}
+	historyID := created[0][0].(uuid.UUID)
+	if result.HistoryID == nil || *result.HistoryID != historyID {
+		t.Errorf("result history ID = %v, want the new row %s", result.HistoryID, historyID)
+	}
+	if got := created[0][16].(pgtype.UUID); !got.Valid || got.Bytes != batchID {
+		t.Errorf("row batch ID = %v, want %s", got, batchID)
+	}
+
+	updates := db.called("UpdateVirtualTryonHistory")
+	saved := updates[len(updates)-1]
+	if saved[0] != historyID || !saved[15].(pgtype.Bool).Bool {
+		t.Errorf("saved row %v with cached %v, want %s marked cached", saved[0], saved[15], historyID)
+	}
+	if got := len(db.called("ReleaseVirtualTryonUsage")); got != 1 {
+		t.Errorf("released quota %d times, want 1", got)
+	}
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// Reasons a user can give for a try-on result
+var feedbackReasons = []string{"bad_fit", "wrong_garment", "distorted_face", "lighting"}
+
+// TryOnFeedbackRequest rates a try-on result
+type TryOnFeedbackRequest struct {
+	Rating  string `json:"rating" validate:"required,oneof=up down"`
+	Reason  string `json:"reason" validate:"omitempty,oneof=bad_fit wrong_garment distorted_face lighting"`
+	Comment string `json:"comment" validate:"max=1000"`
+}
+
+// TryOnFeedbackResponse is the user's rating of a try-on result
+type TryOnFeedbackResponse struct {
+	HistoryID uuid.UUID `json:"historyId"`
+	Rating    string    `json:"rating"`
+	Reason    *string   `json:"reason,omitempty"`
+	Comment   *string   `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// RatingCounts summarizes thumbs up/down ratings and the reasons given
+type RatingCounts struct {
+	TotalRatings int64            `json:"totalRatings"`
+	ThumbsUp     int64            `json:"thumbsUp"`
+	ThumbsDown   int64            `json:"thumbsDown"`
+	ApprovalRate float64          `json:"approvalRate"`
+	Reasons      map[string]int64 `json:"reasons"`
+}
+
+// RatingBreakdown is the ratings for one value of a try-on parameter
+type RatingBreakdown struct {
+	Value string `json:"value"`
+	RatingCounts
+}
+
+// RatingStats aggregates ratings overall and by position, fit, style and prompt version
+type RatingStats struct {
+	RatingCounts
+	ByPosition      []RatingBreakdown `json:"byPosition"`
+	ByFit           []RatingBreakdown `json:"byFit"`
+	ByStyle         []RatingBreakdown `json:"byStyle"`
+	ByPromptVersion []RatingBreakdown `json:"byPromptVersion"`
+}
+
+// RateTryOn records or replaces the user's rating of a completed try-on
+func (h *ImageEditHandler) RateTryOn(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	historyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid history ID")
+		return
+	}
+
+	var req TryOnFeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	item, err := h.db.GetVirtualTryonHistoryByID(ctx, database.GetVirtualTryonHistoryByIDParams{
+		ID:     historyID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "History item not found")
+			return
+		}
+		log.Printf("Error getting edit history item: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save feedback")
+		return
+	}
+	if item.Status != TryOnStatusCompleted {
+		utils.RespondWithError(w, http.StatusConflict, "Only completed try-ons can be rated")
+		return
+	}
+
+	feedback, err := h.db.UpsertVirtualTryonFeedback(ctx, database.UpsertVirtualTryonFeedbackParams{
+		HistoryID: historyID,
+		UserID:    userID,
+		Rating:    req.Rating,
+		Reason:    pgtype.Text{String: req.Reason, Valid: req.Reason != ""},
+		Comment:   pgtype.Text{String: req.Comment, Valid: req.Comment != ""},
+	})
+	if err != nil {
+		log.Printf("Error saving try-on feedback: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save feedback")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, convertFeedbackRow(feedback))
+}
+
+// GetTryOnFeedback returns the user's rating of a try-on
+func (h *ImageEditHandler) GetTryOnFeedback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	historyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid history ID")
+		return
+	}
+
+	feedback, err := h.db.GetVirtualTryonFeedback(ctx, database.GetVirtualTryonFeedbackParams{
+		HistoryID: historyID,
+		UserID:    userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "Feedback not found")
+			return
+		}
+		log.Printf("Error getting try-on feedback: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve feedback")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, convertFeedbackRow(feedback))
+}
+
+// DeleteTryOnFeedback removes the user's rating of a try-on
+func (h *ImageEditHandler) DeleteTryOnFeedback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	historyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid history ID")
+		return
+	}
+
+	deleted, err := h.db.DeleteVirtualTryonFeedback(ctx, database.DeleteVirtualTryonFeedbackParams{
+		HistoryID: historyID,
+		UserID:    userID,
+	})
+	if err != nil {
+		log.Printf("Error deleting try-on feedback: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete feedback")
+		return
+	}
+	if deleted == 0 {
+		utils.RespondWithError(w, http.StatusNotFound, "Feedback not found")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Feedback deleted successfully"})
+}
+
+// GetRatingStats returns rating aggregates for the current user, or for all
+// users with ?scope=all (admins only)
+func (h *ImageEditHandler) GetRatingStats(w http.ResponseWriter, r *http.Request) {
+	filter, ok := statsScope(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.ratingStats(r.Context(), filter)
+	if err != nil {
+		log.Printf("Error getting rating stats: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve rating statistics")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, stats)
+}
+
+// ratingStats aggregates ratings for one user, or all users with a NULL filter
+func (h *ImageEditHandler) ratingStats(ctx context.Context, userID pgtype.UUID) (RatingStats, error) {
+	rows, err := h.db.GetVirtualTryonFeedbackStats(ctx, userID)
+	if err != nil {
+		return RatingStats{}, err
+	}
+
+	stats := RatingStats{
+		RatingCounts:    newRatingCounts(),
+		ByPosition:      []RatingBreakdown{},
+		ByFit:           []RatingBreakdown{},
+		ByStyle:         []RatingBreakdown{},
+		ByPromptVersion: []RatingBreakdown{},
+	}
+	for _, row := range rows {
+		breakdown := RatingBreakdown{Value: row.Value, RatingCounts: newRatingCounts()}
+		breakdown.add(row)
+
+		switch row.Dimension {
+		case "position":
+			stats.ByPosition = append(stats.ByPosition, breakdown)
+			// Every rating has exactly one position, so these rows sum to the totals
+			stats.add(row)
+		case "fit":
+			stats.ByFit = append(stats.ByFit, breakdown)
+		case "style":
+			stats.ByStyle = append(stats.ByStyle, breakdown)
+		case "prompt_version":
+			stats.ByPromptVersion = append(stats.ByPromptVersion, breakdown)
+		}
+	}
+
+	return stats, nil
+}
+
+func newRatingCounts() RatingCounts {
+	reasons := make(map[string]int64, len(feedbackReasons))
+	for _, reason := range feedbackReasons {
+		reasons[reason] = 0
+	}
+	return RatingCounts{Reasons: reasons}
+}
+
+// add accumulates a stats row and updates the approval rate
+func (c *RatingCounts) add(row database.GetVirtualTryonFeedbackStatsRow) {
+	c.TotalRatings += row.TotalRatings
+	c.ThumbsUp += row.ThumbsUp
+	c.ThumbsDown += row.ThumbsDown
+	c.Reasons["bad_fit"] += row.BadFit
+	c.Reasons["wrong_garment"] += row.WrongGarment
+	c.Reasons["distorted_face"] += row.DistortedFace
+	c.Reasons["lighting"] += row.Lighting
+
+	if c.TotalRatings > 0 {
+		c.ApprovalRate = float64(c.ThumbsUp) / float64(c.TotalRatings)
+	}
+}
+
+// statsScope resolves ?scope=all to a NULL user filter for admins, and to the
+// current user otherwise. It responds with 403 and returns false for non-admins.
+func statsScope(w http.ResponseWriter, r *http.Request) (pgtype.UUID, bool) {
+	ctx := r.Context()
+	if r.URL.Query().Get("scope") != "all" {
+		return pgtype.UUID{Bytes: auth.GetUserID(ctx), Valid: true}, true
+	}
+	if userRole(ctx) != "admin" {
+		utils.RespondWithError(w, http.StatusForbidden, "Only admins can view stats for all users")
+		return pgtype.UUID{}, false
+	}
+	return pgtype.UUID{}, true
+}
+
+// convertFeedbackRow converts a database feedback row to its response format
+func convertFeedbackRow(row database.VirtualTryonFeedbackRow) TryOnFeedbackResponse {
+	feedback := TryOnFeedbackResponse{
+		HistoryID: row.HistoryID,
+		Rating:    row.Rating,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+	if row.Reason.Valid {
+		feedback.Reason = &row.Reason.String
+	}
+	if row.Comment.Valid {
+		feedback.Comment = &row.Comment.String
+	}
+	return feedback
+}
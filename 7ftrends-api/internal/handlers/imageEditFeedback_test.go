@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatsScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		role      string
+		wantOK    bool
+		wantValid bool // scoped to the current user rather than all users
+	}{
+		{name: "own stats", query: "", role: "user", wantOK: true, wantValid: true},
+		{name: "admin's own stats", query: "", role: "admin", wantOK: true, wantValid: true},
+		{name: "all users as admin", query: "?scope=all", role: "admin", wantOK: true},
+		{name: "all users as user", query: "?scope=all", role: "user"},
+		{name: "all users without a role", query: "?scope=all"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/stats/ratings"+tt.query, nil)
+			if tt.role != "" {
+				r = r.WithContext(context.WithValue(r.Context(), "role", tt.role))
+			}
+
+			userFilter, ok := statsScope(httptest.NewRecorder(), r)
+			if ok != tt.wantOK || userFilter.Valid != tt.wantValid {
+				t.Errorf("statsScope() = valid %v, ok %v, want valid %v, ok %v", userFilter.Valid, ok, tt.wantValid, tt.wantOK)
+			}
+		})
+	}
+}
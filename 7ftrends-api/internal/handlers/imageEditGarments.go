@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/services"
+)
+
+var (
+	errWardrobeItemNotFound = errors.New("wardrobe item not found")
+	errWardrobeItemNoImage  = errors.New("wardrobe item has no image")
+)
+
+// resolveGarments returns the garments to apply, ordered from the base layer outwards.
+// Wardrobe items are looked up for the requesting user only, so items owned by
+// someone else are reported as not found.
+func (h *ImageEditHandler) resolveGarments(ctx context.Context, userID uuid.UUID, req EditImageRequest) ([]services.GarmentLayer, error) {
+	if len(req.WardrobeItemIDs) == 0 {
+		return []services.GarmentLayer{{Position: req.Position, Image: req.GarmentImage}}, nil
+	}
+
+	garments := make([]services.GarmentLayer, 0, len(req.WardrobeItemIDs))
+	for _, itemID := range req.WardrobeItemIDs {
+		garment, err := h.loadWardrobeGarment(ctx, userID, itemID)
+		if err != nil {
+			return nil, err
+		}
+		garments = append(garments, garment)
+	}
+
+	services.OrderGarmentLayers(garments)
+	return garments, nil
+}
+
+// loadWardrobeGarment loads one of the user's wardrobe items as a garment layer
+// using its first image
+func (h *ImageEditHandler) loadWardrobeGarment(ctx context.Context, userID, itemID uuid.UUID) (services.GarmentLayer, error) {
+	item, err := h.db.GetWardrobeItem(ctx, database.GetWardrobeItemParams{
+		ID:     itemID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return services.GarmentLayer{}, fmt.Errorf("%w: %s", errWardrobeItemNotFound, itemID)
+		}
+		return services.GarmentLayer{}, fmt.Errorf("failed to load wardrobe item %s: %v", itemID, err)
+	}
+
+	var images []string
+	if err := json.Unmarshal(item.Images, &images); err != nil || len(images) == 0 || images[0] == "" {
+		return services.GarmentLayer{}, fmt.Errorf("%w: %s", errWardrobeItemNoImage, itemID)
+	}
+
+	return services.GarmentLayer{
+		ItemID:   item.ID,
+		Category: item.Category,
+		Position: services.GarmentPosition(item.Category),
+		Image:    images[0],
+	}, nil
+}
+
+// sessionPosition is the position recorded for a layered try-on: the shared
+// position when every layer agrees, otherwise full-body
+func sessionPosition(garments []services.GarmentLayer) string {
+	position := garments[0].Position
+	for _, garment := range garments[1:] {
+		if garment.Position != position {
+			return "full-body"
+		}
+	}
+	return position
+}
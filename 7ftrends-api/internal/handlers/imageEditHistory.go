@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/your-org/7ftrends-api/internal/database"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Allowed values for the history list filters
+var (
+	historyStatuses  = []string{TryOnStatusPending, TryOnStatusProcessing, TryOnStatusCompleted, TryOnStatusFailed}
+	historyStyles    = []string{"realistic", "stylized", "enhanced"}
+	historyPositions = []string{"upper-body", "lower-body", "full-body", "accessory"}
+)
+
+// encodeHistoryCursor returns an opaque cursor that resumes the list after the
+// given row
+func encodeHistoryCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "," + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeHistoryCursor parses a cursor from encodeHistoryCursor
+func decodeHistoryCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+	createdAtStr, idStr, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+	return createdAt, id, nil
+}
+
+// parseHistoryFilters reads the history list filters and cursor from the query
+// string. The returned error is safe to show to the client.
+func parseHistoryFilters(query url.Values, userID uuid.UUID, limit int) (database.GetVirtualTryonHistoryParams, error) {
+	params := database.GetVirtualTryonHistoryParams{
+		UserID: userID,
+		Limit:  int32(limit),
+	}
+
+	var err error
+	if params.Status, err = parseEnumFilter(query, "status", historyStatuses); err != nil {
+		return params, err
+	}
+	if params.Style, err = parseEnumFilter(query, "style", historyStyles); err != nil {
+		return params, err
+	}
+	if params.Position, err = parseEnumFilter(query, "position", historyPositions); err != nil {
+		return params, err
+	}
+
+	if from := query.Get("from"); from != "" {
+		t, _, err := parseDateFilter(from)
+		if err != nil {
+			return params, fmt.Errorf("from must be an RFC 3339 timestamp or YYYY-MM-DD date")
+		}
+		params.CreatedFrom = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	if to := query.Get("to"); to != "" {
+		t, dateOnly, err := parseDateFilter(to)
+		if err != nil {
+			return params, fmt.Errorf("to must be an RFC 3339 timestamp or YYYY-MM-DD date")
+		}
+		// A date includes the whole day
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		params.CreatedTo = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	if params.CreatedFrom.Valid && params.CreatedTo.Valid && !params.CreatedFrom.Time.Before(params.CreatedTo.Time) {
+		return params, fmt.Errorf("from must be before to")
+	}
+
+	if minConfidence := query.Get("minConfidence"); minConfidence != "" {
+		value, err := strconv.ParseFloat(minConfidence, 64)
+		if err != nil || value < 0 || value > 1 {
+			return params, fmt.Errorf("minConfidence must be a number between 0 and 1")
+		}
+		params.MinConfidence = pgtype.Float8{Float64: value, Valid: true}
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		createdAt, id, err := decodeHistoryCursor(cursor)
+		if err != nil {
+			return params, err
+		}
+		params.CursorCreatedAt = pgtype.Timestamptz{Time: createdAt, Valid: true}
+		params.CursorID = pgtype.UUID{Bytes: id, Valid: true}
+	}
+
+	return params, nil
+}
+
+// parseEnumFilter reads an optional query parameter restricted to allowed values
+func parseEnumFilter(query url.Values, name string, allowed []string) (pgtype.Text, error) {
+	value := query.Get(name)
+	if value == "" {
+		return pgtype.Text{}, nil
+	}
+	for _, a := range allowed {
+		if value == a {
+			return pgtype.Text{String: value, Valid: true}, nil
+		}
+	}
+	return pgtype.Text{}, fmt.Errorf("%s must be one of: %s", name, strings.Join(allowed, ", "))
+}
+
+// parseDateFilter accepts an RFC 3339 timestamp or a YYYY-MM-DD date (UTC), and
+// reports whether it was a date
+func parseDateFilter(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
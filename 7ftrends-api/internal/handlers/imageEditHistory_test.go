@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHistoryCursorRoundTrip(t *testing.T) {
+	id := uuid.MustParse("0f8fad5b-d9cb-469f-a165-70867728950e")
+
+	tests := []struct {
+		name      string
+		createdAt time.Time
+	}{
+		{name: "utc", createdAt: time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)},
+		{name: "nanoseconds", createdAt: time.Date(2026, 3, 10, 12, 30, 0, 123456789, time.UTC)},
+		{name: "other zone", createdAt: time.Date(2026, 3, 10, 12, 30, 0, 0, time.FixedZone("CET", 3600))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createdAt, gotID, err := decodeHistoryCursor(encodeHistoryCursor(tt.createdAt, id))
+			if err != nil {
+				t.Fatalf("decodeHistoryCursor() error = %v", err)
+			}
+			if !createdAt.Equal(tt.createdAt) {
+				t.Errorf("createdAt = %v, want %v", createdAt, tt.createdAt)
+			}
+			if gotID != id {
+				t.Errorf("id = %s, want %s", gotID, id)
+			}
+		})
+	}
+}
+
+func TestDecodeHistoryCursorInvalid(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "empty", cursor: ""},
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "missing id", cursor: encode("2026-03-10T12:30:00Z")},
+		{name: "bad time", cursor: encode("yesterday,0f8fad5b-d9cb-469f-a165-70867728950e")},
+		{name: "bad id", cursor: encode("2026-03-10T12:30:00Z,not-a-uuid")},
+		{name: "swapped fields", cursor: encode("0f8fad5b-d9cb-469f-a165-70867728950e,2026-03-10T12:30:00Z")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeHistoryCursor(tt.cursor); !errors.Is(err, errInvalidCursor) {
+				t.Errorf("decodeHistoryCursor() error = %v, want %v", err, errInvalidCursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/services"
+)
+
+// Virtual try-on job statuses stored in virtual_tryon_history.status
+const (
+	TryOnStatusPending    = "pending"
+	TryOnStatusProcessing = "processing"
+	TryOnStatusCompleted  = "completed"
+	TryOnStatusFailed     = "failed"
+)
+
+var (
+	errTryOnQueueFull   = errors.New("try-on queue is full")
+	errTryOnQueueClosed = errors.New("try-on queue is closed")
+)
+
+// tryOnJob is a queued virtual try-on generation backed by a history row
+type tryOnJob struct {
+	HistoryID  uuid.UUID
+	UserID     uuid.UUID
+	QuotaMonth time.Time // the month the job's try-on was reserved against
+	Request    EditImageRequest
+	Garments   []services.GarmentLayer
+	Inputs     *tryOnInputs // images are loaded by the worker when the request did not load them
+}
+
+// startWorkers launches the worker pool that drains the try-on queue
+func (h *ImageEditHandler) startWorkers(count int) {
+	for i := 0; i < count; i++ {
+		h.workers.Add(1)
+		go func() {
+			defer h.workers.Done()
+			for job := range h.jobs {
+				h.runTryOnJob(job)
+			}
+		}()
+	}
+}
+
+// enqueueTryOnJob queues a job without blocking the request. The read lock keeps
+// Close from closing the queue while a request is sending to it.
+func (h *ImageEditHandler) enqueueTryOnJob(job tryOnJob) error {
+	h.queueMu.RLock()
+	defer h.queueMu.RUnlock()
+	if h.queueClosed {
+		return errTryOnQueueClosed
+	}
+
+	select {
+	case h.jobs <- job:
+		return nil
+	default:
+		return errTryOnQueueFull
+	}
+}
+
+// Close stops accepting jobs and the retention job, and waits for in-flight work to finish
+func (h *ImageEditHandler) Close() {
+	h.queueMu.Lock()
+	h.queueClosed = true
+	close(h.jobs)
+	h.queueMu.Unlock()
+
+	close(h.stop)
+	h.workers.Wait()
+}
+
+// runTryOnJob runs a queued job with the configured job timeout
+func (h *ImageEditHandler) runTryOnJob(job tryOnJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.jobTimeout)
+	defer cancel()
+
+	h.executeTryOn(ctx, job)
+}
+
+// executeTryOn moves a history row through processing to completed or failed
+// and returns the result that was recorded
+func (h *ImageEditHandler) executeTryOn(ctx context.Context, job tryOnJob) EditImageResponse {
+	if err := h.updateJobStatus(ctx, job, database.UpdateVirtualTryonHistoryParams{Status: TryOnStatusProcessing}); err != nil {
+		log.Printf("Error marking try-on job %s as processing: %v", job.HistoryID, err)
+	}
+
+	result, err := h.runTryOn(ctx, job)
+	if err != nil {
+		result = failedEdit("Image editing failed, please try again", err)
+	}
+
+	params := database.UpdateVirtualTryonHistoryParams{
+		Status: TryOnStatusCompleted,
+	}
+	if result.ProcessingTime > 0 {
+		params.ProcessingTime = pgtype.Int8{Int64: result.ProcessingTime, Valid: true}
+	}
+	// Keep the model's confidence even when the result is rejected
+	if result.Success || result.Details.ModelUsed != "" {
+		params.Confidence = pgtype.Float8{Float64: result.Confidence, Valid: true}
+	}
+	if result.Success {
+		if result.CompositeImageURL != "" {
+			params.CompositeImageUrl = pgtype.Text{String: result.CompositeImageURL, Valid: true}
+			params.CompositeSha256 = pgtype.Text{String: result.CompositeSHA256, Valid: result.CompositeSHA256 != ""}
+		}
+		details := result.Details
+		params.InputWidth = pgtype.Int4{Int32: int32(details.InputDimensions.Width), Valid: true}
+		params.InputHeight = pgtype.Int4{Int32: int32(details.InputDimensions.Height), Valid: true}
+		params.OutputWidth = pgtype.Int4{Int32: int32(details.OutputDimensions.Width), Valid: true}
+		params.OutputHeight = pgtype.Int4{Int32: int32(details.OutputDimensions.Height), Valid: true}
+		params.OutputMimeType = pgtype.Text{String: details.OutputMimeType, Valid: true}
+		params.Cached = pgtype.Bool{Bool: result.Cached, Valid: true}
+	} else {
+		params.Status = TryOnStatusFailed
+		params.ErrorMessage = pgtype.Text{String: result.Error, Valid: true}
+		if result.ErrorDetail != "" {
+			params.ErrorDetail = pgtype.Text{String: result.ErrorDetail, Valid: true}
+		}
+		if len(result.PreflightIssues) > 0 {
+			issues, err := json.Marshal(result.PreflightIssues)
+			if err != nil {
+				log.Printf("Error encoding preflight issues for try-on job %s: %v", job.HistoryID, err)
+			}
+			params.PreflightIssues = issues
+		}
+	}
+
+	// Record the outcome even if the job ran out of time
+	saveCtx, saveCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer saveCancel()
+	if err := h.updateJobStatus(saveCtx, job, params); err != nil {
+		log.Printf("Error saving try-on job %s result: %v", job.HistoryID, err)
+		return failedEdit("Failed to save try-on result", err)
+	}
+
+	// Only new generations are charged. An unsaved result leaves the row to
+	// recoverStaleJobs, which releases it then.
+	if result.Cached || !result.Success {
+		h.releaseQuota(job.UserID, job.QuotaMonth, 1)
+	}
+
+	switch {
+	case result.Cached:
+		log.Printf("♻️ Virtual try-on served from cache for user %s, history ID: %s", job.UserID, job.HistoryID)
+	case result.Success:
+		log.Printf("✅ Virtual try-on completed for user %s, history ID: %s", job.UserID, job.HistoryID)
+	default:
+		reason := result.Error
+		if result.ErrorDetail != "" {
+			reason = fmt.Sprintf("%s: %s", result.Error, result.ErrorDetail)
+		}
+		log.Printf("❌ Virtual try-on failed for user %s, history ID: %s: %s", job.UserID, job.HistoryID, reason)
+	}
+
+	return result
+}
+
+// runTryOn loads the images of a job that was queued before they were fetched and
+// completes it from an identical earlier try-on when there is one. Otherwise it
+// generates a new result.
+func (h *ImageEditHandler) runTryOn(ctx context.Context, job tryOnJob) (EditImageResponse, error) {
+	if job.Inputs.Hash == "" {
+		if err := h.loadTryOnImages(ctx, job.Inputs, job.Request, job.Garments); err != nil {
+			return failedImageLoad(err), nil
+		}
+		if err := h.updateJobStatus(ctx, job, database.UpdateVirtualTryonHistoryParams{
+			Status:    TryOnStatusProcessing,
+			InputHash: pgtype.Text{String: job.Inputs.Hash, Valid: true},
+		}); err != nil {
+			log.Printf("Error recording try-on job %s input hash: %v", job.HistoryID, err)
+		}
+	}
+
+	if !job.Request.Force {
+		if result, ok := h.cachedTryOn(ctx, job); ok {
+			return result, nil
+		}
+	}
+
+	return h.processImageEdit(ctx, job.UserID, job.HistoryID, job.Inputs)
+}
+
+// cachedTryOn returns the result of the user's latest completed try-on with the
+// same inputs, with its composite linked for the job's own history row
+func (h *ImageEditHandler) cachedTryOn(ctx context.Context, job tryOnJob) (EditImageResponse, bool) {
+	cached, err := h.db.GetCachedVirtualTryon(ctx, database.GetCachedVirtualTryonParams{
+		UserID:    job.UserID,
+		InputHash: job.Inputs.Hash,
+	})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Error checking try-on cache: %v", err)
+		}
+		return EditImageResponse{}, false
+	}
+
+	compositeURL, err := h.linkCompositeImage(job.UserID, cached.CompositeImageUrl.String)
+	if err != nil {
+		log.Printf("Error reusing cached try-on %s: %v", cached.ID, err)
+		return EditImageResponse{}, false
+	}
+
+	result := cachedEditResponse(cached)
+	result.HistoryID = &job.HistoryID
+	result.CompositeImageURL = compositeURL
+	return result, true
+}
+
+// updateJobStatus applies a status transition to the job's history row
+func (h *ImageEditHandler) updateJobStatus(ctx context.Context, job tryOnJob, params database.UpdateVirtualTryonHistoryParams) error {
+	params.ID = job.HistoryID
+	params.UserID = job.UserID
+	_, err := h.db.UpdateVirtualTryonHistory(ctx, params)
+	return err
+}
+
+// recoverStaleJobs fails rows left pending or processing by a previous process,
+// since their queued requests only lived in memory
+func (h *ImageEditHandler) recoverStaleJobs() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := h.db.FailStaleVirtualTryonHistory(ctx, database.FailStaleVirtualTryonHistoryParams{
+		UpdatedBefore: time.Now().Add(-h.jobTimeout),
+		ErrorMessage:  "Try-on was interrupted, please try again",
+	})
+	if err != nil {
+		log.Printf("Error recovering stale try-on jobs: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Marked %d stale try-on jobs as failed", count)
+	}
+}
@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/your-org/7ftrends-api/internal/config"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/services"
+)
+
+// fakeDB answers queries by their sqlc name and records every call. Queries
+// without a configured row report pgx.ErrNoRows, as pgx does.
+type fakeDB struct {
+	mu    sync.Mutex
+	rows  map[string]fakeRow
+	calls []fakeCall
+}
+
+type fakeCall struct {
+	name string
+	args []interface{}
+}
+
+// fakeRow scans values into the destinations by position; nil values are skipped
+type fakeRow struct {
+	values []interface{}
+	err    error
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	for i, value := range r.values {
+		if value != nil && i < len(dest) {
+			reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+		}
+	}
+	return nil
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{rows: map[string]fakeRow{}}
+}
+
+func (db *fakeDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	db.record(sql, args)
+	return pgconn.CommandTag{}, nil
+}
+
+func (db *fakeDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	db.record(sql, args)
+	return nil, errors.New("fakeDB: Query is not supported")
+}
+
+func (db *fakeDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	name := db.record(sql, args)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if row, ok := db.rows[name]; ok {
+		return row
+	}
+	return fakeRow{err: pgx.ErrNoRows}
+}
+
+func (db *fakeDB) record(sql string, args []interface{}) string {
+	name := queryName(sql)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.calls = append(db.calls, fakeCall{name: name, args: args})
+	return name
+}
+
+// called returns the arguments of every call to the named query
+func (db *fakeDB) called(name string) [][]interface{} {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var calls [][]interface{}
+	for _, call := range db.calls {
+		if call.name == name {
+			calls = append(calls, call.args)
+		}
+	}
+	return calls
+}
+
+// queryName reads the name from a sqlc "-- name: X :kind" header
+func queryName(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) >= 3 && fields[0] == "--" && fields[1] == "name:" {
+		return fields[2]
+	}
+	return sql
+}
+
+// fakeGenerator counts generations and fails each one
+type fakeGenerator struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (g *fakeGenerator) Generate(ctx context.Context, req services.GenerateRequest) (*services.GenerateResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls++
+	return nil, errors.New("fake generation failure")
+}
+
+func (g *fakeGenerator) Model() string { return "fake" }
+
+// historyRowValues is a completed history row in the column order of the
+// virtual_tryon_history queries
+func historyRowValues(id, userID uuid.UUID, compositeURL string) []interface{} {
+	values := make([]interface{}, 29)
+	values[0] = id
+	values[1] = userID
+	values[4] = pgtype.Text{String: compositeURL, Valid: true}
+	values[9] = pgtype.Float8{Float64: 0.9, Valid: true}
+	values[10] = TryOnStatusCompleted
+	return values
+}
+
+func testImageDataURL(t *testing.T) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatalf("encode test image: %v", err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func newTestImageEditHandler(t *testing.T, db *fakeDB) *ImageEditHandler {
+	return &ImageEditHandler{
+		db:         database.New(db),
+		uploadsDir: t.TempDir(),
+		generator:  &fakeGenerator{},
+		fetcher: services.NewImageFetcher(config.StorageConfig{
+			AllowedTypes: []string{"image/png", "image/jpeg"},
+		}, config.SupabaseConfig{}),
+		maxDimension: 512,
+		quality:      80,
+		jobs:         make(chan tryOnJob, 1),
+		jobTimeout:   time.Minute,
+		stop:         make(chan struct{}),
+	}
+}
+
+// writeEarlierResult stores the composite and manifest of an earlier try-on,
+// owned by its own row, and returns the composite's URL
+func writeEarlierResult(t *testing.T, h *ImageEditHandler, userID uuid.UUID) string {
+	t.Helper()
+	userDir := filepath.Join(h.uploadsDir, "virtual-tryon", userID.String())
+	if err := os.MkdirAll(userDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"earlier.jpg", "earlier.jpg" + manifestSuffix} {
+		if err := os.WriteFile(filepath.Join(userDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return "/uploads/virtual-tryon/" + userID.String() + "/earlier.jpg"
+}
+
+// queuedJob is a try-on as the request queues it, before any image is loaded
+func queuedJob(t *testing.T, force bool) tryOnJob {
+	source := testImageDataURL(t)
+	return tryOnJob{
+		HistoryID:  uuid.New(),
+		UserID:     uuid.New(),
+		QuotaMonth: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		Request: EditImageRequest{
+			UserImage:    source,
+			GarmentImage: source,
+			Position:     "full-body",
+			Fit:          "regular",
+			Style:        "realistic",
+			Force:        force,
+		},
+		Garments: []services.GarmentLayer{{Position: "full-body", Image: source}},
+		Inputs:   &tryOnInputs{Instructions: "Apply the garment", MaxDimension: 512, Quality: 80},
+	}
+}
+
+func TestExecuteTryOnCache(t *testing.T) {
+	tests := []struct {
+		name          string
+		cached        bool // an identical completed try-on exists
+		force         bool
+		wantCached    bool
+		wantLookups   int
+		wantGenerated int
+	}{
+		{name: "hit", cached: true, wantCached: true, wantLookups: 1},
+		{name: "miss", wantLookups: 1, wantGenerated: 1},
+		{name: "forced", cached: true, force: true, wantGenerated: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			db.rows["UpdateVirtualTryonHistory"] = fakeRow{}
+			h := newTestImageEditHandler(t, db)
+			job := queuedJob(t, tt.force)
+
+			earlierURL := writeEarlierResult(t, h, job.UserID)
+			if tt.cached {
+				db.rows["GetCachedVirtualTryon"] = fakeRow{values: historyRowValues(uuid.New(), job.UserID, earlierURL)}
+			}
+
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			result := h.executeTryOn(context.Background(), job)
+
+			if strings.Contains(logs.String(), "Error checking try-on cache") {
+				t.Errorf("a cache miss was logged as an error:\n%s", logs.String())
+			}
+			if result.Cached != tt.wantCached || result.Success != tt.wantCached {
+				t.Errorf("result success = %v, cached = %v, want %v (error %q)", result.Success, result.Cached, tt.wantCached, result.Error)
+			}
+			if got := h.generator.(*fakeGenerator).calls; got != tt.wantGenerated {
+				t.Errorf("generated %d times, want %d", got, tt.wantGenerated)
+			}
+
+			lookups := db.called("GetCachedVirtualTryon")
+			if len(lookups) != tt.wantLookups {
+				t.Fatalf("looked up the cache %d times, want %d", len(lookups), tt.wantLookups)
+			}
+
+			// The worker records the hash it derived from the loaded images
+			updates := db.called("UpdateVirtualTryonHistory")
+			if len(updates) < 3 {
+				t.Fatalf("got %d history updates, want processing, input hash and result", len(updates))
+			}
+			inputHash := updates[1][14].(pgtype.Text)
+			if !inputHash.Valid || inputHash.String != job.Inputs.Hash || job.Inputs.Hash == "" {
+				t.Errorf("recorded input hash %v, want %q", inputHash, job.Inputs.Hash)
+			}
+			if tt.wantLookups > 0 && lookups[0][1] != job.Inputs.Hash {
+				t.Errorf("cache looked up with hash %v, want %q", lookups[0][1], job.Inputs.Hash)
+			}
+
+			saved := updates[len(updates)-1]
+			if cached := saved[15].(pgtype.Bool); cached.Bool != tt.wantCached {
+				t.Errorf("saved cached = %v, want %v", cached, tt.wantCached)
+			}
+
+			// Cache hits and failures give the reserved try-on back
+			if got := len(db.called("ReleaseVirtualTryonUsage")); got != 1 {
+				t.Errorf("released quota %d times, want 1", got)
+			}
+
+			if !tt.wantCached {
+				return
+			}
+			if *result.HistoryID != job.HistoryID {
+				t.Errorf("result history ID = %s, want the job's %s", result.HistoryID, job.HistoryID)
+			}
+			if result.CompositeImageURL == earlierURL || saved[2].(pgtype.Text).String != result.CompositeImageURL {
+				t.Errorf("saved composite %v for result %q, want a new file apart from %q", saved[2], result.CompositeImageURL, earlierURL)
+			}
+			linked, err := h.uploadFilePath(result.CompositeImageURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, suffix := range []string{"", manifestSuffix} {
+				data, err := os.ReadFile(linked + suffix)
+				if err != nil || string(data) != "earlier.jpg"+suffix {
+					t.Errorf("linked file %s = %q, %v, want the earlier result's", linked+suffix, data, err)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteTryOnImageLoadFailure(t *testing.T) {
+	db := newFakeDB()
+	db.rows["UpdateVirtualTryonHistory"] = fakeRow{}
+	h := newTestImageEditHandler(t, db)
+	job := queuedJob(t, false)
+	job.Request.UserImage = "http://169.254.169.254/latest/meta-data/"
+
+	result := h.executeTryOn(context.Background(), job)
+
+	// The fetch error names the refused host, which is for logs only
+	const want = "Failed to load the try-on images"
+	if result.Success || result.Error != want || result.ErrorDetail == "" {
+		t.Errorf("result success = %v, error = %q, detail = %q, want error %q with a detail", result.Success, result.Error, result.ErrorDetail, want)
+	}
+	updates := db.called("UpdateVirtualTryonHistory")
+	saved := updates[len(updates)-1]
+	if message := saved[6].(pgtype.Text); message.String != want {
+		t.Errorf("saved error message %q, want %q", message.String, want)
+	}
+	if detail := saved[12].(pgtype.Text); detail.String != result.ErrorDetail {
+		t.Errorf("saved error detail %q, want %q", detail.String, result.ErrorDetail)
+	}
+	if got := len(db.called("ReleaseVirtualTryonUsage")); got != 1 {
+		t.Errorf("released quota %d times, want 1", got)
+	}
+}
+
+func TestExecuteTryOnPreflightFailure(t *testing.T) {
+	db := newFakeDB()
+	db.rows["UpdateVirtualTryonHistory"] = fakeRow{}
+	h := newTestImageEditHandler(t, db)
+	h.preflight.MinImageResolution = 128 // the test image is 64x64
+	job := queuedJob(t, false)
+
+	result := h.executeTryOn(context.Background(), job)
+
+	if result.Success || result.Error != preflightFailedMessage {
+		t.Errorf("result success = %v, error = %q, want error %q", result.Success, result.Error, preflightFailedMessage)
+	}
+	if len(result.PreflightIssues) != 1 || result.PreflightIssues[0].Field != "userImage" {
+		t.Fatalf("result preflight issues = %+v, want one for userImage", result.PreflightIssues)
+	}
+
+	// The job status endpoint reads the issues back from the row
+	updates := db.called("UpdateVirtualTryonHistory")
+	saved := updates[len(updates)-1]
+	row := database.GetVirtualTryonHistoryRow{Status: TryOnStatusFailed, PreflightIssues: saved[16].([]byte)}
+	issues := convertHistoryRow(row).PreflightIssues
+	if len(issues) != 1 || issues[0].Field != "userImage" || issues[0].Message != result.PreflightIssues[0].Message {
+		t.Errorf("stored preflight issues = %+v, want %+v", issues, result.PreflightIssues)
+	}
+}
+
+func TestEnqueueTryOnJobAfterClose(t *testing.T) {
+	h := &ImageEditHandler{jobs: make(chan tryOnJob, 1), stop: make(chan struct{})}
+
+	if err := h.enqueueTryOnJob(tryOnJob{}); err != nil {
+		t.Fatalf("enqueueTryOnJob() error = %v", err)
+	}
+	if err := h.enqueueTryOnJob(tryOnJob{}); !errors.Is(err, errTryOnQueueFull) {
+		t.Errorf("enqueueTryOnJob() on a full queue error = %v, want %v", err, errTryOnQueueFull)
+	}
+
+	h.Close()
+	if err := h.enqueueTryOnJob(tryOnJob{}); !errors.Is(err, errTryOnQueueClosed) {
+		t.Errorf("enqueueTryOnJob() after Close error = %v, want %v", err, errTryOnQueueClosed)
+	}
+}
+
+func TestCloseDuringEnqueue(t *testing.T) {
+	h := &ImageEditHandler{jobs: make(chan tryOnJob, 100), stop: make(chan struct{})}
+
+	// Requests still enqueueing while the server shuts down must not panic
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.enqueueTryOnJob(tryOnJob{})
+		}()
+	}
+	h.Close()
+	wg.Wait()
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/your-org/7ftrends-api/internal/services"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// preflightImage runs the preflight checks on fetched image bytes so unusable
+// photos are rejected before a generation is paid for
+func (h *ImageEditHandler) preflightImage(data []byte) error {
+	return services.PreflightImage(data, h.preflight)
+}
+
+// inputField records which request field a preflight failure came from
+func inputField(err error, field string) error {
+	var preflight *services.PreflightError
+	if errors.As(err, &preflight) {
+		preflight.Field = field
+	}
+	return err
+}
+
+// garmentField names the request field a garment image came from. Garments are
+// reordered by layer, so the index is looked up in the requested item IDs.
+func garmentField(garment services.GarmentLayer, itemIDs []uuid.UUID) string {
+	if garment.ItemID != uuid.Nil {
+		return fmt.Sprintf("wardrobeItemIds[%d]", slices.Index(itemIDs, garment.ItemID))
+	}
+	return "garmentImage"
+}
+
+// preflightFailedMessage is the client message for images failing the preflight checks
+const preflightFailedMessage = "One or more images cannot be used for a try-on"
+
+// preflightIssues returns one field-level reason per failed check when err is a
+// preflight failure, and nil otherwise
+func preflightIssues(err error) []utils.ValidationError {
+	var preflight *services.PreflightError
+	if !errors.As(err, &preflight) {
+		return nil
+	}
+
+	issues := make([]utils.ValidationError, len(preflight.Issues))
+	for i, issue := range preflight.Issues {
+		issues[i] = utils.ValidationError{
+			Field:   preflight.Field,
+			Message: issue.Message,
+			Value:   issue,
+		}
+	}
+	return issues
+}
+
+// respondPreflightFailed responds with 422 and one field-level reason per failed
+// check when err is a preflight failure, and reports whether it responded
+func respondPreflightFailed(w http.ResponseWriter, err error) bool {
+	issues := preflightIssues(err)
+	if issues == nil {
+		return false
+	}
+
+	utils.RespondWithJSON(w, http.StatusUnprocessableEntity, utils.ErrorResponse(utils.NewAPIError(
+		utils.ErrValidationFailed, "IMAGE_PREFLIGHT_FAILED",
+		preflightFailedMessage,
+		http.StatusUnprocessableEntity).WithDetails(issues)))
+	return true
+}
+
+// failedImageLoad is the result of a queued try-on whose images could not be
+// loaded. Preflight failures keep their field-level reasons for the job status.
+func failedImageLoad(err error) EditImageResponse {
+	issues := preflightIssues(err)
+	if issues == nil {
+		return failedEdit("Failed to load the try-on images", err)
+	}
+
+	result := failedEdit(preflightFailedMessage, err)
+	result.PreflightIssues = issues
+	return result
+}
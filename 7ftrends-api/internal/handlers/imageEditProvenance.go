@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/your-org/7ftrends-api/internal/services"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// manifestSuffix names the signed provenance sidecar next to a composite file
+const manifestSuffix = ".manifest.json"
+
+// Where a provenance verification result came from
+const (
+	ProvenanceSourceManifest = "manifest" // signed sidecar matching this exact file
+	ProvenanceSourceRecord   = "record"   // stored try-on with this exact file, no verifiable manifest
+	ProvenanceSourceEmbedded = "embedded" // unverified metadata inside the file
+	ProvenanceSourceNone     = "none"
+)
+
+// VerifyProvenanceRequest is an image to check against the try-on pipeline
+type VerifyProvenanceRequest struct {
+	Image string `json:"image" validate:"required"` // Base64 or URL
+}
+
+// ProvenanceVerificationResponse reports whether an image came from the try-on pipeline.
+// Verified is only true for the exact file we stored; edited or re-encoded copies
+// can at most report their embedded metadata.
+type ProvenanceVerificationResponse struct {
+	Verified    bool                 `json:"verified"`
+	AIGenerated bool                 `json:"aiGenerated"`
+	Source      string               `json:"source"`
+	Provenance  *services.Provenance `json:"provenance,omitempty"`
+}
+
+// applyProvenance optionally watermarks a composite, embeds its provenance and
+// signs a manifest for the final bytes. The manifest is nil without a signing secret.
+func (h *ImageEditHandler) applyProvenance(composite *services.ProcessedImage, historyID uuid.UUID, model string, quality int) (*services.ProcessedImage, *services.ProvenanceManifest, error) {
+	if h.watermark {
+		watermarked, err := services.WatermarkImage(composite.Data, h.watermarkText, quality)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to watermark composite: %w", err)
+		}
+		composite = watermarked
+	}
+
+	provenance := services.NewProvenance(model, historyID)
+	data, err := services.EmbedProvenance(composite.Data, composite.MimeType, provenance)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to embed provenance: %w", err)
+	}
+	composite = &services.ProcessedImage{
+		Data:     data,
+		MimeType: composite.MimeType,
+		Width:    composite.Width,
+		Height:   composite.Height,
+	}
+
+	if h.provenance == nil {
+		return composite, nil, nil
+	}
+	manifest, err := h.provenance.Sign(provenance, data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign provenance manifest: %w", err)
+	}
+	return composite, &manifest, nil
+}
+
+// VerifyProvenance tells whether an image was produced by the try-on pipeline
+func (h *ImageEditHandler) VerifyProvenance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req VerifyProvenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The raw bytes are checked; normalizing would change the hash
+	image, err := h.fetcher.Fetch(ctx, req.Image)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Failed to load image: %v", err))
+		return
+	}
+
+	response := ProvenanceVerificationResponse{Source: ProvenanceSourceNone}
+
+	record, err := h.db.GetVirtualTryonByCompositeHash(ctx, services.ImageSHA256(image.Data))
+	switch {
+	case err == nil:
+		response.Verified = true
+		response.AIGenerated = true
+		response.Source = ProvenanceSourceRecord
+		if manifest, ok := h.verifiedManifest(record.CompositeImageUrl, image.Data); ok {
+			response.Source = ProvenanceSourceManifest
+			response.Provenance = &manifest.Provenance
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		if embedded, ok := services.ReadProvenance(image.Data); ok {
+			response.AIGenerated = embedded.AIGenerated
+			response.Source = ProvenanceSourceEmbedded
+			response.Provenance = embedded
+		}
+	default:
+		log.Printf("Error looking up composite hash: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to verify image")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// verifiedManifest loads a composite's sidecar manifest and checks it against the image
+func (h *ImageEditHandler) verifiedManifest(compositeURL string, image []byte) (*services.ProvenanceManifest, bool) {
+	if h.provenance == nil {
+		return nil, false
+	}
+	filePath, err := h.uploadFilePath(compositeURL)
+	if err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(filePath + manifestSuffix)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading provenance manifest for %s: %v", compositeURL, err)
+		}
+		return nil, false
+	}
+
+	var manifest services.ProvenanceManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		log.Printf("Error parsing provenance manifest for %s: %v", compositeURL, err)
+		return nil, false
+	}
+	if err := h.provenance.Verify(manifest, image); err != nil {
+		log.Printf("⚠️ Provenance manifest for %s failed verification: %v", compositeURL, err)
+		return nil, false
+	}
+	return &manifest, true
+}
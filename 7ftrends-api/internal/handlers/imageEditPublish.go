@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/models"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// Publication types
+const (
+	PublicationTypePost   = "post"
+	PublicationTypeOutfit = "outfit"
+)
+
+var errWardrobeItemMissing = errors.New("wardrobe item no longer exists")
+
+// PublishTryOnRequest turns a completed try-on into a post or a saved outfit
+type PublishTryOnRequest struct {
+	Type       string   `json:"type" validate:"required,oneof=post outfit"`
+	Caption    string   `json:"caption" validate:"max=2200"`                                  // Post text
+	Visibility string   `json:"visibility" validate:"omitempty,oneof=public private friends"` // Post visibility
+	Draft      bool     `json:"draft"`                                                        // Save the post as a draft
+	Name       string   `json:"name" validate:"required_if=Type outfit,max=100"`              // Outfit name
+	Occasion   string   `json:"occasion" validate:"max=50"`
+	Season     string   `json:"season" validate:"max=50"`
+	Public     bool     `json:"public"` // Make the outfit public
+	Tags       []string `json:"tags" validate:"max=30,dive,min=1,max=50"`
+}
+
+// TryOnPublicationResponse links a try-on to the post or outfit made from it
+type TryOnPublicationResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	HistoryID uuid.UUID  `json:"historyId"`
+	Type      string     `json:"type"`
+	PostID    *uuid.UUID `json:"postId,omitempty"`
+	OutfitID  *uuid.UUID `json:"outfitId,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// PublishTryOn creates a post with the composite as its media, or an outfit of
+// the wardrobe items used with the composite as its primary image
+func (h *ImageEditHandler) PublishTryOn(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	historyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid history ID")
+		return
+	}
+
+	var req PublishTryOnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	item, err := h.db.GetVirtualTryonHistoryByID(ctx, database.GetVirtualTryonHistoryByIDParams{
+		ID:     historyID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "History item not found")
+			return
+		}
+		log.Printf("Error getting edit history item: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to publish try-on")
+		return
+	}
+	if item.Status != TryOnStatusCompleted || !item.CompositeImageUrl.Valid {
+		utils.RespondWithError(w, http.StatusConflict, "Only completed try-ons can be published")
+		return
+	}
+
+	var wardrobeItemIDs []uuid.UUID
+	if len(item.WardrobeItemIds) > 0 {
+		if err := json.Unmarshal(item.WardrobeItemIds, &wardrobeItemIDs); err != nil {
+			log.Printf("Error parsing wardrobe item IDs: %v", err)
+		}
+	}
+	if req.Type == PublicationTypeOutfit && len(wardrobeItemIDs) == 0 {
+		utils.RespondWithError(w, http.StatusConflict, "Only try-ons of wardrobe items can be saved as an outfit")
+		return
+	}
+
+	var publication database.VirtualTryonPublicationRow
+	err = h.db.ExecTx(ctx, func(q *database.Queries) error {
+		var err error
+		if req.Type == PublicationTypePost {
+			publication, err = h.publishPost(ctx, q, userID, item, wardrobeItemIDs, req)
+		} else {
+			publication, err = h.publishOutfit(ctx, q, userID, item, wardrobeItemIDs, req)
+		}
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errWardrobeItemMissing) {
+			utils.RespondWithError(w, http.StatusConflict, "A wardrobe item used in this try-on no longer exists")
+			return
+		}
+		log.Printf("Error publishing try-on %s: %v", historyID, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to publish try-on")
+		return
+	}
+
+	log.Printf("📣 Virtual try-on %s published as %s by user %s", historyID, req.Type, userID)
+
+	utils.RespondWithJSON(w, http.StatusCreated, convertPublicationRow(publication))
+}
+
+// GetTryOnPublications lists the posts and outfits made from a try-on
+func (h *ImageEditHandler) GetTryOnPublications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	historyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid history ID")
+		return
+	}
+
+	rows, err := h.db.ListVirtualTryonPublications(ctx, database.ListVirtualTryonPublicationsParams{
+		HistoryID: historyID,
+		UserID:    userID,
+	})
+	if err != nil {
+		log.Printf("Error listing try-on publications: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve publications")
+		return
+	}
+
+	publications := make([]TryOnPublicationResponse, len(rows))
+	for i, row := range rows {
+		publications[i] = convertPublicationRow(row)
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, publications)
+}
+
+// publishPost creates a post with the composite as its only media
+func (h *ImageEditHandler) publishPost(ctx context.Context, q *database.Queries, userID uuid.UUID, item database.GetVirtualTryonHistoryRow, wardrobeItemIDs []uuid.UUID, req PublishTryOnRequest) (database.VirtualTryonPublicationRow, error) {
+	content, err := json.Marshal(models.PostContent{Text: req.Caption})
+	if err != nil {
+		return database.VirtualTryonPublicationRow{}, err
+	}
+
+	status := "published"
+	publishedAt := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	if req.Draft {
+		status = "draft"
+		publishedAt = pgtype.Timestamptz{}
+	}
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = "public"
+	}
+
+	postID := uuid.New()
+	if _, err := q.CreatePost(ctx, database.CreatePostParams{
+		ID:          postID,
+		UserID:      userID,
+		Content:     content,
+		Tags:        nonNilStrings(req.Tags),
+		Status:      status,
+		Visibility:  visibility,
+		PublishedAt: publishedAt,
+	}); err != nil {
+		return database.VirtualTryonPublicationRow{}, err
+	}
+
+	// The composite is marked as AI-generated and linked to the garments it shows
+	metadata := map[string]interface{}{
+		"ai_generated":      true,
+		"source":            "virtual_tryon",
+		"tryon_id":          item.ID,
+		"wardrobe_item_ids": wardrobeItemIDs,
+	}
+	if len(wardrobeItemIDs) == 0 {
+		metadata["garment_image_url"] = item.GarmentImageUrl
+	}
+	if item.PromptVersion.Valid {
+		metadata["prompt_version"] = item.PromptVersion.String
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return database.VirtualTryonPublicationRow{}, err
+	}
+
+	if err := q.CreatePostMedia(ctx, database.CreatePostMediaParams{
+		PostID:   postID,
+		Type:     "image",
+		URL:      item.CompositeImageUrl.String,
+		Width:    item.OutputWidth.Int32,
+		Height:   item.OutputHeight.Int32,
+		FileSize: h.compositeFileSize(item.CompositeImageUrl.String),
+		MimeType: item.OutputMimeType.String,
+		Metadata: metadataJSON,
+	}); err != nil {
+		return database.VirtualTryonPublicationRow{}, err
+	}
+
+	return q.CreateVirtualTryonPublication(ctx, database.CreateVirtualTryonPublicationParams{
+		HistoryID: item.ID,
+		UserID:    userID,
+		PostID:    pgtype.UUID{Bytes: postID, Valid: true},
+	})
+}
+
+// publishOutfit creates an outfit of the try-on's wardrobe items in layer order
+func (h *ImageEditHandler) publishOutfit(ctx context.Context, q *database.Queries, userID uuid.UUID, item database.GetVirtualTryonHistoryRow, wardrobeItemIDs []uuid.UUID, req PublishTryOnRequest) (database.VirtualTryonPublicationRow, error) {
+	metadata, err := json.Marshal(map[string]interface{}{
+		"ai_generated_image": true,
+		"source":             "virtual_tryon",
+		"tryon_id":           item.ID,
+	})
+	if err != nil {
+		return database.VirtualTryonPublicationRow{}, err
+	}
+
+	outfitID := uuid.New()
+	if _, err := q.CreateOutfit(ctx, database.CreateOutfitParams{
+		ID:           outfitID,
+		UserID:       userID,
+		Name:         req.Name,
+		Description:  pgtype.Text{String: req.Caption, Valid: req.Caption != ""},
+		Occasion:     req.Occasion,
+		Season:       req.Season,
+		Style:        item.Style,
+		Tags:         nonNilStrings(req.Tags),
+		Images:       []string{item.CompositeImageUrl.String},
+		PrimaryImage: item.CompositeImageUrl.String,
+		IsPublic:     req.Public,
+		Metadata:     metadata,
+	}); err != nil {
+		return database.VirtualTryonPublicationRow{}, err
+	}
+
+	for i, wardrobeID := range wardrobeItemIDs {
+		added, err := q.CreateOutfitItem(ctx, database.CreateOutfitItemParams{
+			OutfitID:   outfitID,
+			WardrobeID: wardrobeID,
+			UserID:     userID,
+			Position:   int32(i),
+		})
+		if err != nil {
+			return database.VirtualTryonPublicationRow{}, err
+		}
+		if added == 0 {
+			return database.VirtualTryonPublicationRow{}, errWardrobeItemMissing
+		}
+	}
+
+	return q.CreateVirtualTryonPublication(ctx, database.CreateVirtualTryonPublicationParams{
+		HistoryID: item.ID,
+		UserID:    userID,
+		OutfitID:  pgtype.UUID{Bytes: outfitID, Valid: true},
+	})
+}
+
+// compositeFileSize returns the stored composite's size, or 0 if it is unknown
+func (h *ImageEditHandler) compositeFileSize(compositeURL string) int64 {
+	filePath, err := h.uploadFilePath(compositeURL)
+	if err != nil {
+		return 0
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// nonNilStrings stores a missing list as an empty array rather than NULL
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// convertPublicationRow converts a database publication row to its response format
+func convertPublicationRow(row database.VirtualTryonPublicationRow) TryOnPublicationResponse {
+	publication := TryOnPublicationResponse{
+		ID:        row.ID,
+		HistoryID: row.HistoryID,
+		CreatedAt: row.CreatedAt,
+	}
+	if row.PostID.Valid {
+		postID := uuid.UUID(row.PostID.Bytes)
+		publication.Type = PublicationTypePost
+		publication.PostID = &postID
+	}
+	if row.OutfitID.Valid {
+		outfitID := uuid.UUID(row.OutfitID.Bytes)
+		publication.Type = PublicationTypeOutfit
+		publication.OutfitID = &outfitID
+	}
+	return publication
+}
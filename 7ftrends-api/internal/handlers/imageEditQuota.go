@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/services"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// QuotaResponse reports a user's monthly try-on usage
+type QuotaResponse struct {
+	Role      string    `json:"role"`
+	Limit     *int      `json:"limit"` // null when unlimited
+	Used      int       `json:"used"`
+	Remaining *int      `json:"remaining"` // null when unlimited
+	Unlimited bool      `json:"unlimited"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+var errQuotaExceeded = errors.New("monthly try-on quota exceeded")
+
+// userRole returns the role the auth middleware stored under "role" for the
+// request, or "" when there is none, which gets the default user quota
+func userRole(ctx context.Context) string {
+	role, _ := ctx.Value("role").(string)
+	return role
+}
+
+// quotaStatus returns the user's try-on usage against their role's monthly quota
+func (h *ImageEditHandler) quotaStatus(ctx context.Context, userID uuid.UUID, role string) (services.QuotaStatus, error) {
+	limit := services.MonthlyQuotaLimit(h.monthlyQuotas, role)
+
+	now := time.Now()
+	used, err := h.db.GetVirtualTryonUsage(ctx, database.GetVirtualTryonUsageParams{
+		UserID: userID,
+		Month:  services.QuotaMonth(now),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Nothing reserved yet this month
+		used, err = 0, nil
+	}
+	if err != nil {
+		return services.QuotaStatus{}, err
+	}
+
+	return services.NewQuotaStatus(limit, int64(used), now), nil
+}
+
+// reserveQuota charges count try-ons to the user's usage for month, or fails with
+// errQuotaExceeded when they do not fit. Usage is a ledger rather than a count of
+// history rows, so deleting history never gives try-ons back; only releaseQuota does.
+func (h *ImageEditHandler) reserveQuota(ctx context.Context, q *database.Queries, userID uuid.UUID, role string, month time.Time, count int) error {
+	_, err := q.ReserveVirtualTryonUsage(ctx, database.ReserveVirtualTryonUsageParams{
+		UserID:     userID,
+		Month:      month,
+		Count:      int32(count),
+		UsageLimit: int32(services.MonthlyQuotaLimit(h.monthlyQuotas, role)),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errQuotaExceeded
+	}
+	return err
+}
+
+// releaseQuota gives back reserved try-ons that did not produce a new generation.
+// It uses its own context so the release lands even after the job timed out.
+func (h *ImageEditHandler) releaseQuota(userID uuid.UUID, month time.Time, count int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := h.db.ReleaseVirtualTryonUsage(ctx, database.ReleaseVirtualTryonUsageParams{
+		UserID: userID,
+		Month:  month,
+		Count:  int32(count),
+	})
+	if err != nil {
+		log.Printf("Error releasing %d try-ons for user %s: %v", count, userID, err)
+	}
+}
+
+// respondOverQuota responds with the user's usage after a reservation did not fit
+func (h *ImageEditHandler) respondOverQuota(w http.ResponseWriter, ctx context.Context, userID uuid.UUID, role string) {
+	quota, err := h.quotaStatus(ctx, userID, role)
+	if err != nil {
+		log.Printf("Error checking try-on quota: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check try-on quota")
+		return
+	}
+	respondQuotaExceeded(w, quota)
+}
+
+// respondQuotaExceeded writes the QUOTA_EXCEEDED error envelope with the reset date
+func respondQuotaExceeded(w http.ResponseWriter, quota services.QuotaStatus) {
+	apiErr := utils.NewAPIError(utils.ErrQuotaExceeded, "QUOTA_EXCEEDED",
+		"Monthly try-on quota exceeded", http.StatusTooManyRequests).WithDetails(map[string]interface{}{
+		"limit":   quota.Limit,
+		"used":    quota.Used,
+		"resetAt": quota.ResetAt,
+	})
+	utils.RespondWithJSON(w, apiErr.HTTPStatus, utils.ErrorResponse(apiErr))
+}
+
+// GetQuota returns the user's monthly try-on usage, remaining allowance and reset time
+func (h *ImageEditHandler) GetQuota(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+	role := userRole(ctx)
+
+	quota, err := h.quotaStatus(ctx, userID, role)
+	if err != nil {
+		log.Printf("Error getting try-on quota: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve try-on quota")
+		return
+	}
+
+	response := QuotaResponse{
+		Role:      role,
+		Used:      quota.Used,
+		Unlimited: quota.Unlimited,
+		ResetAt:   quota.ResetAt,
+	}
+	if !quota.Unlimited {
+		response.Limit = &quota.Limit
+		response.Remaining = &quota.Remaining
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
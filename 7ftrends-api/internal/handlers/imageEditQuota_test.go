@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var testQuotas = map[string]int{"user": 20, "premium": 200, "admin": -1}
+
+func TestReserveQuota(t *testing.T) {
+	month := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		role      string
+		fits      bool // the ledger update returns a row
+		wantLimit int32
+		wantErr   error
+	}{
+		{name: "fits", role: "user", fits: true, wantLimit: 20},
+		{name: "over the limit", role: "user", wantLimit: 20, wantErr: errQuotaExceeded},
+		{name: "role's own limit", role: "premium", fits: true, wantLimit: 200},
+		{name: "unknown role gets the user limit", role: "", wantLimit: 20, wantErr: errQuotaExceeded},
+		{name: "unlimited", role: "admin", fits: true, wantLimit: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			if tt.fits {
+				db.rows["ReserveVirtualTryonUsage"] = fakeRow{values: []interface{}{int32(3)}}
+			}
+			h := newTestImageEditHandler(t, db)
+			h.monthlyQuotas = testQuotas
+			userID := uuid.New()
+
+			err := h.reserveQuota(context.Background(), h.db, userID, tt.role, month, 3)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("reserveQuota() error = %v, want %v", err, tt.wantErr)
+			}
+
+			calls := db.called("ReserveVirtualTryonUsage")
+			if len(calls) != 1 {
+				t.Fatalf("reserved %d times, want 1", len(calls))
+			}
+			args := calls[0]
+			if args[0] != userID || args[1] != month || args[2] != int32(3) || args[3] != tt.wantLimit {
+				t.Errorf("reserved with %v, want user %s, month %s, count 3 and limit %d", args, userID, month, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestQuotaStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		role          string
+		used          *int32 // nil when the user has no usage row this month
+		wantUsed      int
+		wantRemaining int
+		wantUnlimited bool
+	}{
+		{name: "no usage yet", role: "user", wantUsed: 0, wantRemaining: 20},
+		{name: "some used", role: "user", used: int32Ptr(5), wantUsed: 5, wantRemaining: 15},
+		{name: "all used", role: "user", used: int32Ptr(20), wantUsed: 20, wantRemaining: 0},
+		{name: "unlimited", role: "admin", used: int32Ptr(500), wantUsed: 500, wantUnlimited: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			if tt.used != nil {
+				db.rows["GetVirtualTryonUsage"] = fakeRow{values: []interface{}{*tt.used}}
+			}
+			h := newTestImageEditHandler(t, db)
+			h.monthlyQuotas = testQuotas
+
+			quota, err := h.quotaStatus(context.Background(), uuid.New(), tt.role)
+			if err != nil {
+				t.Fatalf("quotaStatus() error = %v", err)
+			}
+			if quota.Used != tt.wantUsed || quota.Remaining != tt.wantRemaining || quota.Unlimited != tt.wantUnlimited {
+				t.Errorf("quotaStatus() = %+v, want used %d, remaining %d, unlimited %v", quota, tt.wantUsed, tt.wantRemaining, tt.wantUnlimited)
+			}
+		})
+	}
+}
+
+func TestReleaseQuota(t *testing.T) {
+	db := newFakeDB()
+	h := newTestImageEditHandler(t, db)
+	userID := uuid.New()
+	month := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	h.releaseQuota(userID, month, 2)
+
+	calls := db.called("ReleaseVirtualTryonUsage")
+	if len(calls) != 1 {
+		t.Fatalf("released %d times, want 1", len(calls))
+	}
+	if args := calls[0]; args[0] != userID || args[1] != month || args[2] != int32(2) {
+		t.Errorf("released with %v, want user %s, month %s and count 2", args, userID, month)
+	}
+}
+
+func TestUserRole(t *testing.T) {
+	ctx := context.Background()
+	if role := userRole(ctx); role != "" {
+		t.Errorf("userRole() without a role = %q, want empty", role)
+	}
+	if role := userRole(context.WithValue(ctx, "role", "premium")); role != "premium" {
+		t.Errorf("userRole() = %q, want premium", role)
+	}
+}
+
+func int32Ptr(v int32) *int32 { return &v }
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/your-org/7ftrends-api/internal/config"
+	"github.com/your-org/7ftrends-api/internal/database"
+)
+
+// retentionStats counts the outcome of one retention run
+type retentionStats struct {
+	Deleted      int
+	FilesRemoved int
+	Skipped      int
+	Failed       int
+}
+
+// startRetention runs the retention job now and then on the configured interval until Close
+func (h *ImageEditHandler) startRetention(cfg config.RetentionConfig) {
+	if cfg.Days <= 0 {
+		log.Printf("🧹 Try-on retention disabled")
+		return
+	}
+
+	interval := time.Duration(cfg.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+
+	h.workers.Add(1)
+	go func() {
+		defer h.workers.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			h.runRetention(cfg, interval)
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runRetention deletes finished try-ons older than the retention period, together
+// with their composite files. Rows with an active share link or a publication are kept.
+func (h *ImageEditHandler) runRetention(cfg config.RetentionConfig, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Abort the run when the handler is closed
+	go func() {
+		select {
+		case <-h.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	cutoff := time.Now().AddDate(0, 0, -cfg.Days)
+
+	if cfg.DryRun {
+		count, err := h.db.CountExpiredVirtualTryonHistory(ctx, cutoff)
+		if err != nil {
+			log.Printf("Error counting expired try-ons: %v", err)
+			return
+		}
+		log.Printf("🧹 Try-on retention dry run: %d try-ons created before %s would be deleted", count, cutoff.Format(time.RFC3339))
+		return
+	}
+
+	var stats retentionStats
+	for {
+		rows, err := h.db.ListExpiredVirtualTryonHistory(ctx, database.ListExpiredVirtualTryonHistoryParams{
+			CreatedBefore: cutoff,
+			Limit:         int32(cfg.BatchSize),
+		})
+		if err != nil {
+			log.Printf("Error listing expired try-ons: %v", err)
+			break
+		}
+
+		deleted := stats.Deleted
+		for _, row := range rows {
+			h.deleteExpiredTryOn(ctx, row.ID, cutoff, &stats)
+		}
+
+		// Stop on the last page, or when a whole batch failed so it is not retried forever
+		if len(rows) < cfg.BatchSize || stats.Deleted == deleted || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if stats.Deleted > 0 || stats.Failed > 0 {
+		log.Printf("🧹 Try-on retention deleted %d rows and %d files (%d skipped, %d failed)",
+			stats.Deleted, stats.FilesRemoved, stats.Skipped, stats.Failed)
+	}
+}
+
+// deleteExpiredTryOn deletes one history row and its composite file as a unit.
+// The file is moved aside inside the transaction and only removed after the
+// commit, so a failure on either side leaves both the row and the file in place.
+func (h *ImageEditHandler) deleteExpiredTryOn(ctx context.Context, historyID uuid.UUID, cutoff time.Time, stats *retentionStats) {
+	var filePath, stagedPath string
+
+	err := h.db.ExecTx(ctx, func(q *database.Queries) error {
+		compositeURL, err := q.DeleteExpiredVirtualTryonHistory(ctx, database.DeleteExpiredVirtualTryonHistoryParams{
+			ID:            historyID,
+			CreatedBefore: cutoff,
+		})
+		if err != nil {
+			return err
+		}
+		if !compositeURL.Valid {
+			return nil
+		}
+
+		path, err := h.uploadFilePath(compositeURL.String)
+		if err != nil {
+			// Not stored in the uploads directory, so there is no file to remove
+			return nil
+		}
+
+		if err := os.Rename(path, path+".deleting"); err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to stage composite for deletion: %v", err)
+		}
+		filePath, stagedPath = path, path+".deleting"
+		return nil
+	})
+	if err != nil {
+		if stagedPath != "" {
+			if restoreErr := os.Rename(stagedPath, filePath); restoreErr != nil {
+				log.Printf("Error restoring composite %s: %v", filePath, restoreErr)
+			}
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Shared or otherwise changed since it was listed
+			stats.Skipped++
+			return
+		}
+		log.Printf("Error deleting expired try-on %s: %v", historyID, err)
+		stats.Failed++
+		return
+	}
+
+	stats.Deleted++
+	if stagedPath == "" {
+		return
+	}
+	if err := os.Remove(stagedPath); err != nil {
+		log.Printf("Error removing composite %s: %v", stagedPath, err)
+		return
+	}
+	stats.FilesRemoved++
+
+	// The provenance manifest is meaningless without its composite
+	if err := os.Remove(filePath + manifestSuffix); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error removing provenance manifest for %s: %v", filePath, err)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/your-org/7ftrends-api/internal/models"
+	"github.com/your-org/7ftrends-api/internal/services"
+)
+
+// outputQuality is the output resolution and JPEG quality for a settings quality
+type outputQuality struct {
+	MaxDimension int
+	JPEGQuality  int
+}
+
+// outputQualities maps TryOnSettings.Quality to output parameters. Dimensions are
+// further capped by the configured maximum.
+var outputQualities = map[string]outputQuality{
+	"low":    {MaxDimension: 768, JPEGQuality: 70},
+	"medium": {MaxDimension: 1280, JPEGQuality: 82},
+	"high":   {MaxDimension: 2048, JPEGQuality: 92},
+}
+
+// normalizeSettings drops settings that change nothing, so they do not split the cache
+func normalizeSettings(settings *models.TryOnSettings) *models.TryOnSettings {
+	if settings == nil {
+		return nil
+	}
+	if settings.Background == "" && settings.BackgroundColor == "" && settings.Lighting == "" &&
+		len(settings.Filters) == 0 && settings.Quality == "" && len(settings.Customizations) == 0 {
+		return nil
+	}
+	return settings
+}
+
+// outputParams returns the output's maximum dimension and JPEG quality for the settings
+func (h *ImageEditHandler) outputParams(settings *models.TryOnSettings) (int, int) {
+	if settings == nil || settings.Quality == "" {
+		return h.maxDimension, h.quality
+	}
+
+	preset := outputQualities[settings.Quality]
+	maxDimension := preset.MaxDimension
+	if maxDimension > h.maxDimension {
+		maxDimension = h.maxDimension
+	}
+	return maxDimension, preset.JPEGQuality
+}
+
+// promptSettings exposes the settings that affect generation to prompt templates
+func promptSettings(settings *models.TryOnSettings) *services.PromptSettings {
+	if settings == nil {
+		return nil
+	}
+	return &services.PromptSettings{
+		Background:          settings.Background,
+		BackgroundColor:     settings.BackgroundColor,
+		Lighting:            settings.Lighting,
+		LightingDescription: settings.LightingDescription(),
+		Filters:             settings.Filters,
+	}
+}
+
+// encodeSettings returns the stored form of the settings, or nil without settings.
+// Map keys are sorted by encoding/json, so equal settings encode identically.
+func encodeSettings(settings *models.TryOnSettings) ([]byte, error) {
+	if settings == nil {
+		return nil, nil
+	}
+	return json.Marshal(settings)
+}
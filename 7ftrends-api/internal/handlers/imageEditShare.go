@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/services"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// Route prefixes used to build share URLs relative to where the router is mounted
+const (
+	imageEditRoutePrefix   = "/image-edit/"
+	sharedTryOnRoutePrefix = "/shared/tryon/"
+)
+
+// CreateShareRequest optionally overrides how long a share link stays valid
+type CreateShareRequest struct {
+	ExpiresIn int `json:"expiresIn" validate:"omitempty,min=60"` // seconds
+}
+
+// ShareLinkResponse is a newly issued share link for a try-on result
+type ShareLinkResponse struct {
+	ShareID   uuid.UUID `json:"shareId"`
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ImageURL  string    `json:"imageUrl"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// SharedTryOnResponse is the public view of a shared try-on. It deliberately
+// omits the owner and the input photos.
+type SharedTryOnResponse struct {
+	ImageURL   string      `json:"imageUrl"`
+	MimeType   string      `json:"mimeType,omitempty"`
+	Dimensions *Dimensions `json:"dimensions,omitempty"`
+	Position   string      `json:"position"`
+	Fit        string      `json:"fit"`
+	Style      string      `json:"style"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	ExpiresAt  time.Time   `json:"expiresAt"`
+}
+
+// ShareTryOn issues a signed, expiring public link to a completed try-on
+func (h *ImageEditHandler) ShareTryOn(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	if h.shares == nil {
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "Sharing is not available")
+		return
+	}
+
+	historyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid history ID")
+		return
+	}
+
+	// The body is optional
+	var req CreateShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ttl := h.shareTTL
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if ttl > h.shareMaxTTL {
+		utils.RespondWithError(w, http.StatusBadRequest,
+			fmt.Sprintf("expiresIn must be at most %d seconds", int(h.shareMaxTTL.Seconds())))
+		return
+	}
+
+	item, err := h.db.GetVirtualTryonHistoryByID(ctx, database.GetVirtualTryonHistoryByIDParams{
+		ID:     historyID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "History item not found")
+			return
+		}
+		log.Printf("Error getting edit history item: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create share link")
+		return
+	}
+	if item.Status != TryOnStatusCompleted || !item.CompositeImageUrl.Valid {
+		utils.RespondWithError(w, http.StatusConflict, "Only completed try-ons can be shared")
+		return
+	}
+
+	share, err := h.db.CreateVirtualTryonShare(ctx, database.CreateVirtualTryonShareParams{
+		ID:        uuid.New(),
+		HistoryID: historyID,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(ttl).Truncate(time.Second),
+	})
+	if err != nil {
+		log.Printf("Error creating try-on share: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create share link")
+		return
+	}
+
+	token := h.shares.Sign(share.ID, share.ExpiresAt)
+	shareURL := h.shareURL(r, imageEditRoutePrefix, token)
+
+	log.Printf("🔗 Virtual try-on %s shared by user %s until %s", historyID, userID, share.ExpiresAt.Format(time.RFC3339))
+
+	utils.RespondWithJSON(w, http.StatusCreated, ShareLinkResponse{
+		ShareID:   share.ID,
+		Token:     token,
+		URL:       shareURL,
+		ImageURL:  shareURL + "/image",
+		ExpiresAt: share.ExpiresAt,
+	})
+}
+
+// RevokeTryOnShares revokes every active share link to a try-on
+func (h *ImageEditHandler) RevokeTryOnShares(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	historyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid history ID")
+		return
+	}
+
+	revoked, err := h.db.RevokeVirtualTryonShares(ctx, database.RevokeVirtualTryonSharesParams{
+		HistoryID: historyID,
+		UserID:    userID,
+	})
+	if err != nil {
+		log.Printf("Error revoking try-on shares: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke share links")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Share links revoked successfully",
+		"revoked": revoked,
+	})
+}
+
+// GetSharedTryOn returns the public view of a shared try-on
+func (h *ImageEditHandler) GetSharedTryOn(w http.ResponseWriter, r *http.Request) {
+	share, ok := h.resolveShare(w, r)
+	if !ok {
+		return
+	}
+
+	response := SharedTryOnResponse{
+		ImageURL:  h.shareURL(r, sharedTryOnRoutePrefix, chi.URLParam(r, "token")) + "/image",
+		Position:  share.Position,
+		Fit:       share.Fit,
+		Style:     share.Style,
+		CreatedAt: share.CreatedAt,
+		ExpiresAt: share.ExpiresAt,
+	}
+	if share.OutputMimeType.Valid {
+		response.MimeType = share.OutputMimeType.String
+	}
+	if share.OutputWidth.Valid && share.OutputHeight.Valid {
+		response.Dimensions = &Dimensions{Width: int(share.OutputWidth.Int32), Height: int(share.OutputHeight.Int32)}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// GetSharedTryOnImage streams the composite image of a shared try-on
+func (h *ImageEditHandler) GetSharedTryOnImage(w http.ResponseWriter, r *http.Request) {
+	share, ok := h.resolveShare(w, r)
+	if !ok {
+		return
+	}
+
+	filePath, err := h.uploadFilePath(share.CompositeImageUrl)
+	if err != nil {
+		log.Printf("Error resolving shared composite %s: %v", share.ID, err)
+		utils.RespondWithError(w, http.StatusNotFound, "Shared try-on not found")
+		return
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Printf("Error opening shared composite %s: %v", share.ID, err)
+		utils.RespondWithError(w, http.StatusNotFound, "Shared try-on not found")
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		log.Printf("Error reading shared composite %s: %v", share.ID, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to load shared try-on")
+		return
+	}
+
+	if share.OutputMimeType.Valid {
+		w.Header().Set("Content-Type", share.OutputMimeType.String)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Keep caches short so revocation takes effect quickly
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+// resolveShare verifies the token in the URL and loads its share, writing the
+// error response itself when the share cannot be served
+func (h *ImageEditHandler) resolveShare(w http.ResponseWriter, r *http.Request) (database.GetActiveVirtualTryonShareRow, bool) {
+	if h.shares == nil {
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "Sharing is not available")
+		return database.GetActiveVirtualTryonShareRow{}, false
+	}
+
+	shareID, err := h.shares.Verify(chi.URLParam(r, "token"), time.Now())
+	if err != nil {
+		if errors.Is(err, services.ErrShareTokenExpired) {
+			utils.RespondWithError(w, http.StatusGone, "Share link has expired")
+		} else {
+			utils.RespondWithError(w, http.StatusNotFound, "Shared try-on not found")
+		}
+		return database.GetActiveVirtualTryonShareRow{}, false
+	}
+
+	// Revoked links and deleted try-ons no longer resolve
+	share, err := h.db.GetActiveVirtualTryonShare(r.Context(), shareID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "Shared try-on not found")
+		} else {
+			log.Printf("Error getting try-on share: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to load shared try-on")
+		}
+		return database.GetActiveVirtualTryonShareRow{}, false
+	}
+
+	return share, true
+}
+
+// shareURL builds the public URL for a token. Without a configured base URL it
+// is relative to where the router is mounted, found from the current path.
+func (h *ImageEditHandler) shareURL(r *http.Request, routePrefix, token string) string {
+	base := h.shareBaseURL
+	if base == "" {
+		if i := strings.Index(r.URL.Path, routePrefix); i >= 0 {
+			base = r.URL.Path[:i]
+		}
+	}
+	return strings.TrimRight(base, "/") + sharedTryOnRoutePrefix + token
+}
+
+// uploadFilePath maps a stored upload URL, such as a composite, to its file in the
+// uploads directory
+func (h *ImageEditHandler) uploadFilePath(uploadURL string) (string, error) {
+	relative, ok := strings.CutPrefix(uploadURL, "/uploads/")
+	if !ok {
+		return "", fmt.Errorf("upload %q is not stored locally", uploadURL)
+	}
+
+	root := filepath.Clean(h.uploadsDir)
+	filePath := filepath.Join(root, filepath.FromSlash(relative))
+	if !strings.HasPrefix(filePath, root+string(filepath.Separator)) {
+		return "", fmt.Errorf("upload %q is outside the uploads directory", uploadURL)
+	}
+	return filePath, nil
+}
@@ -7,25 +7,54 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/config"
 	"github.com/your-org/7ftrends-api/internal/database"
 	"github.com/your-org/7ftrends-api/internal/models"
+	"github.com/your-org/7ftrends-api/internal/services"
 	"github.com/your-org/7ftrends-api/internal/utils"
 )
 
 type WardrobeHandler struct {
-	db *database.Queries
+	db      *database.Queries
+	fetcher *services.ImageFetcher
+	tagger  services.GarmentTagger
+	tagging config.TaggingConfig
+	wake    chan struct{}
+	stop    chan struct{}
+	workers sync.WaitGroup
+
+	// exportTimeout is the write deadline for each page or image of an export
+	exportTimeout time.Duration
 }
 
-func NewWardrobeHandler(db *database.Queries) *WardrobeHandler {
-	return &WardrobeHandler{db: db}
+func NewWardrobeHandler(db *database.Queries, cfg *config.Config) *WardrobeHandler {
+	tagger, err := services.NewGarmentTagger(cfg.AI)
+	if err != nil {
+		log.Printf("⚠️ Wardrobe AI tagging unavailable: %v", err)
+	}
+
+	h := &WardrobeHandler{
+		db:      db,
+		fetcher: services.NewImageFetcher(cfg.Storage, cfg.Supabase),
+		tagger:  tagger,
+		tagging: cfg.AI.Tagging,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+
+		exportTimeout: time.Duration(cfg.Storage.FetchTimeout)*time.Second + exportWriteSlack,
+	}
+	h.startTagging()
+	return h
 }
 
 // WardrobeItem represents a clothing item in the wardrobe
@@ -294,9 +323,25 @@ func (h *WardrobeHandler) CreateWardrobeItem(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	itemID := uuid.New()
-	now := time.Now()
+	params := newWardrobeItemParams(userID, req, "good", time.Now())
 
+	item, err := h.db.CreateWardrobeItem(ctx, params)
+	if err != nil {
+		log.Printf("Error creating wardrobe item: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create item")
+		return
+	}
+
+	if len(req.Images) > 0 {
+		h.queueTagging(ctx, item.ID, userID)
+	}
+
+	wardrobeItem := h.convertDBItemToWardrobeItem(item)
+	utils.RespondWithJSON(w, http.StatusCreated, wardrobeItem)
+}
+
+// newWardrobeItemParams builds the insert parameters for a new, available and clean item
+func newWardrobeItemParams(userID uuid.UUID, req CreateWardrobeItemRequest, condition string, now time.Time) database.CreateWardrobeItemParams {
 	// Convert arrays to JSON for database
 	secondaryColorsJSON, _ := json.Marshal(req.SecondaryColors)
 	occasionJSON, _ := json.Marshal(req.Occasion)
@@ -306,49 +351,39 @@ func (h *WardrobeHandler) CreateWardrobeItem(w http.ResponseWriter, r *http.Requ
 	careInstructionsJSON, _ := json.Marshal(req.CareInstructions)
 	metadataJSON, _ := json.Marshal(req.Metadata)
 
-	params := database.CreateWardrobeItemParams{
-		ID:                    itemID,
-		UserID:                userID,
-		Name:                  req.Name,
-		Description:           pgtype.Text{String: utils.StringValue(req.Description), Valid: req.Description != nil},
-		Category:              req.Category,
-		Subcategory:           pgtype.Text{String: utils.StringValue(req.Subcategory), Valid: req.Subcategory != nil},
-		Brand:                 pgtype.Text{String: utils.StringValue(req.Brand), Valid: req.Brand != nil},
-		Color:                 req.Color,
-		SecondaryColors:       secondaryColorsJSON,
-		Size:                  pgtype.Text{String: utils.StringValue(req.Size), Valid: req.Size != nil},
-		Material:              pgtype.Text{String: utils.StringValue(req.Material), Valid: req.Material != nil},
-		Style:                 pgtype.Text{String: utils.StringValue(req.Style), Valid: req.Style != nil},
-		Occasion:              occasionJSON,
-		Season:                seasonJSON,
-		Pattern:               pgtype.Text{String: utils.StringValue(req.Pattern), Valid: req.Pattern != nil},
-		Images:                imagesJSON,
-		Tags:                  tagsJSON,
-		PurchaseDate:          pgtype.Timestamptz{Time: utils.TimeValue(req.PurchaseDate), Valid: req.PurchaseDate != nil},
-		PurchasePrice:         pgtype.Float8{Float64: utils.Float64Value(req.PurchasePrice), Valid: req.PurchasePrice != nil},
-		PurchaseLocation:      pgtype.Text{String: utils.StringValue(req.PurchaseLocation), Valid: req.PurchaseLocation != nil},
-		CareInstructions:      careInstructionsJSON,
-		IsFavorite:            req.IsFavorite,
-		IsAvailable:           true,
-		IsClean:               true,
-		WearCount:             0,
-		Condition:             "good",
-		QualityScore:          req.QualityScore,
-		SustainabilityScore:   pgtype.Int4{Int32: utils.Int32Value(req.SustainabilityScore), Valid: req.SustainabilityScore != nil},
-		Metadata:              metadataJSON,
-		CreatedAt:             now,
-		UpdatedAt:             now,
+	return database.CreateWardrobeItemParams{
+		ID:                  uuid.New(),
+		UserID:              userID,
+		Name:                req.Name,
+		Description:         pgtype.Text{String: utils.StringValue(req.Description), Valid: req.Description != nil},
+		Category:            req.Category,
+		Subcategory:         pgtype.Text{String: utils.StringValue(req.Subcategory), Valid: req.Subcategory != nil},
+		Brand:               pgtype.Text{String: utils.StringValue(req.Brand), Valid: req.Brand != nil},
+		Color:               req.Color,
+		SecondaryColors:     secondaryColorsJSON,
+		Size:                pgtype.Text{String: utils.StringValue(req.Size), Valid: req.Size != nil},
+		Material:            pgtype.Text{String: utils.StringValue(req.Material), Valid: req.Material != nil},
+		Style:               pgtype.Text{String: utils.StringValue(req.Style), Valid: req.Style != nil},
+		Occasion:            occasionJSON,
+		Season:              seasonJSON,
+		Pattern:             pgtype.Text{String: utils.StringValue(req.Pattern), Valid: req.Pattern != nil},
+		Images:              imagesJSON,
+		Tags:                tagsJSON,
+		PurchaseDate:        pgtype.Timestamptz{Time: utils.TimeValue(req.PurchaseDate), Valid: req.PurchaseDate != nil},
+		PurchasePrice:       pgtype.Float8{Float64: utils.Float64Value(req.PurchasePrice), Valid: req.PurchasePrice != nil},
+		PurchaseLocation:    pgtype.Text{String: utils.StringValue(req.PurchaseLocation), Valid: req.PurchaseLocation != nil},
+		CareInstructions:    careInstructionsJSON,
+		IsFavorite:          req.IsFavorite,
+		IsAvailable:         true,
+		IsClean:             true,
+		WearCount:           0,
+		Condition:           condition,
+		QualityScore:        req.QualityScore,
+		SustainabilityScore: pgtype.Int4{Int32: utils.Int32Value(req.SustainabilityScore), Valid: req.SustainabilityScore != nil},
+		Metadata:            metadataJSON,
+		CreatedAt:           now,
+		UpdatedAt:           now,
 	}
-
-	item, err := h.db.CreateWardrobeItem(ctx, params)
-	if err != nil {
-		log.Printf("Error creating wardrobe item: %v", err)
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create item")
-		return
-	}
-
-	wardrobeItem := h.convertDBItemToWardrobeItem(item)
-	utils.RespondWithJSON(w, http.StatusCreated, wardrobeItem)
 }
 
 // UpdateWardrobeItem updates an existing wardrobe item
@@ -500,6 +535,15 @@ func (h *WardrobeHandler) UpdateWardrobeItem(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// New images invalidate the AI suggestions
+	if req.Images != nil {
+		var existingImages []string
+		_ = json.Unmarshal(existingItem.Images, &existingImages)
+		if !slices.Equal(req.Images, existingImages) {
+			h.queueTagging(ctx, itemID, userID)
+		}
+	}
+
 	wardrobeItem := h.convertDBItemToWardrobeItem(item)
 	utils.RespondWithJSON(w, http.StatusOK, wardrobeItem)
 }
@@ -544,21 +588,6 @@ func (h *WardrobeHandler) DeleteWardrobeItem(w http.ResponseWriter, r *http.Requ
 	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Item deleted successfully"})
 }
 
-// GetWardrobeStats retrieves wardrobe statistics for the user
-func (h *WardrobeHandler) GetWardrobeStats(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	userID := auth.GetUserID(ctx)
-
-	stats, err := h.db.GetWardrobeStats(ctx, userID)
-	if err != nil {
-		log.Printf("Error getting wardrobe stats: %v", err)
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve wardrobe statistics")
-		return
-	}
-
-	utils.RespondWithJSON(w, http.StatusOK, stats)
-}
-
 // Helper function to convert database item to WardrobeItem
 func (h *WardrobeHandler) convertDBItemToWardrobeItem(item database.GetWardrobeItemsRow) WardrobeItem {
 	wardrobeItem := WardrobeItem{
@@ -681,11 +710,20 @@ func (h *WardrobeHandler) RegisterRoutes(r chi.Router) {
 	r.Route("/wardrobe", func(r chi.Router) {
 		r.Get("/", h.GetWardrobeItems)
 		r.Post("/", h.CreateWardrobeItem)
+		r.Post("/import", h.ImportWardrobeItems)
+		r.Get("/export", h.ExportWardrobeItems)
 		r.Get("/stats", h.GetWardrobeStats)
+		r.Post("/ai/retag", h.RetagWardrobe)
+		r.Post("/wear", h.LogOutfitWear)
+		r.Get("/wear/calendar", h.GetWearCalendar)
+		r.Delete("/wear/{eventId}", h.DeleteWear)
 		r.Route("/{id}", func(r chi.Router) {
 			r.Get("/", h.GetWardrobeItem)
 			r.Put("/", h.UpdateWardrobeItem)
 			r.Delete("/", h.DeleteWardrobeItem)
+			r.Post("/ai/retag", h.RetagWardrobeItem)
+			r.Post("/ai/accept", h.AcceptAISuggestions)
+			r.Post("/wear", h.LogWear)
 		})
 	})
 }
\ No newline at end of file
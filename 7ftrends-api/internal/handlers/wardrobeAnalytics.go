@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/models"
+	"github.com/your-org/7ftrends-api/internal/services"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// GetWardrobeStats returns the user's wardrobe analytics: breakdowns, value, wear
+// and cost-per-wear figures, health scores and recommendations
+func (h *WardrobeHandler) GetWardrobeStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+	now := time.Now()
+
+	rows, err := h.db.ListWardrobeItemsForAnalytics(ctx, database.ListWardrobeItemsForAnalyticsParams{
+		UserID:    userID,
+		WornSince: now.AddDate(0, 0, -services.WardrobeWearWindowDays),
+	})
+	if err != nil {
+		log.Printf("Error getting wardrobe stats: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve wardrobe statistics")
+		return
+	}
+
+	items := make([]services.WardrobeAnalyticsItem, len(rows))
+	for i, row := range rows {
+		items[i] = convertAnalyticsRow(row)
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, services.ComputeWardrobeStats(items, now))
+}
+
+// convertAnalyticsRow converts a database row to the analytics input
+func convertAnalyticsRow(row database.ListWardrobeItemsForAnalyticsRow) services.WardrobeAnalyticsItem {
+	item := services.WardrobeAnalyticsItem{
+		WardrobeItem: models.WardrobeItem{
+			BaseModel: models.BaseModel{
+				ID:        row.ID,
+				CreatedAt: row.CreatedAt,
+				UpdatedAt: row.UpdatedAt,
+			},
+			UserID:     row.UserID,
+			Name:       row.Name,
+			Category:   row.Category,
+			Color:      row.Color,
+			Tags:       []string{},
+			Images:     []string{},
+			IsFavorite: row.IsFavorite,
+			WearCount:  int(row.WearCount),
+			Condition:  row.Condition,
+		},
+		QualityScore: int(row.QualityScore),
+		RecentWears:  int(row.RecentWears),
+	}
+
+	_ = json.Unmarshal(row.Tags, &item.Tags)
+	_ = json.Unmarshal(row.Images, &item.Images)
+	if len(item.Images) > 0 {
+		item.PrimaryImage = item.Images[0]
+	}
+	var seasons []string
+	if err := json.Unmarshal(row.Season, &seasons); err == nil && len(seasons) > 0 {
+		season := strings.Join(seasons, ", ")
+		item.Season = &season
+	}
+	if len(row.Metadata) > 0 {
+		_ = json.Unmarshal(row.Metadata, &item.Metadata)
+	}
+
+	// Parse nullable fields
+	if row.Subcategory.Valid {
+		item.SubCategory = &row.Subcategory.String
+	}
+	if row.Brand.Valid {
+		item.Brand = &row.Brand.String
+	}
+	if row.Size.Valid {
+		item.Size = &row.Size.String
+	}
+	if row.Material.Valid {
+		item.Material = &row.Material.String
+	}
+	if row.Style.Valid {
+		item.Style = &row.Style.String
+	}
+	if row.Pattern.Valid {
+		item.Pattern = &row.Pattern.String
+	}
+	if row.PurchasePrice.Valid {
+		item.Price = &row.PurchasePrice.Float64
+	}
+	if row.PurchaseDate.Valid {
+		item.PurchaseDate = &row.PurchaseDate.Time
+	}
+	if row.LastWorn.Valid {
+		item.LastWorn = &row.LastWorn.Time
+	}
+	if row.SustainabilityScore.Valid {
+		score := int(row.SustainabilityScore.Int32)
+		item.SustainabilityScore = &score
+	}
+
+	return item
+}
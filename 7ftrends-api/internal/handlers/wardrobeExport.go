@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// exportPageSize is how many items an export reads from the database at a time
+const exportPageSize = 200
+
+// exportWriteSlack is added to the image fetch timeout to get the write deadline
+// for each page or image of an export
+const exportWriteSlack = 20 * time.Second
+
+// wardrobeExportTypes maps each export format to its content type
+var wardrobeExportTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"json": "application/json",
+	"zip":  "application/zip",
+}
+
+// wardrobeExportColumns are the CSV columns, one per WardrobeItem field. List
+// values are joined with ";" so the file can be imported again.
+var wardrobeExportColumns = []string{
+	"id", "user_id", "name", "description", "category", "subcategory", "brand", "color",
+	"secondary_colors", "size", "material", "style", "occasion", "season", "pattern",
+	"images", "tags", "purchase_date", "purchase_price", "purchase_location",
+	"care_instructions", "is_favorite", "is_available", "is_clean", "last_worn",
+	"wear_count", "condition", "quality_score", "sustainability_score", "metadata",
+	"ai_tags", "ai_category", "ai_colors", "ai_occasions", "ai_seasons", "ai_style",
+	"ai_materials", "ai_confidence", "ai_processed_at", "ai_status", "ai_error_message",
+	"created_at", "updated_at",
+}
+
+// ExportWardrobeItems streams the user's wardrobe as CSV, JSON or a zip archive of
+// the JSON and the item images. Admins can export another user's wardrobe with ?user_id=.
+func (h *WardrobeHandler) ExportWardrobeItems(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	contentType, ok := wardrobeExportTypes[format]
+	if !ok {
+		utils.RespondWithError(w, http.StatusBadRequest, "Format must be one of: csv, json, zip")
+		return
+	}
+
+	if target := r.URL.Query().Get("user_id"); target != "" {
+		if userRole(ctx) != "admin" {
+			utils.RespondWithError(w, http.StatusForbidden, "Only admins can export another user's wardrobe")
+			return
+		}
+		targetID, err := uuid.Parse(target)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		userID = targetID
+	}
+
+	// Read the first page before committing to a streamed response, so a database
+	// failure can still be reported with a proper status
+	pages := &wardrobePages{h: h, userID: userID}
+	first, err := pages.next(ctx)
+	if err != nil {
+		log.Printf("Error exporting wardrobe items: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to export wardrobe")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="wardrobe-%s.%s"`, time.Now().Format("2006-01-02"), format))
+	w.Header().Set("Cache-Control", "no-store")
+
+	// Every flush moves the write deadline forward, so a large export outlives the
+	// server's write timeout as long as each page or image arrives in time
+	controller := http.NewResponseController(w)
+	extendDeadline := func() {
+		if err := controller.SetWriteDeadline(time.Now().Add(h.exportTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Printf("Error extending wardrobe export deadline: %v", err)
+		}
+	}
+	flush := func() {
+		if err := controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Printf("Error flushing wardrobe export: %v", err)
+		}
+		extendDeadline()
+	}
+	extendDeadline()
+	w.WriteHeader(http.StatusOK)
+
+	switch format {
+	case "csv":
+		err = writeWardrobeCSV(ctx, w, flush, pages, first)
+	case "json":
+		err = writeWardrobeJSON(ctx, w, flush, pages, first)
+	case "zip":
+		err = h.writeWardrobeArchive(ctx, w, flush, pages, first)
+	}
+	if err != nil {
+		// The status is already sent, so the client sees a truncated file
+		log.Printf("Error streaming %s wardrobe export for user %s: %v", format, userID, err)
+		return
+	}
+
+	log.Printf("📤 Wardrobe exported as %s for user %s", format, userID)
+}
+
+// wardrobePages reads a user's wardrobe page by page in creation order
+type wardrobePages struct {
+	h      *WardrobeHandler
+	userID uuid.UUID
+	after  *WardrobeItem
+	done   bool
+}
+
+// next returns the next page of items, or nil once every item has been read
+func (p *wardrobePages) next(ctx context.Context) ([]WardrobeItem, error) {
+	if p.done {
+		return nil, nil
+	}
+
+	params := database.ListWardrobeItemsForExportParams{
+		UserID: p.userID,
+		Limit:  exportPageSize,
+	}
+	if p.after != nil {
+		params.AfterCreatedAt = pgtype.Timestamptz{Time: p.after.CreatedAt, Valid: true}
+		params.AfterID = pgtype.UUID{Bytes: p.after.ID, Valid: true}
+	}
+
+	rows, err := p.h.db.ListWardrobeItemsForExport(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]WardrobeItem, len(rows))
+	for i, row := range rows {
+		items[i] = p.h.convertDBItemToWardrobeItem(row)
+	}
+	if len(items) < exportPageSize {
+		p.done = true
+	} else {
+		p.after = &items[len(items)-1]
+	}
+	return items, nil
+}
+
+// each calls fn for every item, starting with the already read first page, and
+// pageDone after each page
+func (p *wardrobePages) each(ctx context.Context, first []WardrobeItem, fn func(WardrobeItem) error, pageDone func() error) error {
+	page := first
+	for len(page) > 0 {
+		for _, item := range page {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if err := pageDone(); err != nil {
+			return err
+		}
+
+		var err error
+		if page, err = p.next(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeWardrobeCSV streams items as CSV with one column per field
+func writeWardrobeCSV(ctx context.Context, w io.Writer, flush func(), pages *wardrobePages, first []WardrobeItem) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(wardrobeExportColumns); err != nil {
+		return err
+	}
+
+	err := pages.each(ctx, first, func(item WardrobeItem) error {
+		return out.Write(wardrobeCSVRecord(item))
+	}, func() error {
+		out.Flush()
+		flush()
+		return out.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// writeWardrobeJSON streams items as a JSON array
+func writeWardrobeJSON(ctx context.Context, w io.Writer, flush func(), pages *wardrobePages, first []WardrobeItem) error {
+	array := newJSONArrayWriter(w)
+	err := pages.each(ctx, first, array.write, func() error {
+		flush()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return array.close()
+}
+
+// exportImage is an item image to add to an archive
+type exportImage struct {
+	itemID uuid.UUID
+	index  int
+	url    string
+}
+
+// writeWardrobeArchive streams a zip with wardrobe.json, every item image that can
+// be fetched under images/<item_id>/<index>.<ext>, and an images.csv index that
+// maps each image URL to its file or the reason it is missing
+func (h *WardrobeHandler) writeWardrobeArchive(ctx context.Context, w io.Writer, flush func(), pages *wardrobePages, first []WardrobeItem) error {
+	archive := zip.NewWriter(w)
+
+	entry, err := archive.Create("wardrobe.json")
+	if err != nil {
+		return err
+	}
+
+	// Only the image URLs are kept while the JSON is written
+	var images []exportImage
+	array := newJSONArrayWriter(entry)
+	err = pages.each(ctx, first, func(item WardrobeItem) error {
+		for i, imageURL := range item.Images {
+			images = append(images, exportImage{itemID: item.ID, index: i, url: imageURL})
+		}
+		return array.write(item)
+	}, func() error {
+		if err := archive.Flush(); err != nil {
+			return err
+		}
+		flush()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := array.close(); err != nil {
+		return err
+	}
+
+	index := [][]string{{"item_id", "index", "url", "file", "error"}}
+	for _, image := range images {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		record := []string{image.itemID.String(), strconv.Itoa(image.index), image.url, "", ""}
+		fetched, err := h.fetcher.Fetch(ctx, image.url)
+		if err != nil {
+			record[4] = err.Error()
+			index = append(index, record)
+			continue
+		}
+
+		record[3] = fmt.Sprintf("images/%s/%d%s", image.itemID, image.index, exportImageExtension(fetched.MimeType))
+		// Images are already compressed, so store them as they are
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     record[3],
+			Method:   zip.Store,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(fetched.Data); err != nil {
+			return err
+		}
+		index = append(index, record)
+
+		if err := archive.Flush(); err != nil {
+			return err
+		}
+		flush()
+	}
+
+	entry, err = archive.Create("images.csv")
+	if err != nil {
+		return err
+	}
+	out := csv.NewWriter(entry)
+	if err := out.WriteAll(index); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// jsonArrayWriter streams values as the elements of one JSON array
+type jsonArrayWriter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func newJSONArrayWriter(w io.Writer) *jsonArrayWriter {
+	return &jsonArrayWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+// write appends one element
+func (a *jsonArrayWriter) write(item WardrobeItem) error {
+	separator := ","
+	if a.count == 0 {
+		separator = "["
+	}
+	if _, err := io.WriteString(a.w, separator); err != nil {
+		return err
+	}
+	a.count++
+	return a.enc.Encode(item)
+}
+
+// close ends the array, writing an empty one when nothing was written
+func (a *jsonArrayWriter) close() error {
+	end := "]\n"
+	if a.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(a.w, end)
+	return err
+}
+
+// wardrobeCSVRecord flattens an item in wardrobeExportColumns order
+func wardrobeCSVRecord(item WardrobeItem) []string {
+	metadata := ""
+	if len(item.Metadata) > 0 {
+		if data, err := json.Marshal(item.Metadata); err == nil {
+			metadata = string(data)
+		}
+	}
+
+	return []string{
+		item.ID.String(),
+		item.UserID.String(),
+		item.Name,
+		csvString(item.Description),
+		item.Category,
+		csvString(item.Subcategory),
+		csvString(item.Brand),
+		item.Color,
+		strings.Join(item.SecondaryColors, ";"),
+		csvString(item.Size),
+		csvString(item.Material),
+		csvString(item.Style),
+		strings.Join(item.Occasion, ";"),
+		strings.Join(item.Season, ";"),
+		csvString(item.Pattern),
+		strings.Join(item.Images, ";"),
+		strings.Join(item.Tags, ";"),
+		csvTime(item.PurchaseDate),
+		csvFloat(item.PurchasePrice),
+		csvString(item.PurchaseLocation),
+		strings.Join(item.CareInstructions, ";"),
+		strconv.FormatBool(item.IsFavorite),
+		strconv.FormatBool(item.IsAvailable),
+		strconv.FormatBool(item.IsClean),
+		csvTime(item.LastWorn),
+		strconv.Itoa(int(item.WearCount)),
+		item.Condition,
+		strconv.Itoa(int(item.QualityScore)),
+		csvInt32(item.SustainabilityScore),
+		metadata,
+		strings.Join(item.AITags, ";"),
+		csvString(item.AICategory),
+		strings.Join(item.AIColors, ";"),
+		strings.Join(item.AIOccasions, ";"),
+		strings.Join(item.AISeasons, ";"),
+		csvString(item.AIStyle),
+		strings.Join(item.AIMaterials, ";"),
+		csvFloat(item.AIConfidence),
+		csvTime(item.AIProcessedAt),
+		item.AIStatus,
+		csvString(item.AIErrorMessage),
+		item.CreatedAt.Format(time.RFC3339),
+		item.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func csvString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func csvTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.Format(time.RFC3339)
+}
+
+func csvFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
+
+func csvInt32(value *int32) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(int(*value))
+}
+
+// exportImageExtension returns the archive file extension for a fetched image type
+func exportImageExtension(mimeType string) string {
+	switch mimeType {
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".jpg"
+	}
+}
@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// Import limits
+const (
+	maxImportRows   = 500
+	maxImportBytes  = 5 << 20
+	importChunkSize = 100
+)
+
+// Import row statuses
+const (
+	ImportStatusCreated = "created"
+	ImportStatusValid   = "valid" // would be created, dry runs only
+	ImportStatusSkipped = "skipped"
+	ImportStatusFailed  = "failed"
+)
+
+// ImportWardrobeItem is one imported row: the create request fields plus the item condition
+type ImportWardrobeItem struct {
+	CreateWardrobeItemRequest
+	Condition string `json:"condition" validate:"omitempty,oneof=new excellent good fair poor"`
+}
+
+// ImportRowResult reports what happened to one imported row
+type ImportRowResult struct {
+	Row     int        `json:"row"` // 1-based, not counting the CSV header
+	Status  string     `json:"status"`
+	Name    string     `json:"name,omitempty"`
+	ItemID  *uuid.UUID `json:"item_id,omitempty"`
+	Reasons []string   `json:"reasons,omitempty"`
+}
+
+// ImportWardrobeResponse is the per-row report of an import
+type ImportWardrobeResponse struct {
+	DryRun         bool              `json:"dry_run"`
+	Total          int               `json:"total"`
+	Created        int               `json:"created"`
+	Valid          int               `json:"valid"`
+	Skipped        int               `json:"skipped"`
+	Failed         int               `json:"failed"`
+	IgnoredColumns []string          `json:"ignored_columns,omitempty"`
+	Rows           []ImportRowResult `json:"rows"`
+}
+
+// importRow is a parsed row waiting to be validated and saved
+type importRow struct {
+	item ImportWardrobeItem
+	err  error
+}
+
+// ImportWardrobeItems creates many wardrobe items from a CSV or JSON upload.
+// CSV columns use the JSON field names, with list values separated by ";" or "|".
+// Rows matching an existing item or an earlier row by name, category and color are
+// skipped, so an import can be re-run safely. With ?dry_run=true nothing is saved.
+func (h *WardrobeHandler) ImportWardrobeItems(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	var rows []importRow
+	var ignored []string
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		rows, ignored, err = parseImportCSV(body)
+	case "application/json", "":
+		rows, err = parseImportJSON(body)
+	default:
+		utils.RespondWithError(w, http.StatusUnsupportedMediaType, "Import must be text/csv or application/json")
+		return
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.RespondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Import must be at most %d MB", maxImportBytes>>20))
+			return
+		}
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid import file: %v", err))
+		return
+	}
+	if len(rows) == 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "Import contains no items")
+		return
+	}
+	if len(rows) > maxImportRows {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Import can contain at most %d items", maxImportRows))
+		return
+	}
+
+	existing, err := h.db.ListWardrobeItemKeys(ctx, userID)
+	if err != nil {
+		log.Printf("Error listing wardrobe items for import: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to import items")
+		return
+	}
+	seen := make(map[string]bool, len(existing)+len(rows))
+	for _, item := range existing {
+		seen[importKey(item.Name, item.Category, item.Color)] = true
+	}
+
+	response := ImportWardrobeResponse{
+		DryRun:         dryRun,
+		Total:          len(rows),
+		IgnoredColumns: ignored,
+		Rows:           make([]ImportRowResult, len(rows)),
+	}
+
+	// Validate every row first; pending holds the indexes of rows to insert
+	now := time.Now()
+	var pending []int
+	params := make([]database.CreateWardrobeItemParams, len(rows))
+	for i, row := range rows {
+		result := &response.Rows[i]
+		result.Row = i + 1
+		result.Name = row.item.Name
+
+		if row.err == nil {
+			row.err = utils.ValidateStruct(row.item)
+		}
+		if row.err != nil {
+			result.Status = ImportStatusFailed
+			result.Reasons = []string{row.err.Error()}
+			continue
+		}
+
+		key := importKey(row.item.Name, row.item.Category, row.item.Color)
+		if seen[key] {
+			result.Status = ImportStatusSkipped
+			result.Reasons = []string{"An item with the same name, category and color already exists"}
+			continue
+		}
+		seen[key] = true
+
+		condition := row.item.Condition
+		if condition == "" {
+			condition = "good"
+		}
+		params[i] = newWardrobeItemParams(userID, row.item.CreateWardrobeItemRequest, condition, now)
+		pending = append(pending, i)
+	}
+
+	if dryRun {
+		for _, i := range pending {
+			response.Rows[i].Status = ImportStatusValid
+		}
+	} else {
+		// Each chunk is saved atomically, so a failure only affects the rows in it
+		for start := 0; start < len(pending); start += importChunkSize {
+			chunk := pending[start:min(start+importChunkSize, len(pending))]
+			err := h.db.ExecTx(ctx, func(q *database.Queries) error {
+				for _, i := range chunk {
+					if _, err := q.CreateWardrobeItem(ctx, params[i]); err != nil {
+						return fmt.Errorf("row %d: %w", i+1, err)
+					}
+					if h.tagger == nil || len(rows[i].item.Images) == 0 {
+						continue
+					}
+					if _, err := q.QueueWardrobeItemTagging(ctx, database.QueueWardrobeItemTaggingParams{
+						ID:     params[i].ID,
+						UserID: userID,
+					}); err != nil {
+						return fmt.Errorf("row %d: %w", i+1, err)
+					}
+				}
+				return nil
+			})
+			for _, i := range chunk {
+				result := &response.Rows[i]
+				if err != nil {
+					result.Status = ImportStatusFailed
+					result.Reasons = []string{"Failed to save item"}
+					continue
+				}
+				itemID := params[i].ID
+				result.Status = ImportStatusCreated
+				result.ItemID = &itemID
+			}
+			if err != nil {
+				log.Printf("Error importing wardrobe items: %v", err)
+			}
+		}
+	}
+
+	for _, result := range response.Rows {
+		switch result.Status {
+		case ImportStatusCreated:
+			response.Created++
+		case ImportStatusValid:
+			response.Valid++
+		case ImportStatusSkipped:
+			response.Skipped++
+		case ImportStatusFailed:
+			response.Failed++
+		}
+	}
+
+	if response.Created > 0 {
+		h.wakeTagging()
+	}
+
+	if !dryRun {
+		log.Printf("📥 Wardrobe import for user %s: %d created, %d skipped, %d failed",
+			userID, response.Created, response.Skipped, response.Failed)
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// parseImportJSON reads a JSON array of items. A malformed item fails only its own row.
+func parseImportJSON(body io.Reader) ([]importRow, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	rows := make([]importRow, len(raw))
+	for i, item := range raw {
+		if err := json.Unmarshal(item, &rows[i].item); err != nil {
+			rows[i].err = fmt.Errorf("invalid item: %v", err)
+		}
+	}
+	return rows, nil
+}
+
+// parseImportCSV reads a CSV file with a header row. Unknown columns are returned
+// so the report can list them instead of failing the whole file.
+func parseImportCSV(body io.Reader) ([]importRow, []string, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := make([]string, len(header))
+	var ignored []string
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.ReplaceAll(name, " ", "_")
+		if !importColumns[name] {
+			if name != "" {
+				ignored = append(ignored, name)
+			}
+			name = ""
+		}
+		columns[i] = name
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, err
+			}
+			rows = append(rows, importRow{err: parseErr.Err})
+			continue
+		}
+		if len(rows) >= maxImportRows {
+			// Count the row so the size check rejects the file, without parsing the rest
+			rows = append(rows, importRow{})
+			break
+		}
+
+		var row importRow
+		for i, value := range record {
+			if i >= len(columns) || columns[i] == "" || strings.TrimSpace(value) == "" {
+				continue
+			}
+			if err := setImportField(&row.item, columns[i], strings.TrimSpace(value)); err != nil {
+				row.err = fmt.Errorf("%s: %v", columns[i], err)
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, ignored, nil
+}
+
+// importColumns are the CSV columns an import understands
+var importColumns = map[string]bool{
+	"name": true, "description": true, "category": true, "subcategory": true, "brand": true,
+	"color": true, "secondary_colors": true, "size": true, "material": true, "style": true,
+	"occasion": true, "season": true, "pattern": true, "images": true, "tags": true,
+	"purchase_date": true, "purchase_price": true, "purchase_location": true,
+	"care_instructions": true, "is_favorite": true, "quality_score": true,
+	"sustainability_score": true, "metadata": true, "condition": true,
+}
+
+// setImportField sets one CSV column on an item
+func setImportField(item *ImportWardrobeItem, column, value string) error {
+	switch column {
+	case "name":
+		item.Name = value
+	case "description":
+		item.Description = &value
+	case "category":
+		item.Category = strings.ToLower(value)
+	case "subcategory":
+		item.Subcategory = &value
+	case "brand":
+		item.Brand = &value
+	case "color":
+		item.Color = value
+	case "secondary_colors":
+		item.SecondaryColors = splitImportList(value)
+	case "size":
+		item.Size = &value
+	case "material":
+		item.Material = &value
+	case "style":
+		item.Style = &value
+	case "occasion":
+		item.Occasion = splitImportList(value)
+	case "season":
+		item.Season = splitImportList(value)
+	case "pattern":
+		item.Pattern = &value
+	case "images":
+		item.Images = splitImportList(value)
+	case "tags":
+		item.Tags = splitImportList(value)
+	case "purchase_date":
+		date, err := parseImportDate(value)
+		if err != nil {
+			return err
+		}
+		item.PurchaseDate = &date
+	case "purchase_price":
+		price, err := strconv.ParseFloat(strings.TrimPrefix(value, "$"), 64)
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		item.PurchasePrice = &price
+	case "purchase_location":
+		item.PurchaseLocation = &value
+	case "care_instructions":
+		item.CareInstructions = splitImportList(value)
+	case "is_favorite":
+		favorite, err := strconv.ParseBool(strings.ToLower(value))
+		if err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+		item.IsFavorite = favorite
+	case "quality_score":
+		score, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("must be a whole number")
+		}
+		item.QualityScore = int32(score)
+	case "sustainability_score":
+		score, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("must be a whole number")
+		}
+		sustainability := int32(score)
+		item.SustainabilityScore = &sustainability
+	case "metadata":
+		if err := json.Unmarshal([]byte(value), &item.Metadata); err != nil {
+			return fmt.Errorf("must be a JSON object")
+		}
+	case "condition":
+		item.Condition = strings.ToLower(value)
+	}
+	return nil
+}
+
+// splitImportList splits a CSV list value on ";" or "|"
+func splitImportList(value string) []string {
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == '|' })
+	list := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// parseImportDate accepts a plain date or an RFC 3339 timestamp
+func parseImportDate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be YYYY-MM-DD")
+	}
+	return date, nil
+}
+
+// importKey identifies an item for duplicate detection
+func importKey(name, category, color string) string {
+	return strings.ToLower(strings.TrimSpace(name)) + "\x00" + category + "\x00" + strings.ToLower(strings.TrimSpace(color))
+}
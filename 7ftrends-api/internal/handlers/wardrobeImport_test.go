@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	tests := []struct {
+		name        string
+		csv         string
+		wantNames   []string
+		wantErrRows []int // indexes of rows that failed to parse
+		wantIgnored []string
+	}{
+		{
+			name:      "header only",
+			csv:       "name,category,color\n",
+			wantNames: nil,
+		},
+		{
+			name:      "empty file",
+			csv:       "",
+			wantNames: nil,
+		},
+		{
+			name:      "basic rows",
+			csv:       "name,category,color\nWhite Tee,top,white\nJeans,bottom,blue\n",
+			wantNames: []string{"White Tee", "Jeans"},
+		},
+		{
+			name:      "header is normalized",
+			csv:       "\ufeffName , Category,Purchase Price\nCoat,outerwear,120\n",
+			wantNames: []string{"Coat"},
+		},
+		{
+			name:        "unknown columns are reported",
+			csv:         "name,category,color,owner,\nScarf,accessories,red,me,x\n",
+			wantNames:   []string{"Scarf"},
+			wantIgnored: []string{"owner"},
+		},
+		{
+			name:        "bad value fails only its row",
+			csv:         "name,category,purchase_price\nBoots,shoes,cheap\nSandals,shoes,40\n",
+			wantNames:   []string{"Boots", "Sandals"},
+			wantErrRows: []int{0},
+		},
+		{
+			name:        "malformed quoting fails only its row",
+			csv:         "name,category\n\"Broken,top\nHat,accessories\n",
+			wantNames:   []string{""},
+			wantErrRows: []int{0},
+		},
+		{
+			name:      "short and blank cells are skipped",
+			csv:       "name,category,color\nBelt,accessories\nSocks,,\n",
+			wantNames: []string{"Belt", "Socks"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, ignored, err := parseImportCSV(strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatalf("parseImportCSV() error = %v", err)
+			}
+
+			var names []string
+			var errRows []int
+			for i, row := range rows {
+				names = append(names, row.item.Name)
+				if row.err != nil {
+					errRows = append(errRows, i)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("names = %q, want %q", names, tt.wantNames)
+			}
+			if !reflect.DeepEqual(errRows, tt.wantErrRows) {
+				t.Errorf("failed rows = %v, want %v", errRows, tt.wantErrRows)
+			}
+			if !reflect.DeepEqual(ignored, tt.wantIgnored) {
+				t.Errorf("ignored columns = %v, want %v", ignored, tt.wantIgnored)
+			}
+		})
+	}
+}
+
+func TestParseImportCSVRowLimit(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("name,category\n")
+	for i := 0; i < maxImportRows+10; i++ {
+		b.WriteString("Tee,top\n")
+	}
+
+	rows, _, err := parseImportCSV(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("parseImportCSV() error = %v", err)
+	}
+	if len(rows) != maxImportRows+1 {
+		t.Errorf("got %d rows, want %d so the size check rejects the file", len(rows), maxImportRows+1)
+	}
+}
+
+func TestSetImportField(t *testing.T) {
+	tests := []struct {
+		column  string
+		value   string
+		check   func(ImportWardrobeItem) bool
+		wantErr bool
+	}{
+		{column: "category", value: "TOP", check: func(i ImportWardrobeItem) bool { return i.Category == "top" }},
+		{column: "brand", value: "Acme", check: func(i ImportWardrobeItem) bool { return i.Brand != nil && *i.Brand == "Acme" }},
+		{column: "tags", value: "summer; linen|work;;", check: func(i ImportWardrobeItem) bool {
+			return reflect.DeepEqual(i.Tags, []string{"summer", "linen", "work"})
+		}},
+		{column: "images", value: "https://a.example/1.jpg|https://a.example/2.jpg", check: func(i ImportWardrobeItem) bool {
+			return len(i.Images) == 2
+		}},
+		{column: "purchase_date", value: "2025-06-01", check: func(i ImportWardrobeItem) bool {
+			return i.PurchaseDate != nil && i.PurchaseDate.Equal(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+		}},
+		{column: "purchase_date", value: "2025-06-01T10:00:00Z", check: func(i ImportWardrobeItem) bool {
+			return i.PurchaseDate != nil && i.PurchaseDate.Hour() == 10
+		}},
+		{column: "purchase_date", value: "01/06/2025", wantErr: true},
+		{column: "purchase_price", value: "$49.90", check: func(i ImportWardrobeItem) bool { return i.PurchasePrice != nil && *i.PurchasePrice == 49.9 }},
+		{column: "purchase_price", value: "cheap", wantErr: true},
+		{column: "is_favorite", value: "TRUE", check: func(i ImportWardrobeItem) bool { return i.IsFavorite }},
+		{column: "is_favorite", value: "yes", wantErr: true},
+		{column: "quality_score", value: "8", check: func(i ImportWardrobeItem) bool { return i.QualityScore == 8 }},
+		{column: "quality_score", value: "8.5", wantErr: true},
+		{column: "sustainability_score", value: "6", check: func(i ImportWardrobeItem) bool {
+			return i.SustainabilityScore != nil && *i.SustainabilityScore == 6
+		}},
+		{column: "metadata", value: `{"source": "shop"}`, check: func(i ImportWardrobeItem) bool { return i.Metadata["source"] == "shop" }},
+		{column: "metadata", value: "source=shop", wantErr: true},
+		{column: "condition", value: "Good", check: func(i ImportWardrobeItem) bool { return i.Condition == "good" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.column+"="+tt.value, func(t *testing.T) {
+			var item ImportWardrobeItem
+			err := setImportField(&item, tt.column, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setImportField() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil && !tt.check(item) {
+				t.Errorf("setImportField(%q, %q) gave %+v", tt.column, tt.value, item)
+			}
+		})
+	}
+}
@@ -0,0 +1,535 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/services"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// Garment photos are scaled down before tagging; attributes don't need full resolution
+const (
+	taggingMaxDimension = 1024
+	taggingQuality      = 85
+	taggingMaxBackoff   = time.Hour
+)
+
+// AI suggestion fields that can be accepted into the primary item fields
+const (
+	AIFieldCategory  = "category"
+	AIFieldColors    = "colors"
+	AIFieldOccasions = "occasions"
+	AIFieldSeasons   = "seasons"
+	AIFieldStyle     = "style"
+	AIFieldMaterials = "materials"
+	AIFieldTags      = "tags"
+)
+
+// errNoTaggingImage is returned for an item whose image list is empty or unreadable
+var errNoTaggingImage = errors.New("item has no images")
+
+var allAIFields = []string{AIFieldCategory, AIFieldColors, AIFieldOccasions, AIFieldSeasons, AIFieldStyle, AIFieldMaterials, AIFieldTags}
+
+// AcceptAISuggestionsRequest selects the AI suggestions to copy into the item.
+// Single values replace the item's value, lists are merged into it. No fields means all.
+type AcceptAISuggestionsRequest struct {
+	Fields []string `json:"fields" validate:"dive,oneof=category colors occasions seasons style materials tags"`
+}
+
+// RetagResponse reports how many items were queued for tagging
+type RetagResponse struct {
+	Queued int64 `json:"queued"`
+}
+
+// startTagging starts the tagging workers. Items are queued in wardrobe_items itself,
+// so pending work survives a restart and is shared between instances.
+func (h *WardrobeHandler) startTagging() {
+	if h.tagger == nil {
+		log.Printf("🏷️ Wardrobe AI tagging disabled")
+		return
+	}
+
+	cfg := &h.tagging
+	if cfg.Workers <= 0 {
+		cfg.Workers = 2
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 10
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 30
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.JobTimeout <= 0 {
+		cfg.JobTimeout = 60
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		h.workers.Add(1)
+		go h.runTaggingWorker()
+	}
+	log.Printf("🏷️ Wardrobe AI tagging started with %d workers using %s", cfg.Workers, h.tagger.Model())
+}
+
+// Close stops the tagging workers and waits for the items in progress
+func (h *WardrobeHandler) Close() {
+	close(h.stop)
+	h.workers.Wait()
+}
+
+// wakeTagging tells an idle worker that items were queued
+func (h *WardrobeHandler) wakeTagging() {
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// queueTagging queues an item whose images were set or changed. Failures are only
+// logged: the item is saved and can be re-tagged later.
+func (h *WardrobeHandler) queueTagging(ctx context.Context, itemID, userID uuid.UUID) {
+	if h.tagger == nil {
+		return
+	}
+	queued, err := h.db.QueueWardrobeItemTagging(ctx, database.QueueWardrobeItemTaggingParams{
+		ID:     itemID,
+		UserID: userID,
+	})
+	if err != nil {
+		log.Printf("Error queueing wardrobe item %s for tagging: %v", itemID, err)
+		return
+	}
+	if queued > 0 {
+		h.wakeTagging()
+	}
+}
+
+// runTaggingWorker claims and tags due items until Close
+func (h *WardrobeHandler) runTaggingWorker() {
+	defer h.workers.Done()
+	ticker := time.NewTicker(time.Duration(h.tagging.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		jobs := h.claimTaggingJobs()
+		for _, job := range jobs {
+			select {
+			case <-h.stop:
+				// Unprocessed items are claimed again when their lease runs out
+				return
+			default:
+			}
+			h.tagItem(job)
+		}
+
+		// A full batch means more items are probably due
+		if len(jobs) == h.tagging.BatchSize {
+			continue
+		}
+		select {
+		case <-h.stop:
+			return
+		case <-h.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimTaggingJobs leases a batch of due items for long enough to tag all of them
+func (h *WardrobeHandler) claimTaggingJobs() []database.ClaimWardrobeTaggingJobsRow {
+	jobTimeout := time.Duration(h.tagging.JobTimeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	defer cancel()
+
+	jobs, err := h.db.ClaimWardrobeTaggingJobs(ctx, database.ClaimWardrobeTaggingJobsParams{
+		Limit:      int32(h.tagging.BatchSize),
+		LeaseUntil: time.Now().Add(time.Duration(h.tagging.BatchSize+1) * jobTimeout),
+	})
+	if err != nil {
+		log.Printf("Error claiming wardrobe tagging jobs: %v", err)
+		return nil
+	}
+	return jobs
+}
+
+// tagItem tags the first image of a claimed item and records the result. Failures
+// are retried with exponential backoff until the attempts run out.
+func (h *WardrobeHandler) tagItem(job database.ClaimWardrobeTaggingJobsRow) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(h.tagging.JobTimeout)*time.Second)
+	defer cancel()
+
+	attributes, err := h.tagImage(ctx, job.Images)
+	if err == nil {
+		err = h.completeTagging(ctx, job, attributes)
+		if err == nil {
+			return
+		}
+	}
+
+	// The job context may have run out, so the outcome is recorded with a fresh one
+	recordCtx, cancelRecord := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelRecord()
+
+	message := taggingErrorMessage(err)
+	var updated int64
+	if job.AiAttempts < int32(h.tagging.MaxAttempts) && taggingRetryable(err) {
+		delay := taggingBackoff(job.AiAttempts)
+		var providerErr *services.ProviderError
+		if errors.As(err, &providerErr) && providerErr.RetryAfter > delay {
+			delay = providerErr.RetryAfter
+		}
+		log.Printf("⚠️ Tagging wardrobe item %s failed (attempt %d), retrying in %s: %v", job.ID, job.AiAttempts, delay, err)
+		updated, err = h.db.RetryWardrobeTagging(recordCtx, database.RetryWardrobeTaggingParams{
+			ID:            job.ID,
+			RequestedAt:   job.AiRequestedAt,
+			ErrorMessage:  message,
+			NextAttemptAt: time.Now().Add(delay),
+		})
+	} else {
+		log.Printf("❌ Tagging wardrobe item %s failed permanently (attempt %d): %v", job.ID, job.AiAttempts, err)
+		updated, err = h.db.FailWardrobeTagging(recordCtx, database.FailWardrobeTaggingParams{
+			ID:           job.ID,
+			RequestedAt:  job.AiRequestedAt,
+			ErrorMessage: message,
+		})
+	}
+	if err != nil {
+		log.Printf("Error recording tagging failure for wardrobe item %s: %v", job.ID, err)
+	} else if updated == 0 {
+		log.Printf("Tagging result for wardrobe item %s discarded, the item was re-queued or deleted", job.ID)
+	}
+}
+
+// tagImage loads the item's first image and runs the tagger on it
+func (h *WardrobeHandler) tagImage(ctx context.Context, imagesJSON []byte) (*services.GarmentAttributes, error) {
+	var images []string
+	if err := json.Unmarshal(imagesJSON, &images); err != nil || len(images) == 0 {
+		return nil, errNoTaggingImage
+	}
+
+	fetched, err := h.fetcher.Fetch(ctx, images[0])
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := services.NormalizeImage(fetched.Data, taggingMaxDimension, taggingQuality)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.tagger.Tag(ctx, services.InputImage{
+		MimeType: normalized.MimeType,
+		Data:     normalized.Data,
+	})
+}
+
+// completeTagging stores the attributes unless the item was re-queued in the meantime
+func (h *WardrobeHandler) completeTagging(ctx context.Context, job database.ClaimWardrobeTaggingJobsRow, attributes *services.GarmentAttributes) error {
+	tagsJSON, _ := json.Marshal(nonNilStrings(attributes.Tags))
+	colorsJSON, _ := json.Marshal(nonNilStrings(attributes.Colors))
+	occasionsJSON, _ := json.Marshal(nonNilStrings(attributes.Occasions))
+	seasonsJSON, _ := json.Marshal(nonNilStrings(attributes.Seasons))
+	materialsJSON, _ := json.Marshal(nonNilStrings(attributes.Materials))
+
+	updated, err := h.db.CompleteWardrobeTagging(ctx, database.CompleteWardrobeTaggingParams{
+		ID:           job.ID,
+		RequestedAt:  job.AiRequestedAt,
+		AiTags:       tagsJSON,
+		AiCategory:   pgtype.Text{String: attributes.Category, Valid: attributes.Category != ""},
+		AiColors:     colorsJSON,
+		AiOccasions:  occasionsJSON,
+		AiSeasons:    seasonsJSON,
+		AiStyle:      pgtype.Text{String: attributes.Style, Valid: attributes.Style != ""},
+		AiMaterials:  materialsJSON,
+		AiConfidence: pgtype.Float8{Float64: attributes.Confidence, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		log.Printf("Tagging result for wardrobe item %s discarded, the item was re-queued or deleted", job.ID)
+		return nil
+	}
+	log.Printf("✅ Tagged wardrobe item %s as %q (confidence %.2f)", job.ID, attributes.Category, attributes.Confidence)
+	return nil
+}
+
+// taggingRetryable reports whether tagging the item again may succeed. Rejected or
+// unreadable images and rejected provider requests fail the same way every time.
+func taggingRetryable(err error) bool {
+	for _, permanent := range []error{
+		errNoTaggingImage,
+		services.ErrUnsupportedImageSource,
+		services.ErrImageHostNotAllowed,
+		services.ErrImageAddressBlocked,
+		services.ErrImageTooLarge,
+		services.ErrImageTypeNotAllowed,
+		services.ErrUndecodableImage,
+	} {
+		if errors.Is(err, permanent) {
+			return false
+		}
+	}
+
+	var providerErr *services.ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.Retryable()
+	}
+	return true
+}
+
+// taggingBackoff doubles the delay after every attempt, starting at one minute
+func taggingBackoff(attempts int32) time.Duration {
+	delay := time.Minute
+	for i := int32(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= taggingMaxBackoff {
+			return taggingMaxBackoff
+		}
+	}
+	return delay
+}
+
+// taggingErrorMessage is the error stored on the item and shown to its owner.
+// Provider responses are only logged.
+func taggingErrorMessage(err error) string {
+	var providerErr *services.ProviderError
+	switch {
+	case errors.As(err, &providerErr) && providerErr.StatusCode != 0:
+		return fmt.Sprintf("AI provider returned status %d", providerErr.StatusCode)
+	case errors.As(err, &providerErr):
+		return "AI provider could not be reached"
+	case !taggingRetryable(err):
+		return fmt.Sprintf("Image could not be tagged: %v", err)
+	default:
+		return "AI tagging failed"
+	}
+}
+
+// RetagWardrobe queues the user's items with images for tagging
+func (h *WardrobeHandler) RetagWardrobe(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	if h.tagger == nil {
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "AI tagging is not enabled")
+		return
+	}
+
+	onlyUntagged := false
+	if value := r.URL.Query().Get("only_untagged"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "only_untagged must be true or false")
+			return
+		}
+		onlyUntagged = parsed
+	}
+
+	queued, err := h.db.QueueWardrobeTagging(ctx, database.QueueWardrobeTaggingParams{
+		UserID:       userID,
+		OnlyUntagged: onlyUntagged,
+	})
+	if err != nil {
+		log.Printf("Error queueing wardrobe for tagging: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to queue items for tagging")
+		return
+	}
+	if queued > 0 {
+		h.wakeTagging()
+	}
+
+	utils.RespondWithJSON(w, http.StatusAccepted, RetagResponse{Queued: queued})
+}
+
+// RetagWardrobeItem queues a single item for tagging
+func (h *WardrobeHandler) RetagWardrobeItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	if h.tagger == nil {
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "AI tagging is not enabled")
+		return
+	}
+
+	itemID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid item ID")
+		return
+	}
+
+	queued, err := h.db.QueueWardrobeItemTagging(ctx, database.QueueWardrobeItemTaggingParams{
+		ID:     itemID,
+		UserID: userID,
+	})
+	if err != nil {
+		log.Printf("Error queueing wardrobe item for tagging: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to queue item for tagging")
+		return
+	}
+	if queued == 0 {
+		// Tell a missing item apart from one without images
+		if _, err := h.db.GetWardrobeItem(ctx, database.GetWardrobeItemParams{ID: itemID, UserID: userID}); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				utils.RespondWithError(w, http.StatusNotFound, "Item not found")
+				return
+			}
+			log.Printf("Error getting wardrobe item for tagging: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve item")
+			return
+		}
+		utils.RespondWithError(w, http.StatusBadRequest, "Item has no images to tag")
+		return
+	}
+	h.wakeTagging()
+
+	utils.RespondWithJSON(w, http.StatusAccepted, RetagResponse{Queued: queued})
+}
+
+// AcceptAISuggestions copies the item's completed AI suggestions into its primary fields
+func (h *WardrobeHandler) AcceptAISuggestions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	itemID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid item ID")
+		return
+	}
+
+	var req AcceptAISuggestionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fields := req.Fields
+	if len(fields) == 0 {
+		fields = allAIFields
+	}
+
+	existing, err := h.db.GetWardrobeItem(ctx, database.GetWardrobeItemParams{
+		ID:     itemID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "Item not found")
+			return
+		}
+		log.Printf("Error getting wardrobe item for AI suggestions: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve item")
+		return
+	}
+
+	item := h.convertDBItemToWardrobeItem(existing)
+	if item.AIStatus != "completed" {
+		utils.RespondWithError(w, http.StatusConflict, "Item has no AI suggestions to accept")
+		return
+	}
+
+	params := aiSuggestionParams(item, fields)
+	params.ID = itemID
+	params.UserID = userID
+
+	updated, err := h.db.AcceptWardrobeAISuggestions(ctx, params)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "Item not found")
+			return
+		}
+		log.Printf("Error accepting AI suggestions: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update item")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, h.convertDBItemToWardrobeItem(updated))
+}
+
+// aiSuggestionParams builds the update for the accepted fields. Fields without a
+// suggestion stay NULL and keep the item's value.
+func aiSuggestionParams(item WardrobeItem, fields []string) database.AcceptWardrobeAISuggestionsParams {
+	var params database.AcceptWardrobeAISuggestionsParams
+
+	for _, field := range fields {
+		switch field {
+		case AIFieldCategory:
+			if category := utils.StringValue(item.AICategory); category != "" {
+				params.Category = pgtype.Text{String: category, Valid: true}
+			}
+		case AIFieldColors:
+			// The most prominent color becomes the main color, the rest are secondary
+			if len(item.AIColors) == 0 {
+				continue
+			}
+			primary := item.AIColors[0]
+			params.Color = pgtype.Text{String: primary, Valid: true}
+			var secondary []string
+			for _, color := range mergeStrings(item.SecondaryColors, item.AIColors[1:]) {
+				if !strings.EqualFold(color, primary) {
+					secondary = append(secondary, color)
+				}
+			}
+			params.SecondaryColors, _ = json.Marshal(nonNilStrings(secondary))
+		case AIFieldOccasions:
+			if len(item.AIOccasions) > 0 {
+				params.Occasion, _ = json.Marshal(mergeStrings(item.Occasion, item.AIOccasions))
+			}
+		case AIFieldSeasons:
+			if len(item.AISeasons) > 0 {
+				params.Season, _ = json.Marshal(mergeStrings(item.Season, item.AISeasons))
+			}
+		case AIFieldStyle:
+			if style := utils.StringValue(item.AIStyle); style != "" {
+				params.Style = pgtype.Text{String: style, Valid: true}
+			}
+		case AIFieldMaterials:
+			if len(item.AIMaterials) > 0 {
+				params.Material = pgtype.Text{String: strings.Join(item.AIMaterials, ", "), Valid: true}
+			}
+		case AIFieldTags:
+			if len(item.AITags) > 0 {
+				params.Tags, _ = json.Marshal(mergeStrings(item.Tags, item.AITags))
+			}
+		}
+	}
+
+	return params
+}
+
+// mergeStrings appends the values of extra missing from base, ignoring case
+func mergeStrings(base, extra []string) []string {
+	merged := append([]string{}, base...)
+	for _, value := range extra {
+		found := false
+		for _, existing := range merged {
+			if strings.EqualFold(existing, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, value)
+		}
+	}
+	return merged
+}
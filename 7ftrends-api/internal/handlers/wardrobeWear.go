@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/your-org/7ftrends-api/internal/auth"
+	"github.com/your-org/7ftrends-api/internal/database"
+	"github.com/your-org/7ftrends-api/internal/utils"
+)
+
+// Wear logging limits
+const (
+	maxWearCalendarDays     = 366
+	defaultWearCalendarDays = 30
+)
+
+// Errors that abort a wear transaction
+var (
+	errWearItemNotFound  = errors.New("wardrobe item not found")
+	errWearAlreadyLogged = errors.New("wear already logged for this date")
+)
+
+// LogWearRequest records that an item was worn
+type LogWearRequest struct {
+	Date     *string `json:"date"` // YYYY-MM-DD, defaults to today (UTC)
+	PhotoURL *string `json:"photo_url" validate:"omitempty,url,max=2048"`
+}
+
+// LogOutfitWearRequest records that several items were worn together
+type LogOutfitWearRequest struct {
+	ItemIDs  []uuid.UUID `json:"item_ids" validate:"required,min=1,max=20"`
+	Date     *string     `json:"date"` // YYYY-MM-DD, defaults to today (UTC)
+	PhotoURL *string     `json:"photo_url" validate:"omitempty,url,max=2048"`
+}
+
+// WearEvent is one logged wear of an item
+type WearEvent struct {
+	ID        uuid.UUID  `json:"id"`
+	ItemID    uuid.UUID  `json:"item_id"`
+	WornOn    string     `json:"worn_on"`
+	PhotoURL  *string    `json:"photo_url"`
+	OutfitID  *uuid.UUID `json:"outfit_id"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ItemWear holds an item's wear counters after a change
+type ItemWear struct {
+	ItemID    uuid.UUID  `json:"item_id"`
+	WearCount int32      `json:"wear_count"`
+	LastWorn  *time.Time `json:"last_worn"`
+}
+
+type LogWearResponse struct {
+	Event WearEvent `json:"event"`
+	Item  ItemWear  `json:"item"`
+}
+
+type LogOutfitWearResponse struct {
+	OutfitID      uuid.UUID   `json:"outfit_id"`
+	WornOn        string      `json:"worn_on"`
+	Events        []WearEvent `json:"events"`
+	Items         []ItemWear  `json:"items"`
+	AlreadyLogged []uuid.UUID `json:"already_logged"`
+}
+
+type DeleteWearResponse struct {
+	Message string   `json:"message"`
+	Item    ItemWear `json:"item"`
+}
+
+// WearCalendarEntry is an item worn on a calendar day
+type WearCalendarEntry struct {
+	EventID  uuid.UUID  `json:"event_id"`
+	ItemID   uuid.UUID  `json:"item_id"`
+	Name     string     `json:"name"`
+	Category string     `json:"category"`
+	Image    *string    `json:"image"`
+	PhotoURL *string    `json:"photo_url"`
+	OutfitID *uuid.UUID `json:"outfit_id"`
+}
+
+type WearCalendarDay struct {
+	Date  string              `json:"date"`
+	Items []WearCalendarEntry `json:"items"`
+}
+
+// WearCalendarResponse lists the days in the range on which something was worn
+type WearCalendarResponse struct {
+	From string            `json:"from"`
+	To   string            `json:"to"`
+	Days []WearCalendarDay `json:"days"`
+}
+
+// LogWear records that an item was worn and updates its counters
+func (h *WardrobeHandler) LogWear(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	itemID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid item ID")
+		return
+	}
+
+	var req LogWearRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	wornOn, err := parseWearDate(req.Date, time.Now())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var response LogWearResponse
+	err = h.db.ExecTx(ctx, func(q *database.Queries) error {
+		event, wear, err := logItemWear(ctx, q, userID, itemID, wornOn, req.PhotoURL, pgtype.UUID{})
+		if err != nil {
+			return err
+		}
+		response = LogWearResponse{Event: convertWearEvent(event), Item: convertItemWear(wear)}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errWearItemNotFound):
+			utils.RespondWithError(w, http.StatusNotFound, "Item not found")
+		case errors.Is(err, errWearAlreadyLogged):
+			utils.RespondWithError(w, http.StatusConflict, "Item is already logged as worn on this date")
+		default:
+			log.Printf("Error logging wear: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to log wear")
+		}
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, response)
+}
+
+// LogOutfitWear records that several items were worn together on one day. Either
+// every item is logged or none is; items already logged for the day are skipped.
+func (h *WardrobeHandler) LogOutfitWear(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	var req LogOutfitWearRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	wornOn, err := parseWearDate(req.Date, time.Now())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	outfitID := uuid.New()
+	response := LogOutfitWearResponse{
+		OutfitID:      outfitID,
+		WornOn:        wornOn.Format("2006-01-02"),
+		Events:        []WearEvent{},
+		Items:         []ItemWear{},
+		AlreadyLogged: []uuid.UUID{},
+	}
+
+	var missing uuid.UUID
+	err = h.db.ExecTx(ctx, func(q *database.Queries) error {
+		seen := make(map[uuid.UUID]bool, len(req.ItemIDs))
+		for _, itemID := range req.ItemIDs {
+			if seen[itemID] {
+				continue
+			}
+			seen[itemID] = true
+
+			event, wear, err := logItemWear(ctx, q, userID, itemID, wornOn, req.PhotoURL, pgtype.UUID{Bytes: outfitID, Valid: true})
+			if errors.Is(err, errWearAlreadyLogged) {
+				response.AlreadyLogged = append(response.AlreadyLogged, itemID)
+				continue
+			}
+			if errors.Is(err, errWearItemNotFound) {
+				missing = itemID
+			}
+			if err != nil {
+				return err
+			}
+			response.Events = append(response.Events, convertWearEvent(event))
+			response.Items = append(response.Items, convertItemWear(wear))
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errWearItemNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, fmt.Sprintf("Item %s not found", missing))
+			return
+		}
+		log.Printf("Error logging outfit wear: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to log outfit")
+		return
+	}
+
+	if len(response.Events) == 0 {
+		utils.RespondWithError(w, http.StatusConflict, "Every item is already logged as worn on this date")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, response)
+}
+
+// logItemWear records a wear event and counts it on the item, within the caller's transaction
+func logItemWear(ctx context.Context, q *database.Queries, userID, itemID uuid.UUID, wornOn time.Time, photoURL *string, outfitID pgtype.UUID) (database.WardrobeWearEventRow, database.WardrobeItemWearRow, error) {
+	date := pgtype.Date{Time: wornOn, Valid: true}
+
+	event, err := q.CreateWardrobeWearEvent(ctx, database.CreateWardrobeWearEventParams{
+		ID:       uuid.New(),
+		UserID:   userID,
+		ItemID:   itemID,
+		WornOn:   date,
+		PhotoUrl: pgtype.Text{String: utils.StringValue(photoURL), Valid: photoURL != nil},
+		OutfitID: outfitID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Either a repeat for the day or not the user's item
+		if _, err := q.GetWardrobeItem(ctx, database.GetWardrobeItemParams{ID: itemID, UserID: userID}); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return event, database.WardrobeItemWearRow{}, errWearItemNotFound
+			}
+			return event, database.WardrobeItemWearRow{}, err
+		}
+		return event, database.WardrobeItemWearRow{}, errWearAlreadyLogged
+	}
+	if err != nil {
+		return event, database.WardrobeItemWearRow{}, err
+	}
+
+	wear, err := q.RecordWardrobeItemWear(ctx, database.RecordWardrobeItemWearParams{
+		ID:     itemID,
+		UserID: userID,
+		WornOn: date,
+	})
+	return event, wear, err
+}
+
+// DeleteWear deletes a mistaken wear event and recomputes the item's counters
+func (h *WardrobeHandler) DeleteWear(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+
+	eventID, err := uuid.Parse(chi.URLParam(r, "eventId"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid wear event ID")
+		return
+	}
+
+	var wear database.WardrobeItemWearRow
+	err = h.db.ExecTx(ctx, func(q *database.Queries) error {
+		event, err := q.DeleteWardrobeWearEvent(ctx, database.DeleteWardrobeWearEventParams{
+			ID:     eventID,
+			UserID: userID,
+		})
+		if err != nil {
+			return err
+		}
+		wear, err = q.RemoveWardrobeItemWear(ctx, database.RemoveWardrobeItemWearParams{
+			ID:     event.ItemID,
+			UserID: userID,
+			WornOn: event.WornOn,
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "Wear event not found")
+			return
+		}
+		log.Printf("Error deleting wear event: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete wear event")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, DeleteWearResponse{
+		Message: "Wear event deleted successfully",
+		Item:    convertItemWear(wear),
+	})
+}
+
+// GetWearCalendar lists what was worn on each day of a date range, optionally for one item.
+// The range defaults to the last 30 days.
+func (h *WardrobeHandler) GetWearCalendar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := auth.GetUserID(ctx)
+	query := r.URL.Query()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to, from := today, today.AddDate(0, 0, 1-defaultWearCalendarDays)
+	if value := query.Get("to"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "to must be a YYYY-MM-DD date")
+			return
+		}
+		to, from = parsed, parsed.AddDate(0, 0, 1-defaultWearCalendarDays)
+	}
+	if value := query.Get("from"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "from must be a YYYY-MM-DD date")
+			return
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		utils.RespondWithError(w, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+	if to.Sub(from) >= maxWearCalendarDays*24*time.Hour {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Range can span at most %d days", maxWearCalendarDays))
+		return
+	}
+
+	params := database.ListWardrobeWearCalendarParams{
+		UserID: userID,
+		From:   pgtype.Date{Time: from, Valid: true},
+		To:     pgtype.Date{Time: to, Valid: true},
+	}
+	if value := query.Get("item_id"); value != "" {
+		itemID, err := uuid.Parse(value)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid item ID")
+			return
+		}
+		params.ItemID = pgtype.UUID{Bytes: itemID, Valid: true}
+	}
+
+	rows, err := h.db.ListWardrobeWearCalendar(ctx, params)
+	if err != nil {
+		log.Printf("Error listing wear calendar: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve wear calendar")
+		return
+	}
+
+	response := WearCalendarResponse{
+		From: from.Format("2006-01-02"),
+		To:   to.Format("2006-01-02"),
+		Days: []WearCalendarDay{},
+	}
+	// Rows are ordered by date, so each day's entries are contiguous
+	for _, row := range rows {
+		date := row.WornOn.Time.Format("2006-01-02")
+		if len(response.Days) == 0 || response.Days[len(response.Days)-1].Date != date {
+			response.Days = append(response.Days, WearCalendarDay{Date: date})
+		}
+		day := &response.Days[len(response.Days)-1]
+
+		entry := WearCalendarEntry{
+			EventID:  row.ID,
+			ItemID:   row.ItemID,
+			Name:     row.Name,
+			Category: row.Category,
+		}
+		var images []string
+		if err := json.Unmarshal(row.Images, &images); err == nil && len(images) > 0 {
+			entry.Image = &images[0]
+		}
+		if row.PhotoUrl.Valid {
+			entry.PhotoURL = &row.PhotoUrl.String
+		}
+		if row.OutfitID.Valid {
+			outfitID := uuid.UUID(row.OutfitID.Bytes)
+			entry.OutfitID = &outfitID
+		}
+		day.Items = append(day.Items, entry)
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// parseWearDate reads a YYYY-MM-DD wear date, defaulting to today (UTC). One day
+// ahead is allowed for users whose local date is ahead of UTC.
+func parseWearDate(value *string, now time.Time) (time.Time, error) {
+	today := now.UTC().Truncate(24 * time.Hour)
+	if value == nil || *value == "" {
+		return today, nil
+	}
+
+	date, err := time.Parse("2006-01-02", *value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date must be a YYYY-MM-DD date")
+	}
+	if date.After(today.AddDate(0, 0, 1)) {
+		return time.Time{}, fmt.Errorf("date cannot be in the future")
+	}
+	return date, nil
+}
+
+// convertWearEvent converts a database wear event to its response format
+func convertWearEvent(row database.WardrobeWearEventRow) WearEvent {
+	event := WearEvent{
+		ID:        row.ID,
+		ItemID:    row.ItemID,
+		WornOn:    row.WornOn.Time.Format("2006-01-02"),
+		CreatedAt: row.CreatedAt,
+	}
+	if row.PhotoUrl.Valid {
+		event.PhotoURL = &row.PhotoUrl.String
+	}
+	if row.OutfitID.Valid {
+		outfitID := uuid.UUID(row.OutfitID.Bytes)
+		event.OutfitID = &outfitID
+	}
+	return event
+}
+
+// convertItemWear converts an item's updated wear counters to their response format
+func convertItemWear(row database.WardrobeItemWearRow) ItemWear {
+	wear := ItemWear{
+		ItemID:    row.ID,
+		WearCount: row.WearCount,
+	}
+	if row.LastWorn.Valid {
+		wear.LastWorn = &row.LastWorn.Time
+	}
+	return wear
+}
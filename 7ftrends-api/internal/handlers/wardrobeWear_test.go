@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/your-org/7ftrends-api/internal/database"
+)
+
+func TestLogItemWear(t *testing.T) {
+	tests := []struct {
+		name        string
+		logged      bool // the insert returns the new event
+		itemExists  bool
+		wantErr     error
+		wantRecords int
+	}{
+		{name: "new wear", logged: true, itemExists: true, wantRecords: 1},
+		{name: "already worn that day", itemExists: true, wantErr: errWearAlreadyLogged},
+		{name: "not the user's item", wantErr: errWearItemNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			if tt.logged {
+				db.rows["CreateWardrobeWearEvent"] = fakeRow{}
+			}
+			if tt.itemExists {
+				db.rows["GetWardrobeItem"] = fakeRow{}
+			}
+			db.rows["RecordWardrobeItemWear"] = fakeRow{}
+
+			_, _, err := logItemWear(context.Background(), database.New(db), uuid.New(), uuid.New(), time.Now(), nil, pgtype.UUID{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("logItemWear() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := len(db.called("RecordWardrobeItemWear")); got != tt.wantRecords {
+				t.Errorf("counted the wear %d times, want %d", got, tt.wantRecords)
+			}
+		})
+	}
+}
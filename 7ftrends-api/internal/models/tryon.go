@@ -1,7 +1,11 @@
 package models
 
 import (
+	"fmt"
+	"regexp"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // TryOnSession represents an AR try-on session
@@ -29,6 +33,89 @@ type TryOnSettings struct {
 	Customizations  map[string]interface{} `json:"customizations" db:"customizations"`
 }
 
+// Supported TryOnSettings values. Empty fields leave the model's defaults in place.
+var (
+	TryOnBackgrounds = []string{"remove", "blur", "replace"}
+	TryOnLightings   = []string{"natural", "studio", "custom"}
+	TryOnFilters     = []string{"brightness", "contrast", "saturation"}
+	TryOnQualities   = []string{"low", "medium", "high"}
+
+	// TryOnCustomizations lists the accepted customization keys
+	TryOnCustomizations = []string{"lighting_description"}
+)
+
+// maxLightingDescription bounds the free-text custom lighting description
+const maxLightingDescription = 200
+
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Validate checks every field against the supported values. Background "replace"
+// needs a #RRGGBB BackgroundColor and Lighting "custom" needs a
+// lighting_description customization.
+func (s TryOnSettings) Validate() error {
+	if s.Background != "" && !containsString(TryOnBackgrounds, s.Background) {
+		return fmt.Errorf("settings.background must be one of %v", TryOnBackgrounds)
+	}
+	if s.Background == "replace" {
+		if !hexColorPattern.MatchString(s.BackgroundColor) {
+			return fmt.Errorf("settings.background_color must be a #RRGGBB color when background is replace")
+		}
+	} else if s.BackgroundColor != "" {
+		return fmt.Errorf("settings.background_color is only allowed when background is replace")
+	}
+
+	if s.Lighting != "" && !containsString(TryOnLightings, s.Lighting) {
+		return fmt.Errorf("settings.lighting must be one of %v", TryOnLightings)
+	}
+
+	seen := make(map[string]bool, len(s.Filters))
+	for _, filter := range s.Filters {
+		if !containsString(TryOnFilters, filter) {
+			return fmt.Errorf("settings.filters must only contain %v", TryOnFilters)
+		}
+		if seen[filter] {
+			return fmt.Errorf("settings.filters contains %q more than once", filter)
+		}
+		seen[filter] = true
+	}
+
+	if s.Quality != "" && !containsString(TryOnQualities, s.Quality) {
+		return fmt.Errorf("settings.quality must be one of %v", TryOnQualities)
+	}
+
+	for key := range s.Customizations {
+		if !containsString(TryOnCustomizations, key) {
+			return fmt.Errorf("settings.customizations.%s is not supported", key)
+		}
+	}
+	description, hasDescription := s.Customizations["lighting_description"]
+	if s.Lighting == "custom" {
+		text, ok := description.(string)
+		if !ok || text == "" || len(text) > maxLightingDescription {
+			return fmt.Errorf("settings.customizations.lighting_description must be 1-%d characters when lighting is custom", maxLightingDescription)
+		}
+	} else if hasDescription {
+		return fmt.Errorf("settings.customizations.lighting_description is only allowed when lighting is custom")
+	}
+
+	return nil
+}
+
+// LightingDescription returns the custom lighting description, if any
+func (s TryOnSettings) LightingDescription() string {
+	text, _ := s.Customizations["lighting_description"].(string)
+	return text
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // TryOnRequest represents a try-on request
 type TryOnRequest struct {
 	UserID         uuid.UUID     `json:"user_id" binding:"required"`
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreaker fails calls fast after repeated provider failures.
+// After the cooldown a single trial call is let through; its outcome closes
+// the circuit again or restarts the cooldown.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	open      bool
+	probing   bool
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive
+// failures. A threshold of zero or less disables it.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow reserves a call, returning ErrCircuitOpen while the provider is considered down
+func (b *CircuitBreaker) Allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if b.probing || time.Now().Sub(b.openedAt) < b.cooldown {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// Ready reports whether a call would currently be allowed, without reserving it
+func (b *CircuitBreaker) Ready() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !b.open || (!b.probing && time.Now().Sub(b.openedAt) >= b.cooldown)
+}
+
+// Success records a call that reached a working provider and closes the circuit
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.open = false
+	b.probing = false
+}
+
+// Failure records a call that failed because the provider is unavailable
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.probing || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.open = true
+		b.openedAt = time.Now()
+	}
+	b.probing = false
+}
+
+// Release gives up a reserved call whose outcome says nothing about the provider,
+// such as one cancelled by its caller
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	b := NewCircuitBreaker(3, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		b.Failure()
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Allow() after %d failures error = %v, want nil", i+1, err)
+		}
+	}
+
+	b.Failure()
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Allow() after 3 failures error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if b.Ready() {
+		t.Error("Ready() = true while the circuit is open")
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	b := NewCircuitBreaker(2, time.Hour)
+
+	b.Failure()
+	b.Success()
+	b.Failure()
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow() error = %v, want nil as the failures were not consecutive", err)
+	}
+}
+
+func TestCircuitBreakerProbe(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		outcome  func(b *CircuitBreaker)
+		wantOpen bool
+	}{
+		{name: "success closes", outcome: (*CircuitBreaker).Success},
+		{name: "failure reopens", outcome: (*CircuitBreaker).Failure, wantOpen: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewCircuitBreaker(1, cooldown)
+			b.Failure()
+			time.Sleep(cooldown)
+
+			if !b.Ready() {
+				t.Fatal("Ready() = false after the cooldown")
+			}
+			if err := b.Allow(); err != nil {
+				t.Fatalf("Allow() of the trial call error = %v", err)
+			}
+			// Only one trial call at a time
+			if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+				t.Errorf("Allow() during the trial call error = %v, want %v", err, ErrCircuitOpen)
+			}
+
+			tt.outcome(b)
+			if err := b.Allow(); errors.Is(err, ErrCircuitOpen) != tt.wantOpen {
+				t.Errorf("Allow() after the trial call error = %v, want open %v", err, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerRelease(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	b := NewCircuitBreaker(1, cooldown)
+	b.Failure()
+	time.Sleep(cooldown)
+
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() of the trial call error = %v", err)
+	}
+	// A cancelled trial frees the slot for another without restarting the cooldown
+	b.Release()
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow() after Release error = %v, want nil", err)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := NewCircuitBreaker(0, time.Hour)
+	for i := 0; i < 10; i++ {
+		b.Failure()
+	}
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow() of a disabled breaker error = %v, want nil", err)
+	}
+	if !b.Ready() {
+		t.Error("Ready() of a disabled breaker = false")
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Garment layer ranks, applied from the body outwards
+const (
+	layerBase = iota
+	layerMain
+	layerFootwear
+	layerOuter
+	layerAccessory
+)
+
+var categoryLayers = map[string]int{
+	"underwear":   layerBase,
+	"top":         layerMain,
+	"bottom":      layerMain,
+	"dress":       layerMain,
+	"shoes":       layerFootwear,
+	"outerwear":   layerOuter,
+	"accessories": layerAccessory,
+}
+
+var categoryPositions = map[string]string{
+	"underwear":   "full-body",
+	"top":         "upper-body",
+	"bottom":      "lower-body",
+	"dress":       "full-body",
+	"shoes":       "lower-body",
+	"outerwear":   "upper-body",
+	"accessories": "accessory",
+}
+
+// GarmentLayer is one wardrobe item in a layered try-on
+type GarmentLayer struct {
+	ItemID   uuid.UUID
+	Category string
+	Position string
+	Image    string
+}
+
+// GarmentPosition infers the try-on position for a wardrobe category
+func GarmentPosition(category string) string {
+	if position, ok := categoryPositions[category]; ok {
+		return position
+	}
+	return "full-body"
+}
+
+// OrderGarmentLayers sorts garments from the base layer through outerwear to accessories.
+// Garments in the same layer keep the order they were requested in.
+func OrderGarmentLayers(layers []GarmentLayer) {
+	sort.SliceStable(layers, func(i, j int) bool {
+		return layerRank(layers[i].Category) < layerRank(layers[j].Category)
+	})
+}
+
+// layerRank returns the layer of a category, placing unknown categories with the main garments
+func layerRank(category string) int {
+	if rank, ok := categoryLayers[category]; ok {
+		return rank
+	}
+	return layerMain
+}
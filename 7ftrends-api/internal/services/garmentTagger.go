@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/7ftrends/api/internal/config"
+)
+
+// Wardrobe categories a tagger may suggest, matching the wardrobe item validation
+var GarmentCategories = []string{"top", "bottom", "dress", "outerwear", "shoes", "accessories", "underwear"}
+
+// Seasons a tagger may suggest
+var GarmentSeasons = []string{"spring", "summer", "fall", "winter"}
+
+// GarmentAttributes are the attributes a vision model extracts from a garment photo
+type GarmentAttributes struct {
+	Tags       []string `json:"tags"`
+	Category   string   `json:"category"`
+	Colors     []string `json:"colors"` // most prominent first
+	Occasions  []string `json:"occasions"`
+	Seasons    []string `json:"seasons"`
+	Style      string   `json:"style"`
+	Materials  []string `json:"materials"`
+	Confidence float64  `json:"confidence"`
+}
+
+// GarmentTagger extracts wardrobe attributes from a garment photo
+type GarmentTagger interface {
+	// Tag returns the attributes of the garment in image
+	Tag(ctx context.Context, image InputImage) (*GarmentAttributes, error)
+	// Model returns the model identifier
+	Model() string
+}
+
+// NewGarmentTagger creates the tagger selected in the AI tagging configuration.
+// It returns nil without an error when tagging is disabled.
+func NewGarmentTagger(cfg config.AIConfig) (GarmentTagger, error) {
+	switch cfg.Tagging.Provider {
+	case "":
+		return nil, nil
+	case ProviderGemini:
+		return NewGeminiTagger(cfg)
+	case ProviderStub:
+		return NewStubTagger(), nil
+	default:
+		return nil, fmt.Errorf("unknown tagging provider %q", cfg.Tagging.Provider)
+	}
+}
+
+// garmentTaggingPrompt asks for the attributes as JSON matching GarmentAttributes
+var garmentTaggingPrompt = fmt.Sprintf(`You are cataloguing a piece of clothing for a digital wardrobe.
+Describe the single garment in the photo.
+- category: one of %s
+- colors: plain color names, most prominent first
+- occasions: short lowercase words such as casual, work, formal, party, sport
+- seasons: any of %s
+- style: one or two words, such as minimalist, streetwear or classic
+- materials: likely fabrics
+- tags: up to 8 short lowercase descriptive tags
+- confidence: 0 to 1, how sure you are overall
+Leave a field empty rather than guessing.`,
+	strings.Join(GarmentCategories, ", "), strings.Join(GarmentSeasons, ", "))
+
+// normalize lowercases and de-duplicates the attributes and drops values outside
+// the allowed categories and seasons, so model output can be stored as is
+func (a *GarmentAttributes) normalize() {
+	a.Category = strings.ToLower(strings.TrimSpace(a.Category))
+	if !containsString(GarmentCategories, a.Category) {
+		a.Category = ""
+	}
+	a.Style = strings.ToLower(strings.TrimSpace(a.Style))
+	a.Tags = normalizeList(a.Tags, nil, 8)
+	a.Colors = normalizeList(a.Colors, nil, 5)
+	a.Occasions = normalizeList(a.Occasions, nil, 6)
+	a.Seasons = normalizeList(a.Seasons, GarmentSeasons, 4)
+	a.Materials = normalizeList(a.Materials, nil, 4)
+	if math.IsNaN(a.Confidence) {
+		a.Confidence = 0
+	}
+	a.Confidence = math.Max(0, math.Min(1, a.Confidence))
+}
+
+// normalizeList lowercases, trims and de-duplicates values, keeping only allowed
+// ones when allowed is set, up to limit entries
+func normalizeList(values, allowed []string, limit int) []string {
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" || containsString(list, value) || (allowed != nil && !containsString(allowed, value)) {
+			continue
+		}
+		list = append(list, value)
+		if len(list) == limit {
+			break
+		}
+	}
+	return list
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/7ftrends/api/internal/config"
+)
+
+const (
+	defaultGeminiEndpoint = "https://generativelanguage.googleapis.com/v1beta"
+	defaultGeminiModel    = "gemini-2.5-flash-image"
+)
+
+// GeminiGenerator generates try-on images with the Gemini image editing API
+type GeminiGenerator struct {
+	client   *http.Client
+	endpoint string
+	model    string
+	apiKey   string
+}
+
+// NewGeminiGenerator creates a Gemini generator from the AI configuration
+func NewGeminiGenerator(cfg config.AIConfig) (*GeminiGenerator, error) {
+	if cfg.GeminiAPIKey == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	endpoint := strings.TrimRight(cfg.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = defaultGeminiEndpoint
+	}
+
+	model := cfg.Model
+	if model == "" {
+		model = defaultGeminiModel
+	}
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &GeminiGenerator{
+		client:   &http.Client{Timeout: timeout},
+		endpoint: endpoint,
+		model:    model,
+		apiKey:   cfg.GeminiAPIKey,
+	}, nil
+}
+
+// Model returns the configured Gemini model
+func (g *GeminiGenerator) Model() string {
+	return g.model
+}
+
+// Gemini API request structure
+type geminiEditRequest struct {
+	Contents         []geminiContent        `json:"contents"`
+	GenerationConfig geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiContent struct {
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text       string            `json:"text,omitempty"`
+	InlineData *geminiInlineData `json:"inline_data,omitempty"`
+}
+
+type geminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      float64      `json:"temperature"`
+	TopK             int          `json:"topK"`
+	TopP             float64      `json:"topP"`
+	MaxOutputTokens  int          `json:"maxOutputTokens"`
+	ResponseMimeType string       `json:"responseMimeType"`
+	ResponseSchema   geminiSchema `json:"responseSchema"`
+}
+
+type geminiSchema struct {
+	Type       string                  `json:"type"`
+	Properties map[string]geminiSchema `json:"properties,omitempty"`
+	Items      *geminiSchema           `json:"items,omitempty"`
+	Minimum    *float64                `json:"minimum,omitempty"`
+	Maximum    *float64                `json:"maximum,omitempty"`
+	Required   []string                `json:"required,omitempty"`
+}
+
+// Gemini API response structure
+type geminiEditResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+}
+
+// tryOnResponseSchema describes the JSON report the model returns alongside the image
+func tryOnResponseSchema() geminiSchema {
+	minConfidence, maxConfidence := 0.0, 1.0
+	return geminiSchema{
+		Type: "object",
+		Properties: map[string]geminiSchema{
+			"success":             {Type: "boolean"},
+			"confidence":          {Type: "number", Minimum: &minConfidence, Maximum: &maxConfidence},
+			"appliedInstructions": {Type: "array", Items: &geminiSchema{Type: "string"}},
+		},
+		Required: []string{"success", "confidence", "appliedInstructions"},
+	}
+}
+
+// Generate calls the Gemini API with the instructions and input images
+func (g *GeminiGenerator) Generate(ctx context.Context, req GenerateRequest) (*GenerateResult, error) {
+	parts := []geminiPart{{Text: req.Instructions}}
+	for _, img := range req.Images {
+		parts = append(parts, geminiPart{
+			InlineData: &geminiInlineData{
+				MimeType: img.MimeType,
+				Data:     base64.StdEncoding.EncodeToString(img.Data),
+			},
+		})
+	}
+
+	requestBody := geminiEditRequest{
+		Contents: []geminiContent{{Parts: parts}},
+		GenerationConfig: geminiGenerationConfig{
+			Temperature:      0.1,
+			TopK:             32,
+			TopP:             0.95,
+			MaxOutputTokens:  1024,
+			ResponseMimeType: "application/json",
+			ResponseSchema:   tryOnResponseSchema(),
+		},
+	}
+
+	requestJSON, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:edit", g.endpoint, g.model)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(requestJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", g.apiKey)
+
+	resp, err := g.client.Do(httpReq)
+	if err != nil {
+		return nil, &ProviderError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxProviderErrorBody))
+		return nil, newProviderStatusError(resp, body)
+	}
+
+	var geminiResponse geminiEditResponse
+	if err := json.NewDecoder(resp.Body).Decode(&geminiResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse Gemini response: %v", err)
+	}
+
+	return g.extractResult(&geminiResponse)
+}
+
+// extractResult pulls the first generated image and the text parts from a Gemini response
+func (g *GeminiGenerator) extractResult(response *geminiEditResponse) (*GenerateResult, error) {
+	result := &GenerateResult{Model: g.model}
+	var text []string
+
+	for _, candidate := range response.Candidates {
+		for _, part := range candidate.Content.Parts {
+			if part.Text != "" {
+				text = append(text, part.Text)
+			}
+			if result.Image == nil && part.InlineData != nil && part.InlineData.Data != "" &&
+				strings.HasPrefix(part.InlineData.MimeType, "image/") {
+				data, err := base64.StdEncoding.DecodeString(part.InlineData.Data)
+				if err != nil {
+					return nil, fmt.Errorf("failed to decode generated image: %v", err)
+				}
+				result.Image = data
+				result.MimeType = part.InlineData.MimeType
+			}
+		}
+	}
+
+	if result.Image == nil {
+		return nil, fmt.Errorf("failed to extract edited image from API response")
+	}
+
+	result.Text = strings.Join(text, "\n")
+	return result, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/7ftrends/api/internal/config"
+)
+
+const defaultGeminiTaggingModel = "gemini-2.5-flash"
+
+// GeminiTagger extracts garment attributes with a Gemini vision model
+type GeminiTagger struct {
+	client   *http.Client
+	endpoint string
+	model    string
+	apiKey   string
+}
+
+// NewGeminiTagger creates a Gemini tagger. It shares the API key, endpoint and
+// timeout with the image generator but uses its own model.
+func NewGeminiTagger(cfg config.AIConfig) (*GeminiTagger, error) {
+	if cfg.GeminiAPIKey == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	endpoint := strings.TrimRight(cfg.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = defaultGeminiEndpoint
+	}
+
+	model := cfg.Tagging.Model
+	if model == "" {
+		model = defaultGeminiTaggingModel
+	}
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &GeminiTagger{
+		client:   &http.Client{Timeout: timeout},
+		endpoint: endpoint,
+		model:    model,
+		apiKey:   cfg.GeminiAPIKey,
+	}, nil
+}
+
+// Model returns the configured Gemini model
+func (t *GeminiTagger) Model() string {
+	return t.model
+}
+
+// garmentAttributesSchema describes the JSON the model must return
+func garmentAttributesSchema() geminiSchema {
+	minConfidence, maxConfidence := 0.0, 1.0
+	list := &geminiSchema{Type: "string"}
+	return geminiSchema{
+		Type: "object",
+		Properties: map[string]geminiSchema{
+			"tags":       {Type: "array", Items: list},
+			"category":   {Type: "string"},
+			"colors":     {Type: "array", Items: list},
+			"occasions":  {Type: "array", Items: list},
+			"seasons":    {Type: "array", Items: list},
+			"style":      {Type: "string"},
+			"materials":  {Type: "array", Items: list},
+			"confidence": {Type: "number", Minimum: &minConfidence, Maximum: &maxConfidence},
+		},
+		Required: []string{"category", "colors", "confidence"},
+	}
+}
+
+// Tag asks the model for the garment's attributes
+func (t *GeminiTagger) Tag(ctx context.Context, image InputImage) (*GarmentAttributes, error) {
+	requestBody := geminiEditRequest{
+		Contents: []geminiContent{{Parts: []geminiPart{
+			{Text: garmentTaggingPrompt},
+			{InlineData: &geminiInlineData{
+				MimeType: image.MimeType,
+				Data:     base64.StdEncoding.EncodeToString(image.Data),
+			}},
+		}}},
+		GenerationConfig: geminiGenerationConfig{
+			Temperature:      0.1,
+			TopK:             32,
+			TopP:             0.95,
+			MaxOutputTokens:  1024,
+			ResponseMimeType: "application/json",
+			ResponseSchema:   garmentAttributesSchema(),
+		},
+	}
+
+	requestJSON, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:generateContent", t.endpoint, t.model)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(requestJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", t.apiKey)
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return nil, &ProviderError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxProviderErrorBody))
+		return nil, newProviderStatusError(resp, body)
+	}
+
+	var geminiResponse geminiEditResponse
+	if err := json.NewDecoder(resp.Body).Decode(&geminiResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse Gemini response: %v", err)
+	}
+
+	for _, candidate := range geminiResponse.Candidates {
+		for _, part := range candidate.Content.Parts {
+			if part.Text == "" {
+				continue
+			}
+			var attributes GarmentAttributes
+			if err := json.Unmarshal([]byte(part.Text), &attributes); err != nil {
+				return nil, fmt.Errorf("failed to parse garment attributes: %v", err)
+			}
+			attributes.normalize()
+			return &attributes, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no garment attributes in API response")
+}
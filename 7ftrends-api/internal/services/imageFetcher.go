@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/7ftrends/api/internal/config"
+)
+
+// Errors returned when an image source is rejected
+var (
+	ErrUnsupportedImageSource = errors.New("unsupported image source format")
+	ErrImageHostNotAllowed    = errors.New("image host is not allowed")
+	ErrImageAddressBlocked    = errors.New("image host resolves to a blocked address")
+	ErrImageTooLarge          = errors.New("image exceeds the maximum file size")
+	ErrImageTypeNotAllowed    = errors.New("image type is not allowed")
+)
+
+const maxImageRedirects = 3
+
+// carrierGradeNAT is the shared address space (RFC 6598) not covered by net.IP.IsPrivate
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// FetchedImage is an image loaded from a URL or data URL
+type FetchedImage struct {
+	Data     []byte
+	MimeType string
+}
+
+// ImageFetcher loads user-supplied images without exposing internal services.
+// Remote URLs must use an allowlisted host, every connection (including redirects)
+// is refused if it resolves to a private, loopback or link-local address, and the
+// body is capped and sniffed against the allowed image types.
+type ImageFetcher struct {
+	client       *http.Client
+	allowedHosts []string
+	allowedTypes []string
+	maxBytes     int64
+}
+
+// NewImageFetcher creates a fetcher from the storage configuration.
+// The Supabase project host is always allowed so storage bucket URLs work out of the box.
+func NewImageFetcher(storage config.StorageConfig, supabase config.SupabaseConfig) *ImageFetcher {
+	timeout := time.Duration(storage.FetchTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	var allowedHosts []string
+	for _, host := range storage.AllowedHosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			allowedHosts = append(allowedHosts, host)
+		}
+	}
+	if u, err := url.Parse(supabase.URL); err == nil && u.Hostname() != "" {
+		allowedHosts = append(allowedHosts, strings.ToLower(u.Hostname()))
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isBlockedIP(ip) {
+				return ErrImageAddressBlocked
+			}
+			return nil
+		},
+	}
+
+	f := &ImageFetcher{
+		allowedHosts: allowedHosts,
+		allowedTypes: storage.AllowedTypes,
+		maxBytes:     storage.MaxFileSize,
+	}
+
+	f.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxImageRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxImageRedirects)
+			}
+			return f.checkURL(req.URL)
+		},
+	}
+
+	return f
+}
+
+// ValidateSource checks an image source without fetching it,
+// so bad input can be rejected before any work is queued
+func (f *ImageFetcher) ValidateSource(source string) error {
+	if strings.HasPrefix(source, "data:") {
+		_, _, err := splitDataURL(source)
+		return err
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return ErrUnsupportedImageSource
+	}
+	return f.checkURL(u)
+}
+
+// Fetch loads an image from a data URL or an allowlisted remote URL
+func (f *ImageFetcher) Fetch(ctx context.Context, source string) (*FetchedImage, error) {
+	var data []byte
+	var err error
+
+	if strings.HasPrefix(source, "data:") {
+		data, err = f.decodeDataURL(source)
+	} else {
+		data, err = f.download(ctx, source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := http.DetectContentType(data)
+	if !f.typeAllowed(mimeType) {
+		return nil, fmt.Errorf("%w: %s", ErrImageTypeNotAllowed, mimeType)
+	}
+
+	return &FetchedImage{Data: data, MimeType: mimeType}, nil
+}
+
+// download retrieves a remote image through the restricted client
+func (f *ImageFetcher) download(ctx context.Context, source string) ([]byte, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, ErrUnsupportedImageSource
+	}
+	if err := f.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image request: %v", err)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		for _, sentinel := range []error{ErrImageHostNotAllowed, ErrImageAddressBlocked} {
+			if errors.Is(err, sentinel) {
+				return nil, sentinel
+			}
+		}
+		return nil, fmt.Errorf("failed to download image: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image download failed with status: %d", resp.StatusCode)
+	}
+	if f.maxBytes > 0 && resp.ContentLength > f.maxBytes {
+		return nil, ErrImageTooLarge
+	}
+
+	body := io.Reader(resp.Body)
+	if f.maxBytes > 0 {
+		body = io.LimitReader(resp.Body, f.maxBytes+1)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image data: %v", err)
+	}
+	if f.maxBytes > 0 && int64(len(data)) > f.maxBytes {
+		return nil, ErrImageTooLarge
+	}
+
+	return data, nil
+}
+
+// decodeDataURL decodes a base64 data URL, refusing payloads over the size limit before decoding
+func (f *ImageFetcher) decodeDataURL(source string) ([]byte, error) {
+	_, payload, err := splitDataURL(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.maxBytes > 0 && int64(base64.StdEncoding.DecodedLen(len(payload))) > f.maxBytes+2 {
+		return nil, ErrImageTooLarge
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 image: %v", err)
+	}
+	if f.maxBytes > 0 && int64(len(data)) > f.maxBytes {
+		return nil, ErrImageTooLarge
+	}
+
+	return data, nil
+}
+
+// checkURL enforces the scheme and host allowlist
+func (f *ImageFetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrUnsupportedImageSource
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return ErrUnsupportedImageSource
+	}
+
+	for _, allowed := range f.allowedHosts {
+		if host == allowed {
+			return nil
+		}
+		// "*.example.com" allows any subdomain of example.com
+		if strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:]) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrImageHostNotAllowed, host)
+}
+
+// typeAllowed reports whether a sniffed content type is in the allowed list
+func (f *ImageFetcher) typeAllowed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range f.allowedTypes {
+		if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitDataURL returns the declared media type and base64 payload of a data URL
+func splitDataURL(source string) (string, string, error) {
+	header, payload, found := strings.Cut(strings.TrimPrefix(source, "data:"), ",")
+	if !found || !strings.HasSuffix(header, ";base64") || payload == "" {
+		return "", "", fmt.Errorf("%w: invalid base64 data URL format", ErrUnsupportedImageSource)
+	}
+	return strings.TrimSuffix(header, ";base64"), payload, nil
+}
+
+// isBlockedIP reports whether an address must never be fetched from
+func isBlockedIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		carrierGradeNAT.Contains(ip)
+}
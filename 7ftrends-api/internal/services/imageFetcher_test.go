@@ -0,0 +1,239 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/png"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/7ftrends/api/internal/config"
+)
+
+func newTestFetcher(maxBytes int64, hosts ...string) *ImageFetcher {
+	return NewImageFetcher(config.StorageConfig{
+		MaxFileSize:  maxBytes,
+		AllowedTypes: []string{"image/jpeg", "image/png"},
+		AllowedHosts: hosts,
+		FetchTimeout: 2,
+	}, config.SupabaseConfig{URL: "https://myproject.supabase.co"})
+}
+
+func TestImageFetcherValidateSource(t *testing.T) {
+	f := newTestFetcher(1<<20, "cdn.example.com", " *.images.example.org ", "")
+
+	tests := []struct {
+		name    string
+		source  string
+		wantErr error
+	}{
+		{name: "allowlisted host", source: "https://cdn.example.com/a.jpg"},
+		{name: "host case is ignored", source: "https://CDN.Example.com/a.jpg"},
+		{name: "plain http", source: "http://cdn.example.com/a.jpg"},
+		{name: "configured supabase project", source: "https://myproject.supabase.co/storage/v1/object/public/a.jpg"},
+		{name: "wildcard subdomain", source: "https://eu.images.example.org/a.jpg"},
+		{name: "other supabase project", source: "https://attacker.supabase.co/a.jpg", wantErr: ErrImageHostNotAllowed},
+		{name: "wildcard does not match its apex", source: "https://images.example.org/a.jpg", wantErr: ErrImageHostNotAllowed},
+		{name: "wildcard does not match a look-alike", source: "https://evilimages.example.org/a.jpg", wantErr: ErrImageHostNotAllowed},
+		{name: "allowlisted name as a prefix", source: "https://cdn.example.com.attacker.net/a.jpg", wantErr: ErrImageHostNotAllowed},
+		{name: "userinfo does not change the host", source: "https://cdn.example.com@169.254.169.254/", wantErr: ErrImageHostNotAllowed},
+		{name: "metadata address", source: "http://169.254.169.254/latest/meta-data/", wantErr: ErrImageHostNotAllowed},
+		{name: "file scheme", source: "file:///etc/passwd", wantErr: ErrUnsupportedImageSource},
+		{name: "gopher scheme", source: "gopher://cdn.example.com/", wantErr: ErrUnsupportedImageSource},
+		{name: "no host", source: "https:///a.jpg", wantErr: ErrUnsupportedImageSource},
+		{name: "relative path", source: "/uploads/a.jpg", wantErr: ErrUnsupportedImageSource},
+		{name: "data URL", source: "data:image/png;base64,iVBORw0KGgo="},
+		{name: "data URL without base64", source: "data:image/png,abc", wantErr: ErrUnsupportedImageSource},
+		{name: "empty data URL", source: "data:image/png;base64,", wantErr: ErrUnsupportedImageSource},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.ValidateSource(tt.source)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("ValidateSource(%q) error = %v, want nil", tt.source, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateSource(%q) error = %v, want %v", tt.source, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImageFetcherBlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request reached the loopback server: %s", r.URL)
+	}))
+	defer server.Close()
+
+	// Allowlisting the host is not enough when it resolves to a blocked address
+	u, _ := url.Parse(server.URL)
+	f := newTestFetcher(1<<20, u.Hostname())
+
+	if _, err := f.Fetch(context.Background(), server.URL+"/a.png"); !errors.Is(err, ErrImageAddressBlocked) {
+		t.Errorf("Fetch() error = %v, want %v", err, ErrImageAddressBlocked)
+	}
+}
+
+func TestImageFetcherRedirects(t *testing.T) {
+	f := newTestFetcher(1<<20, "cdn.example.com")
+
+	redirect := func(target string, hops int) error {
+		req, _ := http.NewRequest(http.MethodGet, target, nil)
+		return f.client.CheckRedirect(req, make([]*http.Request, hops))
+	}
+
+	if err := redirect("https://cdn.example.com/b.jpg", 1); err != nil {
+		t.Errorf("redirect to an allowlisted host error = %v, want nil", err)
+	}
+	if err := redirect("http://169.254.169.254/latest/meta-data/", 1); !errors.Is(err, ErrImageHostNotAllowed) {
+		t.Errorf("redirect to another host error = %v, want %v", err, ErrImageHostNotAllowed)
+	}
+	if err := redirect("https://cdn.example.com/b.jpg", maxImageRedirects); err == nil {
+		t.Error("redirect past the limit was followed")
+	}
+}
+
+func TestImageFetcherDataURL(t *testing.T) {
+	pngData := testPNG(t, 4, 4)
+	dataURL := func(data []byte) string {
+		return "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		maxBytes int64
+		wantErr  error
+	}{
+		{name: "png", source: dataURL(pngData), maxBytes: 1 << 20},
+		{name: "exactly the limit", source: dataURL(pngData), maxBytes: int64(len(pngData))},
+		{name: "over the limit", source: dataURL(pngData), maxBytes: int64(len(pngData)) - 1, wantErr: ErrImageTooLarge},
+		{name: "far over the limit is refused before decoding", source: dataURL(bytes.Repeat([]byte{0}, 4096)), maxBytes: 64, wantErr: ErrImageTooLarge},
+		{name: "declared type is not trusted", source: "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("<html></html>")), maxBytes: 1 << 20, wantErr: ErrImageTypeNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched, err := newTestFetcher(tt.maxBytes).Fetch(context.Background(), tt.source)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Fetch() error = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("Fetch() error = %v", err)
+			case fetched.MimeType != "image/png" || !bytes.Equal(fetched.Data, pngData):
+				t.Errorf("Fetch() = %s with %d bytes, want image/png with %d bytes", fetched.MimeType, len(fetched.Data), len(pngData))
+			}
+		})
+	}
+}
+
+func TestImageFetcherDownloadLimits(t *testing.T) {
+	pngData := testPNG(t, 4, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Write(pngData)
+		case "/chunked.png":
+			// No Content-Length, so only the read limit applies
+			w.(http.Flusher).Flush()
+			w.Write(pngData)
+		case "/page.html":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("<html><body>not an image</body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	tests := []struct {
+		name     string
+		path     string
+		maxBytes int64
+		wantErr  error
+	}{
+		{name: "within the limit", path: "/image.png", maxBytes: 1 << 20},
+		{name: "content length over the limit", path: "/image.png", maxBytes: int64(len(pngData)) - 1, wantErr: ErrImageTooLarge},
+		{name: "body over the limit", path: "/chunked.png", maxBytes: int64(len(pngData)) - 1, wantErr: ErrImageTooLarge},
+		{name: "content type header is not trusted", path: "/page.html", maxBytes: 1 << 20, wantErr: ErrImageTypeNotAllowed},
+	}
+
+	// The test server is on loopback, which the real dialer refuses
+	loopbackFetcher := func(maxBytes int64) *ImageFetcher {
+		f := newTestFetcher(maxBytes, u.Hostname())
+		f.client.Transport = &http.Transport{DialContext: (&net.Dialer{}).DialContext}
+		return f
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loopbackFetcher(tt.maxBytes).Fetch(context.Background(), server.URL+tt.path)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Fetch() error = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Errorf("Fetch() error = %v", err)
+			}
+		})
+	}
+
+	if _, err := loopbackFetcher(1<<20).Fetch(context.Background(), server.URL+"/missing.png"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Fetch() of a missing image error = %v, want a 404 failure", err)
+	}
+}
+
+func TestIsBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "127.0.0.1", want: true},
+		{ip: "::1", want: true},
+		{ip: "10.1.2.3", want: true},
+		{ip: "172.16.0.1", want: true},
+		{ip: "192.168.1.1", want: true},
+		{ip: "169.254.169.254", want: true},
+		{ip: "100.64.0.1", want: true},
+		{ip: "fd00::1", want: true},
+		{ip: "fe80::1", want: true},
+		{ip: "0.0.0.0", want: true},
+		{ip: "::", want: true},
+		{ip: "224.0.0.1", want: true},
+		{ip: "::ffff:127.0.0.1", want: true},
+		{ip: "8.8.8.8", want: false},
+		{ip: "100.128.0.1", want: false},
+		{ip: "2001:4860:4860::8888", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isBlockedIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isBlockedIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+// testPNG encodes a blank PNG of the given size
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
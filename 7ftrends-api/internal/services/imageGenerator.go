@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/7ftrends/api/internal/config"
+)
+
+// Image generation providers selectable through ai.provider
+const (
+	ProviderGemini = "gemini"
+	ProviderStub   = "stub"
+)
+
+// ErrMissingAPIKey is returned when a hosted provider is selected without credentials
+var ErrMissingAPIKey = errors.New("image generation API key is not configured")
+
+// InputImage is an image passed to an image generator
+type InputImage struct {
+	MimeType string
+	Data     []byte
+}
+
+// GenerateRequest describes a single image generation call.
+// Images are ordered with the person photo first, followed by the garments.
+type GenerateRequest struct {
+	Instructions string
+	Images       []InputImage
+}
+
+// GenerateResult holds the generated image and any text the model returned
+type GenerateResult struct {
+	Image    []byte
+	MimeType string
+	Text     string
+	Model    string
+}
+
+// ImageGenerator produces a virtual try-on image from a person photo and garments
+type ImageGenerator interface {
+	// Generate runs one generation and returns the resulting image
+	Generate(ctx context.Context, req GenerateRequest) (*GenerateResult, error)
+	// Model returns the model identifier recorded with each result
+	Model() string
+}
+
+// NewImageGenerator creates the image generator selected in the AI configuration.
+// Hosted providers are wrapped with retries and a circuit breaker.
+func NewImageGenerator(cfg config.AIConfig) (ImageGenerator, error) {
+	switch cfg.Provider {
+	case "", ProviderGemini:
+		gemini, err := NewGeminiGenerator(cfg)
+		if err != nil {
+			return nil, err
+		}
+		breaker := NewCircuitBreaker(cfg.BreakerThreshold, time.Duration(cfg.BreakerCooldown)*time.Second)
+		return NewResilientGenerator(gemini, NewRetryPolicy(cfg), breaker), nil
+	case ProviderStub:
+		return NewStubGenerator(), nil
+	default:
+		return nil, fmt.Errorf("unknown image generation provider %q", cfg.Provider)
+	}
+}
+
+// GeneratorAvailable reports whether generator is currently accepting calls.
+// Generators without a circuit breaker are always available.
+func GeneratorAvailable(generator ImageGenerator) bool {
+	if resilient, ok := generator.(*ResilientGenerator); ok {
+		return resilient.Available()
+	}
+	return true
+}
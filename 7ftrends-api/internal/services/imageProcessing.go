@@ -0,0 +1,272 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ErrUndecodableImage is returned when image bytes are not a supported format
+var ErrUndecodableImage = errors.New("image could not be decoded")
+
+// ProcessedImage is an image normalized for storage or generation
+type ProcessedImage struct {
+	Data     []byte
+	MimeType string
+	Width    int
+	Height   int
+}
+
+// DecodedImage is a decoded image with EXIF orientation applied
+type DecodedImage struct {
+	Image  image.Image
+	Format string
+}
+
+// DecodeImage decodes a JPEG, PNG or WebP image and applies its EXIF orientation
+func DecodeImage(data []byte) (*DecodedImage, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUndecodableImage, err)
+	}
+
+	if format == "jpeg" {
+		if orientation := jpegOrientation(data); orientation > 1 {
+			img = applyOrientation(img, orientation)
+		}
+	}
+
+	return &DecodedImage{Image: img, Format: format}, nil
+}
+
+// NormalizeImage decodes an image, applies EXIF orientation, downscales it so neither
+// side exceeds maxDimension and re-encodes it. Images with transparency are written
+// as PNG, everything else as JPEG at the given quality. Metadata is not carried over.
+func NormalizeImage(data []byte, maxDimension, quality int) (*ProcessedImage, error) {
+	decoded, err := DecodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	img := toNRGBA(decoded.Image)
+	if maxDimension > 0 {
+		img = downscale(img, maxDimension)
+	}
+
+	return encodeImage(img, quality)
+}
+
+// encodeImage writes images with transparency as PNG and everything else as JPEG
+// at the given quality
+func encodeImage(img *image.NRGBA, quality int) (*ProcessedImage, error) {
+	var out bytes.Buffer
+	var err error
+	mimeType := "image/jpeg"
+	if hasTransparency(img) {
+		mimeType = "image/png"
+		err = png.Encode(&out, img)
+	} else {
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: clampQuality(quality)})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %v", err)
+	}
+
+	bounds := img.Bounds()
+	return &ProcessedImage{
+		Data:     out.Bytes(),
+		MimeType: mimeType,
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+	}, nil
+}
+
+// StoredImage describes an image previously written by NormalizeImage, without
+// decoding pixels or re-encoding it
+func StoredImage(data []byte) (*ProcessedImage, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUndecodableImage, err)
+	}
+	return &ProcessedImage{
+		Data:     data,
+		MimeType: "image/" + format,
+		Width:    config.Width,
+		Height:   config.Height,
+	}, nil
+}
+
+// ImageExtension returns the file extension for a normalized image MIME type
+func ImageExtension(mimeType string) string {
+	if mimeType == "image/png" {
+		return ".png"
+	}
+	return ".jpg"
+}
+
+// toNRGBA copies an image into an NRGBA buffer anchored at the origin
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Bounds().Min == (image.Point{}) {
+		return nrgba
+	}
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+// downscale shrinks an image proportionally so its longest side is at most maxDimension
+func downscale(img *image.NRGBA, maxDimension int) *image.NRGBA {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width <= maxDimension && height <= maxDimension {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*maxDimension/width)
+		width = maxDimension
+	} else {
+		width = max(1, width*maxDimension/height)
+		height = maxDimension
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// hasTransparency reports whether any pixel is not fully opaque
+func hasTransparency(img *image.NRGBA) bool {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0xff {
+			return true
+		}
+	}
+	return false
+}
+
+func clampQuality(quality int) int {
+	if quality < 1 || quality > 100 {
+		return jpeg.DefaultQuality
+	}
+	return quality
+}
+
+// applyOrientation rotates and flips an image according to an EXIF orientation value (2-8)
+func applyOrientation(img image.Image, orientation int) image.Image {
+	src := toNRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirror horizontal
+				sx, sy = w-1-x, y
+			case 3: // rotate 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirror vertical
+				sx, sy = x, h-1-y
+			case 5: // transpose
+				sx, sy = y, x
+			case 6: // rotate 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // transverse
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotate 90 counter-clockwise
+				sx, sy = w-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			si := src.PixOffset(sx, sy)
+			di := dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+
+	return dst
+}
+
+// jpegOrientation returns the EXIF orientation tag of a JPEG, or 1 when absent
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+
+	// Walk the marker segments up to the start of scan looking for an Exif APP1 segment
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xda || marker == 0xd9 {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return 1
+		}
+		segment := data[i+4 : end]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i = end
+	}
+
+	return 1
+}
+
+// exifOrientation reads the orientation tag (0x0112) from IFD0 of a TIFF structure
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset : offset+2]))
+
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) != 0x0112 {
+			continue
+		}
+		// Orientation is a SHORT stored inline in the value field
+		value := int(order.Uint16(tiff[entry+8 : entry+10]))
+		if value < 1 || value > 8 {
+			return 1
+		}
+		return value
+	}
+
+	return 1
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestJPEGOrientation(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{name: "little endian rotate 90", data: exifJPEG(binary.LittleEndian, 6), want: 6},
+		{name: "big endian rotate 180", data: exifJPEG(binary.BigEndian, 3), want: 3},
+		{name: "big endian transverse", data: exifJPEG(binary.BigEndian, 7), want: 7},
+		{name: "normal", data: exifJPEG(binary.LittleEndian, 1), want: 1},
+		{name: "out of range value", data: exifJPEG(binary.LittleEndian, 9), want: 1},
+		{name: "no exif", data: []byte{0xff, 0xd8, 0xff, 0xd9}, want: 1},
+		{name: "not a jpeg", data: []byte("\x89PNG\r\n\x1a\n"), want: 1},
+		{name: "truncated segment", data: exifJPEG(binary.LittleEndian, 6)[:20], want: 1},
+		{name: "empty", data: nil, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// A 2x3 source where each pixel records its own coordinates
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 2; x++ {
+			src.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+
+	tests := []struct {
+		orientation   int
+		width, height int
+		topLeft       image.Point // source pixel expected at the top-left
+		topRight      image.Point // source pixel expected at the top-right
+	}{
+		{orientation: 1, width: 2, height: 3, topLeft: image.Pt(0, 0), topRight: image.Pt(1, 0)},
+		{orientation: 2, width: 2, height: 3, topLeft: image.Pt(1, 0), topRight: image.Pt(0, 0)},
+		{orientation: 3, width: 2, height: 3, topLeft: image.Pt(1, 2), topRight: image.Pt(0, 2)},
+		{orientation: 4, width: 2, height: 3, topLeft: image.Pt(0, 2), topRight: image.Pt(1, 2)},
+		{orientation: 5, width: 3, height: 2, topLeft: image.Pt(0, 0), topRight: image.Pt(0, 2)},
+		{orientation: 6, width: 3, height: 2, topLeft: image.Pt(0, 2), topRight: image.Pt(0, 0)},
+		{orientation: 7, width: 3, height: 2, topLeft: image.Pt(1, 2), topRight: image.Pt(1, 0)},
+		{orientation: 8, width: 3, height: 2, topLeft: image.Pt(1, 0), topRight: image.Pt(1, 2)},
+	}
+
+	for _, tt := range tests {
+		got := applyOrientation(src, tt.orientation)
+		bounds := got.Bounds()
+		if bounds.Dx() != tt.width || bounds.Dy() != tt.height {
+			t.Errorf("orientation %d: size = %dx%d, want %dx%d", tt.orientation, bounds.Dx(), bounds.Dy(), tt.width, tt.height)
+			continue
+		}
+		if p := sourcePixel(got, 0, 0); p != tt.topLeft {
+			t.Errorf("orientation %d: top-left = %v, want %v", tt.orientation, p, tt.topLeft)
+		}
+		if p := sourcePixel(got, tt.width-1, 0); p != tt.topRight {
+			t.Errorf("orientation %d: top-right = %v, want %v", tt.orientation, p, tt.topRight)
+		}
+	}
+}
+
+// sourcePixel reads back the source coordinates recorded in a pixel
+func sourcePixel(img image.Image, x, y int) image.Point {
+	c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+	return image.Pt(int(c.R), int(c.G))
+}
+
+// exifJPEG builds a minimal JPEG header with an Exif segment holding one
+// orientation entry in IFD0
+func exifJPEG(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	entry := tiff[10:]
+	order.PutUint16(entry[0:], 0x0112)
+	order.PutUint16(entry[2:], 3) // SHORT
+	order.PutUint32(entry[4:], 1)
+	order.PutUint16(entry[8:], orientation)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	data := []byte{0xff, 0xd8, 0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(data[4:], uint16(len(segment)+2))
+	data = append(data, segment...)
+	return append(data, 0xff, 0xd9)
+}
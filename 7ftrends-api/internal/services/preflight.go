@@ -0,0 +1,115 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// Preflight checks, reported in PreflightIssue.Check
+const (
+	PreflightDecodable   = "decodable"
+	PreflightFileSize    = "fileSize"
+	PreflightResolution  = "resolution"
+	PreflightAspectRatio = "aspectRatio"
+)
+
+// PreflightRules are the limits an input image must meet before it is sent to the generator
+type PreflightRules struct {
+	MinImageResolution int     // minimum shortest side in pixels, as in competition requirements
+	MaxAspectRatio     float64 // maximum longest over shortest side
+	MaxFileSize        int64   // bytes
+}
+
+// PreflightIssue is one failed preflight check
+type PreflightIssue struct {
+	Check   string      `json:"check"`
+	Message string      `json:"-"`
+	Actual  interface{} `json:"actual,omitempty"`
+	Limit   interface{} `json:"limit,omitempty"`
+}
+
+// PreflightError lists every preflight check an input image failed. Field names the
+// request field the image came from and is set by the caller.
+type PreflightError struct {
+	Field  string
+	Issues []PreflightIssue
+}
+
+// Error implements the error interface
+func (e *PreflightError) Error() string {
+	messages := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		messages[i] = issue.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// UndecodableImageError is the preflight failure for image bytes that cannot be decoded
+func UndecodableImageError() *PreflightError {
+	return &PreflightError{Issues: []PreflightIssue{{
+		Check:   PreflightDecodable,
+		Message: "Image could not be read, use a JPEG, PNG or WebP photo",
+	}}}
+}
+
+// FileTooLargeError is the preflight failure for an image over maxFileSize bytes
+func FileTooLargeError(maxFileSize int64) *PreflightError {
+	return &PreflightError{Issues: []PreflightIssue{fileSizeIssue(0, maxFileSize)}}
+}
+
+// PreflightImage checks image bytes against the rules from the header alone, without
+// decoding pixels. It returns a *PreflightError listing every failed check, or nil.
+func PreflightImage(data []byte, rules PreflightRules) error {
+	var issues []PreflightIssue
+
+	if rules.MaxFileSize > 0 && int64(len(data)) > rules.MaxFileSize {
+		issues = append(issues, fileSizeIssue(int64(len(data)), rules.MaxFileSize))
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return &PreflightError{Issues: append(issues, UndecodableImageError().Issues...)}
+	}
+
+	shortest, longest := min(config.Width, config.Height), max(config.Width, config.Height)
+	if rules.MinImageResolution > 0 && shortest < rules.MinImageResolution {
+		issues = append(issues, PreflightIssue{
+			Check:   PreflightResolution,
+			Message: fmt.Sprintf("Image is %dx%d, its shortest side must be at least %d pixels", config.Width, config.Height, rules.MinImageResolution),
+			Actual:  fmt.Sprintf("%dx%d", config.Width, config.Height),
+			Limit:   rules.MinImageResolution,
+		})
+	}
+
+	ratio := float64(longest) / float64(shortest)
+	if rules.MaxAspectRatio > 0 && ratio > rules.MaxAspectRatio {
+		issues = append(issues, PreflightIssue{
+			Check:   PreflightAspectRatio,
+			Message: fmt.Sprintf("Image is cropped too narrowly (%.1f:1), use a photo no more than %.1f:1", ratio, rules.MaxAspectRatio),
+			Actual:  math.Round(ratio*100) / 100,
+			Limit:   rules.MaxAspectRatio,
+		})
+	}
+
+	if len(issues) > 0 {
+		return &PreflightError{Issues: issues}
+	}
+	return nil
+}
+
+// fileSizeIssue reports an image over the size limit. size is 0 when the download
+// was cut off at the limit.
+func fileSizeIssue(size, maxFileSize int64) PreflightIssue {
+	issue := PreflightIssue{
+		Check:   PreflightFileSize,
+		Message: fmt.Sprintf("Image is larger than %.1f MB", float64(maxFileSize)/(1<<20)),
+		Limit:   maxFileSize,
+	}
+	if size > 0 {
+		issue.Actual = size
+	}
+	return issue
+}
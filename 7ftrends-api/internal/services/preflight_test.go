@@ -0,0 +1,70 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPreflightImage(t *testing.T) {
+	rules := PreflightRules{
+		MinImageResolution: 100,
+		MaxAspectRatio:     2.5,
+		MaxFileSize:        1 << 20,
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		rules      PreflightRules
+		wantChecks []string
+	}{
+		{name: "valid", data: testPNG(t, 200, 300), rules: rules},
+		{name: "at the limits", data: testPNG(t, 100, 250), rules: rules},
+		{name: "too small", data: testPNG(t, 80, 120), rules: rules, wantChecks: []string{PreflightResolution}},
+		{name: "too narrow", data: testPNG(t, 100, 400), rules: rules, wantChecks: []string{PreflightAspectRatio}},
+		{name: "too small and too narrow", data: testPNG(t, 40, 200), rules: rules, wantChecks: []string{PreflightResolution, PreflightAspectRatio}},
+		{
+			name:       "too large",
+			data:       testPNG(t, 200, 300),
+			rules:      PreflightRules{MinImageResolution: 100, MaxAspectRatio: 2.5, MaxFileSize: 64},
+			wantChecks: []string{PreflightFileSize},
+		},
+		{name: "undecodable", data: []byte("not an image"), rules: rules, wantChecks: []string{PreflightDecodable}},
+		{
+			name:       "undecodable and too large",
+			data:       bytes.Repeat([]byte{0}, 128),
+			rules:      PreflightRules{MaxFileSize: 64},
+			wantChecks: []string{PreflightFileSize, PreflightDecodable},
+		},
+		{name: "no rules", data: testPNG(t, 10, 100), rules: PreflightRules{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := PreflightImage(tt.data, tt.rules)
+			if tt.wantChecks == nil {
+				if err != nil {
+					t.Fatalf("PreflightImage() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var preflight *PreflightError
+			if !errors.As(err, &preflight) {
+				t.Fatalf("PreflightImage() error = %v, want a *PreflightError", err)
+			}
+			var checks []string
+			for _, issue := range preflight.Issues {
+				checks = append(checks, issue.Check)
+				if issue.Message == "" {
+					t.Errorf("issue %s has no message", issue.Check)
+				}
+			}
+			if !reflect.DeepEqual(checks, tt.wantChecks) {
+				t.Errorf("PreflightImage() checks = %v, want %v", checks, tt.wantChecks)
+			}
+		})
+	}
+}
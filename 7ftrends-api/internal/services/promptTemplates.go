@@ -0,0 +1,115 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/7ftrends/api/internal/config"
+)
+
+// ErrNoPromptVariants is returned when no prompt variant has a positive weight
+var ErrNoPromptVariants = errors.New("no try-on prompt variants are configured")
+
+// PromptLayer is one garment in a layered try-on prompt
+type PromptLayer struct {
+	Number   int
+	Category string
+	Position string
+}
+
+// PromptSettings are the optional scene settings of a try-on prompt
+type PromptSettings struct {
+	Background          string
+	BackgroundColor     string
+	Lighting            string
+	LightingDescription string
+	Filters             []string
+}
+
+// PromptData holds the variables available to try-on prompt templates
+type PromptData struct {
+	Position     string
+	Fit          string
+	Style        string
+	Layers       []PromptLayer
+	Settings     *PromptSettings
+	Instructions string
+}
+
+// PromptSet holds the loaded try-on prompt templates and their weights
+type PromptSet struct {
+	variants    []promptVariant
+	totalWeight int
+}
+
+type promptVariant struct {
+	version  string
+	weight   int
+	template *template.Template
+}
+
+// LoadPromptSet parses the template file of every configured prompt variant
+func LoadPromptSet(cfg config.PromptConfig) (*PromptSet, error) {
+	set := &PromptSet{}
+	for _, variant := range cfg.Variants {
+		if variant.Weight <= 0 {
+			continue
+		}
+
+		path := filepath.Join(cfg.Dir, fmt.Sprintf("tryon-%s.tmpl", variant.Version))
+		tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").ParseFiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prompt %s: %w", variant.Version, err)
+		}
+
+		set.variants = append(set.variants, promptVariant{
+			version:  variant.Version,
+			weight:   variant.Weight,
+			template: tmpl,
+		})
+		set.totalWeight += variant.Weight
+	}
+
+	if set.totalWeight == 0 {
+		return nil, ErrNoPromptVariants
+	}
+	return set, nil
+}
+
+// Choose picks a prompt version in proportion to the variant weights. The pick
+// is a hash of key, so repeating a request gets the same version and can be
+// served from the result cache.
+func (s *PromptSet) Choose(key string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	n := int(hash.Sum32() % uint32(s.totalWeight))
+	for _, variant := range s.variants {
+		if n < variant.weight {
+			return variant.version
+		}
+		n -= variant.weight
+	}
+	return s.variants[len(s.variants)-1].version
+}
+
+// Render executes the template for version. Runs of whitespace in the output
+// are collapsed to single spaces so templates can be laid out for readability.
+func (s *PromptSet) Render(version string, data PromptData) (string, error) {
+	for _, variant := range s.variants {
+		if variant.version != version {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := variant.template.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to render prompt %s: %w", version, err)
+		}
+		return strings.Join(strings.Fields(buf.String()), " "), nil
+	}
+	return "", fmt.Errorf("unknown prompt version %q", version)
+}
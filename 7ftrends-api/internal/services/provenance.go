@@ -0,0 +1,283 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProvenanceGenerator names the pipeline in embedded metadata and manifests
+const ProvenanceGenerator = "7ftrends-virtual-tryon"
+
+// provenanceManifestVersion is bumped when the signed manifest layout changes
+const provenanceManifestVersion = 1
+
+var (
+	// ErrMissingProvenanceSecret is returned when manifests are signed without a key
+	ErrMissingProvenanceSecret = errors.New("provenance signing secret is not configured")
+	// ErrInvalidManifest is returned for malformed or tampered manifests
+	ErrInvalidManifest = errors.New("invalid provenance manifest")
+	// ErrManifestImageMismatch is returned when an image is not the one a manifest describes
+	ErrManifestImageMismatch = errors.New("image does not match provenance manifest")
+)
+
+// Provenance identifies a composite as synthetic output of the try-on pipeline
+type Provenance struct {
+	Generator   string    `json:"generator"`
+	Model       string    `json:"model"`
+	HistoryID   uuid.UUID `json:"historyId"`
+	CreatedAt   time.Time `json:"createdAt"`
+	AIGenerated bool      `json:"aiGenerated"`
+}
+
+// NewProvenance describes a composite generated now for a history row
+func NewProvenance(model string, historyID uuid.UUID) Provenance {
+	return Provenance{
+		Generator:   ProvenanceGenerator,
+		Model:       model,
+		HistoryID:   historyID,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+		AIGenerated: true,
+	}
+}
+
+// ProvenanceManifest is the signed sidecar stored next to a composite. The
+// signature covers every other field, including the image's SHA-256.
+type ProvenanceManifest struct {
+	Version int `json:"version"`
+	Provenance
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature"`
+}
+
+// ProvenanceSigner signs and verifies manifests with HMAC-SHA256
+type ProvenanceSigner struct {
+	secret []byte
+}
+
+// NewProvenanceSigner creates a signer from the configured provenance secret
+func NewProvenanceSigner(secret string) (*ProvenanceSigner, error) {
+	if secret == "" {
+		return nil, ErrMissingProvenanceSecret
+	}
+	return &ProvenanceSigner{secret: []byte(secret)}, nil
+}
+
+// Sign returns a manifest binding the provenance to the exact image bytes
+func (s *ProvenanceSigner) Sign(provenance Provenance, image []byte) (ProvenanceManifest, error) {
+	manifest := ProvenanceManifest{
+		Version:    provenanceManifestVersion,
+		Provenance: provenance,
+		SHA256:     ImageSHA256(image),
+	}
+	signature, err := s.mac(manifest)
+	if err != nil {
+		return ProvenanceManifest{}, err
+	}
+	manifest.Signature = base64.RawURLEncoding.EncodeToString(signature)
+	return manifest, nil
+}
+
+// Verify checks the manifest's signature and that it describes the image
+func (s *ProvenanceSigner) Verify(manifest ProvenanceManifest, image []byte) error {
+	signature, err := base64.RawURLEncoding.DecodeString(manifest.Signature)
+	if err != nil {
+		return ErrInvalidManifest
+	}
+	expected, err := s.mac(manifest)
+	if err != nil || !hmac.Equal(signature, expected) {
+		return ErrInvalidManifest
+	}
+	if manifest.SHA256 != ImageSHA256(image) {
+		return ErrManifestImageMismatch
+	}
+	return nil
+}
+
+// mac signs the manifest's JSON encoding without its signature
+func (s *ProvenanceSigner) mac(manifest ProvenanceManifest) ([]byte, error) {
+	manifest.Signature = ""
+	payload, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+// ImageSHA256 returns the hex SHA-256 of an image file's bytes
+func ImageSHA256(image []byte) string {
+	sum := sha256.Sum256(image)
+	return hex.EncodeToString(sum[:])
+}
+
+// XMP namespaces. The IPTC digital source type is the standard marker for
+// media created by a trained model.
+const (
+	xmpNamespace              = "http://ns.adobe.com/xap/1.0/"
+	xmpProvenanceNamespace    = "http://ns.7ftrends.com/provenance/1.0/"
+	iptcTrainedAlgorithmMedia = "http://cv.iptc.org/newscodes/digitalsourcetype/trainedAlgorithmicMedia"
+	jpegXMPHeader             = xmpNamespace + "\x00"
+	pngXMPKeyword             = "XML:com.adobe.xmp"
+)
+
+// EmbedProvenance writes the provenance into the image as an XMP packet, in an
+// APP1 segment for JPEG or an iTXt chunk for PNG. Other formats are returned as is.
+func EmbedProvenance(data []byte, mimeType string, provenance Provenance) ([]byte, error) {
+	packet := provenanceXMP(provenance)
+	switch mimeType {
+	case "image/jpeg":
+		return embedJPEGXMP(data, packet)
+	case "image/png":
+		return embedPNGXMP(data, packet)
+	default:
+		return data, nil
+	}
+}
+
+// provenanceXMP builds the XMP packet for a composite
+func provenanceXMP(provenance Provenance) []byte {
+	attr := func(name, value string) string {
+		var escaped strings.Builder
+		xml.EscapeText(&escaped, []byte(value))
+		return fmt.Sprintf("\n    %s=\"%s\"", name, escaped.String())
+	}
+
+	var b strings.Builder
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("  <rdf:Description rdf:about=\"\"")
+	b.WriteString(attr("xmlns:xmp", xmpNamespace))
+	b.WriteString(attr("xmlns:Iptc4xmpExt", "http://iptc.org/std/Iptc4xmpExt/2008-02-29/"))
+	b.WriteString(attr("xmlns:sft", xmpProvenanceNamespace))
+	b.WriteString(attr("Iptc4xmpExt:DigitalSourceType", iptcTrainedAlgorithmMedia))
+	b.WriteString(attr("xmp:CreatorTool", provenance.Generator))
+	b.WriteString(attr("xmp:CreateDate", provenance.CreatedAt.Format(time.RFC3339)))
+	b.WriteString(attr("sft:aiGenerated", fmt.Sprintf("%t", provenance.AIGenerated)))
+	b.WriteString(attr("sft:model", provenance.Model))
+	b.WriteString(attr("sft:historyId", provenance.HistoryID.String()))
+	b.WriteString("/>\n </rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"r\"?>")
+	return []byte(b.String())
+}
+
+// embedJPEGXMP inserts an APP1 XMP segment after SOI and any APP0 (JFIF) segment
+func embedJPEGXMP(data, packet []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("%w: missing JPEG start of image", ErrUndecodableImage)
+	}
+	payloadSize := len(jpegXMPHeader) + len(packet)
+	if payloadSize+2 > 0xFFFF {
+		return nil, fmt.Errorf("XMP packet of %d bytes does not fit in a JPEG segment", payloadSize)
+	}
+
+	offset := 2
+	if data[2] == 0xFF && data[3] == 0xE0 && len(data) >= 6 {
+		offset = 4 + int(binary.BigEndian.Uint16(data[4:6]))
+		if offset > len(data) {
+			return nil, fmt.Errorf("%w: truncated APP0 segment", ErrUndecodableImage)
+		}
+	}
+
+	segment := make([]byte, 4, 4+payloadSize)
+	segment[0], segment[1] = 0xFF, 0xE1
+	binary.BigEndian.PutUint16(segment[2:], uint16(payloadSize+2))
+	segment = append(segment, jpegXMPHeader...)
+	segment = append(segment, packet...)
+
+	out := make([]byte, 0, len(data)+len(segment))
+	out = append(out, data[:offset]...)
+	out = append(out, segment...)
+	return append(out, data[offset:]...), nil
+}
+
+// pngSignatureSize and pngIHDREnd locate the first chunk after the header
+const (
+	pngSignatureSize = 8
+	pngIHDREnd       = pngSignatureSize + 4 + 4 + 13 + 4
+)
+
+// embedPNGXMP inserts an uncompressed iTXt XMP chunk right after IHDR
+func embedPNGXMP(data, packet []byte) ([]byte, error) {
+	if len(data) < pngIHDREnd || !bytes.Equal(data[:pngSignatureSize], []byte("\x89PNG\r\n\x1a\n")) ||
+		string(data[12:16]) != "IHDR" {
+		return nil, fmt.Errorf("%w: missing PNG header", ErrUndecodableImage)
+	}
+
+	// keyword, null, compression flag and method, empty language tag and
+	// translated keyword, then the text
+	var chunkData bytes.Buffer
+	chunkData.WriteString(pngXMPKeyword)
+	chunkData.Write([]byte{0, 0, 0, 0, 0})
+	chunkData.Write(packet)
+
+	chunk := make([]byte, 8, 12+chunkData.Len())
+	binary.BigEndian.PutUint32(chunk, uint32(chunkData.Len()))
+	copy(chunk[4:], "iTXt")
+	chunk = append(chunk, chunkData.Bytes()...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	out := make([]byte, 0, len(data)+len(chunk))
+	out = append(out, data[:pngIHDREnd]...)
+	out = append(out, chunk...)
+	return append(out, data[pngIHDREnd:]...), nil
+}
+
+// xmpProvenanceAttr matches the attributes written by provenanceXMP
+var xmpProvenanceAttr = regexp.MustCompile(`(Iptc4xmpExt:DigitalSourceType|xmp:CreatorTool|xmp:CreateDate|sft:aiGenerated|sft:model|sft:historyId)="([^"]*)"`)
+
+// ReadProvenance extracts provenance embedded by EmbedProvenance. It reports false
+// when the image carries no XMP packet from this pipeline. The result is only a
+// claim: anyone can write these fields, so it must not be treated as verified.
+func ReadProvenance(data []byte) (*Provenance, bool) {
+	start := bytes.Index(data, []byte("<x:xmpmeta"))
+	if start < 0 {
+		return nil, false
+	}
+	end := bytes.Index(data[start:], []byte("</x:xmpmeta>"))
+	if end < 0 {
+		return nil, false
+	}
+
+	var provenance Provenance
+	found := false
+	for _, match := range xmpProvenanceAttr.FindAllSubmatch(data[start:start+end], -1) {
+		value := html.UnescapeString(string(match[2]))
+		switch string(match[1]) {
+		case "Iptc4xmpExt:DigitalSourceType":
+			if value == iptcTrainedAlgorithmMedia {
+				provenance.AIGenerated = true
+			}
+		case "xmp:CreatorTool":
+			provenance.Generator = value
+			found = found || value == ProvenanceGenerator
+		case "xmp:CreateDate":
+			provenance.CreatedAt, _ = time.Parse(time.RFC3339, value)
+		case "sft:aiGenerated":
+			provenance.AIGenerated = provenance.AIGenerated || value == "true"
+		case "sft:model":
+			provenance.Model = value
+		case "sft:historyId":
+			provenance.HistoryID, _ = uuid.Parse(value)
+		}
+	}
+	if !found {
+		return nil, false
+	}
+	return &provenance, true
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the provider while it is considered down
+var ErrCircuitOpen = errors.New("image generation provider is unavailable")
+
+// maxProviderErrorBody caps how much of a provider error body is kept for logs
+const maxProviderErrorBody = 2048
+
+// ProviderError is a failed call to a hosted image generation provider.
+// StatusCode is zero when no response was received.
+type ProviderError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+	Err        error
+}
+
+// Error includes the full provider response and is meant for logs, not clients
+func (e *ProviderError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("provider request failed: %v", e.Err)
+	}
+	return fmt.Sprintf("provider error: status=%d, body=%s", e.StatusCode, e.Body)
+}
+
+// Unwrap returns the underlying transport error
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether repeating the call may succeed: rate limiting,
+// server errors and transport failures such as connection resets
+func (e *ProviderError) Retryable() bool {
+	switch e.StatusCode {
+	case 0:
+		return true
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// newProviderStatusError builds a ProviderError from a non-200 response and its body
+func newProviderStatusError(resp *http.Response, body []byte) *ProviderError {
+	if len(body) > maxProviderErrorBody {
+		body = body[:maxProviderErrorBody]
+	}
+	return &ProviderError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// IsRetryable reports whether err is a provider error worth retrying
+func IsRetryable(err error) bool {
+	var providerErr *ProviderError
+	return errors.As(err, &providerErr) && providerErr.Retryable()
+}
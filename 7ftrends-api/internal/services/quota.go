@@ -0,0 +1,68 @@
+package services
+
+import "time"
+
+// UnlimitedQuota marks a role without a monthly try-on limit
+const UnlimitedQuota = -1
+
+// defaultQuotaRole is used for roles without their own quota
+const defaultQuotaRole = "user"
+
+// QuotaStatus is a user's try-on usage for the current month
+type QuotaStatus struct {
+	Limit     int
+	Used      int
+	Remaining int
+	Unlimited bool
+	ResetAt   time.Time
+}
+
+// Exceeded reports whether no try-ons remain this month
+func (s QuotaStatus) Exceeded() bool {
+	return !s.Unlimited && s.Remaining <= 0
+}
+
+// MonthlyQuotaLimit returns the monthly limit for a role, falling back to the user quota.
+// With no quotas configured at all, usage is unlimited.
+func MonthlyQuotaLimit(quotas map[string]int, role string) int {
+	if limit, ok := quotas[role]; ok {
+		return limit
+	}
+	if limit, ok := quotas[defaultQuotaRole]; ok {
+		return limit
+	}
+	return UnlimitedQuota
+}
+
+// NewQuotaStatus computes the remaining quota from a limit and this month's usage
+func NewQuotaStatus(limit int, used int64, now time.Time) QuotaStatus {
+	status := QuotaStatus{
+		Limit:   limit,
+		Used:    int(used),
+		ResetAt: NextMonthlyReset(now),
+	}
+
+	if limit < 0 {
+		status.Unlimited = true
+		return status
+	}
+
+	status.Remaining = limit - status.Used
+	if status.Remaining < 0 {
+		status.Remaining = 0
+	}
+	return status
+}
+
+// QuotaMonth returns the start of the calendar month in UTC that usage at now is
+// charged to
+func QuotaMonth(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// NextMonthlyReset returns the start of the next calendar month in UTC
+func NextMonthlyReset(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/7ftrends/api/internal/config"
+)
+
+// RetryPolicy bounds retries of transient provider errors
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// NewRetryPolicy reads the retry settings from the AI configuration
+func NewRetryPolicy(cfg config.AIConfig) RetryPolicy {
+	policy := RetryPolicy{
+		MaxRetries: cfg.MaxRetries,
+		BaseDelay:  time.Duration(cfg.RetryBaseDelay) * time.Millisecond,
+		MaxDelay:   time.Duration(cfg.RetryMaxDelay) * time.Millisecond,
+	}
+	if policy.MaxRetries < 0 {
+		policy.MaxRetries = 0
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = 500 * time.Millisecond
+	}
+	if policy.MaxDelay < policy.BaseDelay {
+		policy.MaxDelay = policy.BaseDelay
+	}
+	return policy
+}
+
+// Backoff returns the delay before retry number attempt (starting at 0): an
+// exponentially growing, capped delay with jitter over its upper half
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 30 {
+		if d := p.BaseDelay << uint(attempt); d > 0 && d < p.MaxDelay {
+			delay = d
+		}
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// ResilientGenerator retries transient failures of another generator and
+// stops calling it while its circuit breaker is open
+type ResilientGenerator struct {
+	next    ImageGenerator
+	policy  RetryPolicy
+	breaker *CircuitBreaker
+}
+
+// NewResilientGenerator wraps a generator with retries and a circuit breaker
+func NewResilientGenerator(next ImageGenerator, policy RetryPolicy, breaker *CircuitBreaker) *ResilientGenerator {
+	return &ResilientGenerator{
+		next:    next,
+		policy:  policy,
+		breaker: breaker,
+	}
+}
+
+// Model returns the wrapped generator's model
+func (g *ResilientGenerator) Model() string {
+	return g.next.Model()
+}
+
+// Available reports whether the provider is currently accepting calls
+func (g *ResilientGenerator) Available() bool {
+	return g.breaker.Ready()
+}
+
+// Generate calls the wrapped generator, retrying retryable errors with backoff.
+// A Retry-After from the provider is honoured when it is longer than the backoff.
+func (g *ResilientGenerator) Generate(ctx context.Context, req GenerateRequest) (*GenerateResult, error) {
+	if err := g.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		result, err := g.next.Generate(ctx, req)
+		if err == nil {
+			g.breaker.Success()
+			return result, nil
+		}
+
+		// The caller gave up; that says nothing about the provider
+		if ctx.Err() != nil {
+			g.breaker.Release()
+			return nil, err
+		}
+
+		if !IsRetryable(err) {
+			// The provider answered, so it is up even though this call failed
+			g.breaker.Success()
+			return nil, err
+		}
+
+		if attempt >= g.policy.MaxRetries {
+			g.breaker.Failure()
+			return nil, err
+		}
+
+		delay := g.policy.Backoff(attempt)
+		if retryAfter := retryAfter(err); retryAfter > delay {
+			delay = retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			g.breaker.Failure()
+			return nil, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			g.breaker.Release()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAfter returns the provider's requested delay for err, if any
+func retryAfter(err error) time.Duration {
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.RetryAfter
+	}
+	return 0
+}
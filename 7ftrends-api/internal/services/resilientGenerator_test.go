@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/7ftrends/api/internal/config"
+)
+
+// scriptedGenerator returns the scripted errors in turn, then succeeds
+type scriptedGenerator struct {
+	errs  []error
+	calls int
+}
+
+func (g *scriptedGenerator) Generate(ctx context.Context, req GenerateRequest) (*GenerateResult, error) {
+	g.calls++
+	if g.calls <= len(g.errs) {
+		return nil, g.errs[g.calls-1]
+	}
+	return &GenerateResult{Image: []byte("image"), MimeType: "image/png"}, nil
+}
+
+func (g *scriptedGenerator) Model() string { return "scripted" }
+
+var testRetryPolicy = RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+func TestResilientGeneratorRetries(t *testing.T) {
+	unavailable := &ProviderError{StatusCode: http.StatusServiceUnavailable}
+	badRequest := &ProviderError{StatusCode: http.StatusBadRequest}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+		wantOpen  bool // the breaker, with a threshold of 1, is open afterwards
+	}{
+		{name: "success", wantCalls: 1},
+		{name: "transient error is retried", errs: []error{unavailable, unavailable}, wantCalls: 3},
+		{name: "transport error is retried", errs: []error{&ProviderError{Err: errors.New("connection reset")}}, wantCalls: 2},
+		{name: "retries are bounded", errs: []error{unavailable, unavailable, unavailable}, wantCalls: 3, wantErr: unavailable, wantOpen: true},
+		{name: "client error is not retried", errs: []error{badRequest}, wantCalls: 1, wantErr: badRequest},
+		{name: "other errors are not retried", errs: []error{ErrMissingAPIKey}, wantCalls: 1, wantErr: ErrMissingAPIKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &scriptedGenerator{errs: tt.errs}
+			breaker := NewCircuitBreaker(1, time.Hour)
+			g := NewResilientGenerator(next, testRetryPolicy, breaker)
+
+			result, err := g.Generate(context.Background(), GenerateRequest{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Generate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && result == nil {
+				t.Error("Generate() returned no result")
+			}
+			if next.calls != tt.wantCalls {
+				t.Errorf("called the provider %d times, want %d", next.calls, tt.wantCalls)
+			}
+			if g.Available() == tt.wantOpen {
+				t.Errorf("Available() = %v, want %v", g.Available(), !tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestResilientGeneratorOpenCircuit(t *testing.T) {
+	next := &scriptedGenerator{}
+	breaker := NewCircuitBreaker(1, time.Hour)
+	breaker.Failure()
+	g := NewResilientGenerator(next, testRetryPolicy, breaker)
+
+	if _, err := g.Generate(context.Background(), GenerateRequest{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Generate() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if next.calls != 0 {
+		t.Errorf("called the provider %d times while the circuit was open", next.calls)
+	}
+}
+
+func TestResilientGeneratorDeadline(t *testing.T) {
+	// The provider asks for more time than the caller has left
+	limited := &ProviderError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}
+	next := &scriptedGenerator{errs: []error{limited}}
+	breaker := NewCircuitBreaker(1, time.Hour)
+	g := NewResilientGenerator(next, testRetryPolicy, breaker)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := g.Generate(ctx, GenerateRequest{}); !errors.Is(err, limited) {
+		t.Fatalf("Generate() error = %v, want %v", err, limited)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Generate() waited %s instead of giving up", elapsed)
+	}
+	if next.calls != 1 {
+		t.Errorf("called the provider %d times, want 1", next.calls)
+	}
+}
+
+func TestResilientGeneratorCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	next := &scriptedGenerator{errs: []error{ctx.Err()}}
+	breaker := NewCircuitBreaker(1, time.Hour)
+	g := NewResilientGenerator(next, testRetryPolicy, breaker)
+
+	if _, err := g.Generate(ctx, GenerateRequest{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Generate() error = %v, want %v", err, context.Canceled)
+	}
+	// The caller gave up, which says nothing about the provider
+	if !g.Available() {
+		t.Error("a cancelled call opened the circuit")
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration // the upper bound; jitter covers its upper half
+	}{
+		{attempt: 0, want: 100 * time.Millisecond},
+		{attempt: 1, want: 200 * time.Millisecond},
+		{attempt: 3, want: 800 * time.Millisecond},
+		{attempt: 4, want: time.Second},
+		{attempt: 100, want: time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := policy.Backoff(tt.attempt); got < tt.want/2 || got > tt.want {
+				t.Fatalf("Backoff(%d) = %s, want between %s and %s", tt.attempt, got, tt.want/2, tt.want)
+			}
+		}
+	}
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	policy := NewRetryPolicy(config.AIConfig{MaxRetries: -1, RetryMaxDelay: 10})
+	want := RetryPolicy{MaxRetries: 0, BaseDelay: 500 * time.Millisecond, MaxDelay: 500 * time.Millisecond}
+	if policy != want {
+		t.Errorf("NewRetryPolicy() = %+v, want %+v", policy, want)
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestShareSignerVerify(t *testing.T) {
+	signer, err := NewShareSigner("test-secret")
+	if err != nil {
+		t.Fatalf("NewShareSigner: %v", err)
+	}
+	other, err := NewShareSigner("other-secret")
+	if err != nil {
+		t.Fatalf("NewShareSigner: %v", err)
+	}
+
+	shareID := uuid.MustParse("4b1c6f2e-8d3a-4f57-9a0e-2c7d5e6f8a91")
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	token := signer.Sign(shareID, now.Add(time.Hour))
+	payload, signature, _ := strings.Cut(token, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		now     time.Time
+		wantErr error
+	}{
+		{name: "valid", token: token, now: now},
+		{name: "just before expiry", token: token, now: now.Add(time.Hour - time.Second)},
+		{name: "at expiry", token: token, now: now.Add(time.Hour), wantErr: ErrShareTokenExpired},
+		{name: "after expiry", token: token, now: now.Add(48 * time.Hour), wantErr: ErrShareTokenExpired},
+		{name: "signed with another secret", token: other.Sign(shareID, now.Add(time.Hour)), now: now, wantErr: ErrInvalidShareToken},
+		{name: "extended expiry", token: signer.Sign(shareID, now.Add(24*time.Hour))[:len(payload)] + "." + signature, now: now, wantErr: ErrInvalidShareToken},
+		{name: "tampered payload", token: flipFirstChar(payload) + "." + signature, now: now, wantErr: ErrInvalidShareToken},
+		{name: "tampered signature", token: payload + "." + flipFirstChar(signature), now: now, wantErr: ErrInvalidShareToken},
+		{name: "missing signature", token: payload, now: now, wantErr: ErrInvalidShareToken},
+		{name: "empty", token: "", now: now, wantErr: ErrInvalidShareToken},
+		{name: "not base64", token: "!!!." + signature, now: now, wantErr: ErrInvalidShareToken},
+		{name: "short payload", token: payload[:10] + "." + signature, now: now, wantErr: ErrInvalidShareToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := signer.Verify(tt.token, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got != shareID {
+				t.Errorf("Verify() = %s, want %s", got, shareID)
+			}
+		})
+	}
+}
+
+func TestNewShareSignerRequiresSecret(t *testing.T) {
+	if _, err := NewShareSigner(""); !errors.Is(err, ErrMissingShareSecret) {
+		t.Fatalf("NewShareSigner(\"\") error = %v, want %v", err, ErrMissingShareSecret)
+	}
+}
+
+// flipFirstChar changes the first character of a base64url string to another
+// valid one. The last character may only carry padding bits, so it is left alone.
+func flipFirstChar(s string) string {
+	replacement := "A"
+	if s[0] == 'A' {
+		replacement = "B"
+	}
+	return replacement + s[1:]
+}
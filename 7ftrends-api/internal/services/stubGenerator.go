@@ -0,0 +1,102 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// defaultStubModel is recorded as the model of stub results. ai.model names the
+// hosted model and is ignored, so stub output is never attributed to a real provider.
+const defaultStubModel = "local-stub"
+
+// StubGenerator is a deterministic, offline image generator for development and demos.
+// It pastes a scaled copy of the first garment onto the centre of the person photo,
+// so the same inputs always produce the same output.
+type StubGenerator struct {
+	model string
+}
+
+// NewStubGenerator creates a stub generator
+func NewStubGenerator() *StubGenerator {
+	return &StubGenerator{model: defaultStubModel}
+}
+
+// Model returns the stub model name
+func (g *StubGenerator) Model() string {
+	return g.model
+}
+
+// Generate composes the inputs locally without calling any external service
+func (g *StubGenerator) Generate(ctx context.Context, req GenerateRequest) (*GenerateResult, error) {
+	if len(req.Images) == 0 {
+		return nil, fmt.Errorf("stub generator requires at least one image")
+	}
+
+	person, _, err := image.Decode(bytes.NewReader(req.Images[0].Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode person image: %v", err)
+	}
+
+	bounds := person.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), person, bounds.Min, draw.Src)
+
+	if len(req.Images) > 1 {
+		garment, _, err := image.Decode(bytes.NewReader(req.Images[1].Data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode garment image: %v", err)
+		}
+		pasteCentered(canvas, garment, bounds.Dx()/3)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, canvas, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, fmt.Errorf("failed to encode stub image: %v", err)
+	}
+
+	report, _ := json.Marshal(map[string]interface{}{
+		"success":             true,
+		"confidence":          0.9,
+		"appliedInstructions": []string{req.Instructions},
+	})
+
+	return &GenerateResult{
+		Image:    out.Bytes(),
+		MimeType: "image/jpeg",
+		Text:     string(report),
+		Model:    g.model,
+	}, nil
+}
+
+// pasteCentered draws src onto dst scaled to the given width with nearest-neighbour sampling
+func pasteCentered(dst draw.Image, src image.Image, width int) {
+	sb := src.Bounds()
+	if width <= 0 || sb.Dx() == 0 || sb.Dy() == 0 {
+		return
+	}
+	height := width * sb.Dy() / sb.Dx()
+
+	db := dst.Bounds()
+	offsetX := db.Min.X + (db.Dx()-width)/2
+	offsetY := db.Min.Y + (db.Dy()-height)/2
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := src.At(sb.Min.X+x*sb.Dx()/width, sb.Min.Y+y*sb.Dy()/height)
+			if _, _, _, a := c.RGBA(); a == 0 {
+				continue
+			}
+			dst.Set(offsetX+x, offsetY+y, c)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"math"
+)
+
+// stubColors are the named colors the stub tagger can report
+var stubColors = []struct {
+	name    string
+	r, g, b float64
+}{
+	{"black", 20, 20, 20},
+	{"white", 240, 240, 240},
+	{"gray", 128, 128, 128},
+	{"red", 200, 30, 40},
+	{"orange", 240, 140, 30},
+	{"yellow", 240, 220, 60},
+	{"green", 50, 140, 60},
+	{"blue", 40, 70, 180},
+	{"navy", 25, 35, 80},
+	{"purple", 120, 60, 150},
+	{"pink", 240, 160, 190},
+	{"brown", 120, 80, 45},
+	{"beige", 220, 200, 160},
+}
+
+// StubTagger is a deterministic, offline tagger for development and demos. It
+// reports the garment's average color and nothing else it cannot know.
+type StubTagger struct {
+	model string
+}
+
+// NewStubTagger creates a stub tagger. Like the stub generator it always reports
+// defaultStubModel, whatever ai.tagging.model says.
+func NewStubTagger() *StubTagger {
+	return &StubTagger{model: defaultStubModel}
+}
+
+// Model returns the stub model name
+func (t *StubTagger) Model() string {
+	return t.model
+}
+
+// Tag names the nearest color to the image's average without calling any external service
+func (t *StubTagger) Tag(ctx context.Context, input InputImage) (*GarmentAttributes, error) {
+	img, _, err := image.Decode(bytes.NewReader(input.Data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUndecodableImage, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Sample a grid rather than every pixel; opaque pixels only
+	bounds := img.Bounds()
+	step := max(1, min(bounds.Dx(), bounds.Dy())/64)
+	var r, g, b, n float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			cr, cg, cb, ca := img.At(x, y).RGBA()
+			if ca < 0x8000 {
+				continue
+			}
+			r, g, b, n = r+float64(cr>>8), g+float64(cg>>8), b+float64(cb>>8), n+1
+		}
+	}
+
+	attributes := &GarmentAttributes{Tags: []string{}, Confidence: 0.3}
+	if n > 0 {
+		attributes.Colors = []string{nearestColor(r/n, g/n, b/n)}
+	}
+	attributes.normalize()
+	return attributes, nil
+}
+
+// nearestColor returns the stub color name closest to an RGB value
+func nearestColor(r, g, b float64) string {
+	best, bestDistance := "", math.MaxFloat64
+	for _, color := range stubColors {
+		distance := (r-color.r)*(r-color.r) + (g-color.g)*(g-color.g) + (b-color.b)*(b-color.b)
+		if distance < bestDistance {
+			best, bestDistance = color.name, distance
+		}
+	}
+	return best
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// tryOnCacheVersion changes whenever the key derivation changes, invalidating old keys
+const tryOnCacheVersion = "tryon-cache-v2"
+
+// TryOnCacheKey hashes the normalized input images and the generation parameters of a
+// try-on. Identical inputs always produce the same key, so a previous completed result
+// can be reused instead of paying for a new generation. settings is the canonical
+// encoding of any output settings, or empty.
+func TryOnCacheKey(instructions, position, fit, style, settings string, images ...[]byte) string {
+	h := sha256.New()
+	writeField := func(data []byte) {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(data)))
+		h.Write(length[:])
+		h.Write(data)
+	}
+
+	writeField([]byte(tryOnCacheVersion))
+	for _, image := range images {
+		sum := sha256.Sum256(image)
+		writeField(sum[:])
+	}
+	for _, field := range []string{instructions, position, fit, style, settings} {
+		writeField([]byte(field))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTryOnReport is returned when the model's JSON report does not match the response schema
+var ErrInvalidTryOnReport = errors.New("invalid try-on report")
+
+// TryOnReport is the JSON report a generator returns alongside the image,
+// matching the response schema sent with each request
+type TryOnReport struct {
+	Success             bool     `json:"success"`
+	Confidence          float64  `json:"confidence"`
+	AppliedInstructions []string `json:"appliedInstructions"`
+}
+
+// ParseTryOnReport parses and validates the text part of a generation result.
+// All schema fields are required and confidence must be within [0, 1].
+func ParseTryOnReport(text string) (*TryOnReport, error) {
+	text = strings.TrimSpace(text)
+	// Some models wrap JSON output in a markdown code fence
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+	if text == "" {
+		return nil, fmt.Errorf("%w: response contained no report", ErrInvalidTryOnReport)
+	}
+
+	var raw struct {
+		Success             *bool     `json:"success"`
+		Confidence          *float64  `json:"confidence"`
+		AppliedInstructions *[]string `json:"appliedInstructions"`
+	}
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTryOnReport, err)
+	}
+
+	switch {
+	case raw.Success == nil:
+		return nil, fmt.Errorf("%w: missing success", ErrInvalidTryOnReport)
+	case raw.Confidence == nil:
+		return nil, fmt.Errorf("%w: missing confidence", ErrInvalidTryOnReport)
+	case raw.AppliedInstructions == nil:
+		return nil, fmt.Errorf("%w: missing appliedInstructions", ErrInvalidTryOnReport)
+	case *raw.Confidence < 0 || *raw.Confidence > 1:
+		return nil, fmt.Errorf("%w: confidence %v is outside [0, 1]", ErrInvalidTryOnReport, *raw.Confidence)
+	}
+
+	return &TryOnReport{
+		Success:             *raw.Success,
+		Confidence:          *raw.Confidence,
+		AppliedInstructions: *raw.AppliedInstructions,
+	}, nil
+}
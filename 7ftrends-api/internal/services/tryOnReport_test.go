@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseTryOnReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    *TryOnReport
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			text: `{"success": true, "confidence": 0.92, "appliedInstructions": ["fit", "lighting"]}`,
+			want: &TryOnReport{Success: true, Confidence: 0.92, AppliedInstructions: []string{"fit", "lighting"}},
+		},
+		{
+			name: "unsuccessful with no instructions",
+			text: `{"success": false, "confidence": 0, "appliedInstructions": []}`,
+			want: &TryOnReport{Success: false, Confidence: 0, AppliedInstructions: []string{}},
+		},
+		{
+			name: "json code fence",
+			text: "```json\n{\"success\": true, \"confidence\": 1, \"appliedInstructions\": []}\n```",
+			want: &TryOnReport{Success: true, Confidence: 1, AppliedInstructions: []string{}},
+		},
+		{
+			name: "plain code fence with whitespace",
+			text: "  ```\n{\"success\": true, \"confidence\": 0.5, \"appliedInstructions\": [\"fit\"]}\n```  ",
+			want: &TryOnReport{Success: true, Confidence: 0.5, AppliedInstructions: []string{"fit"}},
+		},
+		{name: "empty", text: "", wantErr: true},
+		{name: "empty code fence", text: "```json\n```", wantErr: true},
+		{name: "not json", text: "The garment was applied.", wantErr: true},
+		{name: "missing success", text: `{"confidence": 0.9, "appliedInstructions": []}`, wantErr: true},
+		{name: "missing confidence", text: `{"success": true, "appliedInstructions": []}`, wantErr: true},
+		{name: "missing appliedInstructions", text: `{"success": true, "confidence": 0.9}`, wantErr: true},
+		{name: "null appliedInstructions", text: `{"success": true, "confidence": 0.9, "appliedInstructions": null}`, wantErr: true},
+		{name: "confidence above 1", text: `{"success": true, "confidence": 1.2, "appliedInstructions": []}`, wantErr: true},
+		{name: "negative confidence", text: `{"success": true, "confidence": -0.1, "appliedInstructions": []}`, wantErr: true},
+		{name: "wrong type", text: `{"success": "yes", "confidence": 0.9, "appliedInstructions": []}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTryOnReport(tt.text)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTryOnReport) {
+					t.Fatalf("ParseTryOnReport() error = %v, want %v", err, ErrInvalidTryOnReport)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTryOnReport() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTryOnReport() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/7ftrends/api/internal/models"
+)
+
+// Analytics windows. An item counts as in use when it was worn within the wear
+// window; items added within the grace period are not held against the wardrobe yet.
+const (
+	WardrobeWearWindowDays = 90
+	wardrobeGraceDays      = 30
+)
+
+// Analytics list sizes and thresholds
+const (
+	analyticsListSize      = 5
+	analyticsFavoritesSize = 10
+	maxRecommendations     = 5
+	rewearThreshold        = 5  // wears after which an item counts as regularly re-worn
+	highCostPerWear        = 20 // cost per wear worth a recommendation, in the purchase currency
+)
+
+// essentialCategories are recommended when missing from a wardrobe
+var essentialCategories = []string{"top", "bottom", "shoes", "outerwear"}
+
+// conditionWeights rate an item's condition from 0 to 1 for the quality score
+var conditionWeights = map[string]float64{
+	"new":       1,
+	"excellent": 0.9,
+	"good":      0.75,
+	"fair":      0.5,
+	"poor":      0.25,
+}
+
+// WardrobeAnalyticsItem is a wardrobe item with the fields analytics needs that
+// models.WardrobeItem does not carry
+type WardrobeAnalyticsItem struct {
+	models.WardrobeItem
+	QualityScore        int  // 1-10, 0 when not rated
+	SustainabilityScore *int // 1-10, nil when not rated
+	RecentWears         int  // wears logged within the wear window
+}
+
+// ComputeWardrobeStats computes the wardrobe statistics and health scores for items as of now.
+//
+//   - TotalValue is the sum of the purchase prices.
+//   - CostPerWear maps item IDs to purchase price / wear count, counting an unworn item
+//     as worn once, for every item with a purchase price.
+//   - MostWornItems are the worn items with the highest wear count, LeastWornItems the
+//     items past the grace period with the lowest, FavoriteItems the favorites by wear count.
+//
+// See computeWardrobeHealth for the scores.
+func ComputeWardrobeStats(items []WardrobeAnalyticsItem, now time.Time) models.WardrobeStats {
+	stats := models.WardrobeStats{
+		TotalItems:      len(items),
+		ItemsByCategory: make(map[string]int),
+		ItemsByColor:    make(map[string]int),
+		ItemsByBrand:    make(map[string]int),
+		MostWornItems:   []models.WardrobeItem{},
+		LeastWornItems:  []models.WardrobeItem{},
+		FavoriteItems:   []models.WardrobeItem{},
+		RecentAdditions: []models.WardrobeItem{},
+		CostPerWear:     make(map[string]float64),
+	}
+
+	graceCutoff := now.AddDate(0, 0, -wardrobeGraceDays)
+	var worn, settled, favorites []WardrobeAnalyticsItem
+	for _, item := range items {
+		stats.ItemsByCategory[item.Category]++
+		if color := normalizeColor(item.Color); color != "" {
+			stats.ItemsByColor[color]++
+		}
+		if item.Brand != nil && strings.TrimSpace(*item.Brand) != "" {
+			stats.ItemsByBrand[strings.TrimSpace(*item.Brand)]++
+		}
+
+		if item.Price != nil && *item.Price > 0 {
+			stats.TotalValue += *item.Price
+			stats.CostPerWear[item.ID.String()] = roundTo(*item.Price/float64(max(item.WearCount, 1)), 2)
+		}
+
+		if item.WearCount > 0 {
+			worn = append(worn, item)
+		}
+		if item.CreatedAt.Before(graceCutoff) {
+			settled = append(settled, item)
+		}
+		if item.IsFavorite {
+			favorites = append(favorites, item)
+		}
+	}
+	stats.TotalValue = roundTo(stats.TotalValue, 2)
+
+	byWearDesc := func(list []WardrobeAnalyticsItem) func(i, j int) bool {
+		return func(i, j int) bool {
+			if list[i].WearCount != list[j].WearCount {
+				return list[i].WearCount > list[j].WearCount
+			}
+			return lastWornBefore(list[j].LastWorn, list[i].LastWorn)
+		}
+	}
+
+	sort.SliceStable(worn, byWearDesc(worn))
+	stats.MostWornItems = analyticsModels(worn, analyticsListSize)
+
+	sort.SliceStable(settled, func(i, j int) bool {
+		if settled[i].WearCount != settled[j].WearCount {
+			return settled[i].WearCount < settled[j].WearCount
+		}
+		return lastWornBefore(settled[i].LastWorn, settled[j].LastWorn)
+	})
+	stats.LeastWornItems = analyticsModels(settled, analyticsListSize)
+
+	sort.SliceStable(favorites, byWearDesc(favorites))
+	stats.FavoriteItems = analyticsModels(favorites, analyticsFavoritesSize)
+
+	recent := append([]WardrobeAnalyticsItem{}, items...)
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].CreatedAt.After(recent[j].CreatedAt)
+	})
+	stats.RecentAdditions = analyticsModels(recent, analyticsListSize)
+
+	stats.WardrobeHealth = computeWardrobeHealth(items, stats, now)
+	return stats
+}
+
+// computeWardrobeHealth scores the wardrobe from 0 to 100:
+//
+//   - Variety = 100 × (0.6 × categories present / all categories + 0.4 × color spread),
+//     where color spread is the Shannon entropy of the item colors over ln 10, capped at 1,
+//     so ten evenly used colors score fully.
+//   - Utilization = 100 × items worn within the wear window / items, leaving out unworn
+//     items added within the grace period (100 when that leaves no items).
+//   - Quality = 100 × (0.5 × average quality score / 10 + 0.5 × average condition weight),
+//     averaging the quality score over rated items only, or the condition term alone when
+//     no item is rated.
+//   - Sustainability = 100 × (0.5 × average sustainability rating / 10 + 0.5 × share of
+//     items worn at least rewearThreshold times), or the re-wear share alone when no item is rated.
+//   - Overall is the mean of the four.
+func computeWardrobeHealth(items []WardrobeAnalyticsItem, stats models.WardrobeStats, now time.Time) models.WardrobeHealthMetrics {
+	health := models.WardrobeHealthMetrics{Recommendations: []string{}}
+	if len(items) == 0 {
+		health.Recommendations = append(health.Recommendations, "Add items to your wardrobe to see how you use it")
+		return health
+	}
+	n := float64(len(items))
+
+	categorySpread := float64(len(stats.ItemsByCategory)) / float64(len(GarmentCategories))
+	colorSpread := math.Min(1, shannonEntropy(stats.ItemsByColor)/math.Log(10))
+	health.VarietyScore = 100 * (0.6*math.Min(1, categorySpread) + 0.4*colorSpread)
+
+	wearCutoff := now.AddDate(0, 0, -WardrobeWearWindowDays)
+	graceCutoff := now.AddDate(0, 0, -wardrobeGraceDays)
+	var inUse, eligible, unworn, rewornItems, qualityRated, sustainabilityRated int
+	var qualityTotal, conditionTotal, ratingTotal float64
+	for _, item := range items {
+		recentlyWorn := item.RecentWears > 0 || (item.LastWorn != nil && item.LastWorn.After(wearCutoff))
+		switch {
+		case recentlyWorn:
+			inUse++
+			eligible++
+		case item.CreatedAt.Before(graceCutoff):
+			eligible++
+			unworn++
+		}
+		if item.WearCount >= rewearThreshold {
+			rewornItems++
+		}
+
+		if item.QualityScore >= 1 && item.QualityScore <= 10 {
+			qualityRated++
+			qualityTotal += float64(item.QualityScore) / 10
+		}
+		weight, ok := conditionWeights[item.Condition]
+		if !ok {
+			weight = conditionWeights["good"]
+		}
+		conditionTotal += weight
+		if item.SustainabilityScore != nil && *item.SustainabilityScore >= 1 && *item.SustainabilityScore <= 10 {
+			sustainabilityRated++
+			ratingTotal += float64(*item.SustainabilityScore) / 10
+		}
+	}
+
+	health.UtilizationScore = 100
+	if eligible > 0 {
+		health.UtilizationScore = 100 * float64(inUse) / float64(eligible)
+	}
+
+	if qualityRated > 0 {
+		health.QualityScore = 100 * (0.5*qualityTotal/float64(qualityRated) + 0.5*conditionTotal/n)
+	} else {
+		health.QualityScore = 100 * conditionTotal / n
+	}
+
+	rewearShare := float64(rewornItems) / n
+	if sustainabilityRated > 0 {
+		health.SustainabilityScore = 100 * (0.5*ratingTotal/float64(sustainabilityRated) + 0.5*rewearShare)
+	} else {
+		health.SustainabilityScore = 100 * rewearShare
+	}
+
+	health.OverallScore = (health.VarietyScore + health.UtilizationScore + health.QualityScore + health.SustainabilityScore) / 4
+
+	health.VarietyScore = roundTo(health.VarietyScore, 1)
+	health.UtilizationScore = roundTo(health.UtilizationScore, 1)
+	health.QualityScore = roundTo(health.QualityScore, 1)
+	health.SustainabilityScore = roundTo(health.SustainabilityScore, 1)
+	health.OverallScore = roundTo(health.OverallScore, 1)
+
+	health.Recommendations = wardrobeRecommendations(items, stats, unworn, health)
+	return health
+}
+
+// wardrobeRecommendations suggests the most useful improvements first
+func wardrobeRecommendations(items []WardrobeAnalyticsItem, stats models.WardrobeStats, unworn int, health models.WardrobeHealthMetrics) []string {
+	var recommendations []string
+
+	var missing []string
+	for _, category := range essentialCategories {
+		if stats.ItemsByCategory[category] == 0 {
+			missing = append(missing, category)
+		}
+	}
+	if len(missing) > 0 {
+		recommendations = append(recommendations, fmt.Sprintf("Your wardrobe has no %s yet; adding some would open up more outfits", joinWords(missing)))
+	}
+
+	if unworn > 0 && health.UtilizationScore < 60 {
+		recommendations = append(recommendations, fmt.Sprintf("%d items haven't been worn in the last %d days; style them into outfits or consider donating or reselling them", unworn, WardrobeWearWindowDays))
+	}
+
+	if len(items) >= 5 {
+		for color, count := range stats.ItemsByColor {
+			if float64(count)/float64(len(items)) > 0.5 {
+				recommendations = append(recommendations, fmt.Sprintf("Over half of your items are %s; a few pieces in other colors would add variety", color))
+				break
+			}
+		}
+	}
+
+	// The priced item with the highest cost per wear that hasn't been re-worn much yet
+	var costly *WardrobeAnalyticsItem
+	for i := range items {
+		item := &items[i]
+		if item.Price == nil || item.WearCount >= rewearThreshold {
+			continue
+		}
+		if costly == nil || stats.CostPerWear[item.ID.String()] > stats.CostPerWear[costly.ID.String()] {
+			costly = item
+		}
+	}
+	if costly != nil && stats.CostPerWear[costly.ID.String()] >= highCostPerWear {
+		recommendations = append(recommendations, fmt.Sprintf("Wearing your %s more often would bring its cost per wear down from %.2f", costly.Name, stats.CostPerWear[costly.ID.String()]))
+	}
+
+	var needsCare int
+	for _, item := range items {
+		if item.Condition == "fair" || item.Condition == "poor" {
+			needsCare++
+		}
+	}
+	if needsCare > 0 {
+		recommendations = append(recommendations, fmt.Sprintf("%d items are in fair or poor condition; repair or replace the ones you still wear", needsCare))
+	}
+
+	if len(stats.CostPerWear) == 0 {
+		recommendations = append(recommendations, "Add purchase prices to your items to track their value and cost per wear")
+	}
+
+	if len(recommendations) > maxRecommendations {
+		recommendations = recommendations[:maxRecommendations]
+	}
+	return append([]string{}, recommendations...)
+}
+
+// analyticsModels returns the models of the first limit items
+func analyticsModels(items []WardrobeAnalyticsItem, limit int) []models.WardrobeItem {
+	result := make([]models.WardrobeItem, 0, min(len(items), limit))
+	for i := 0; i < len(items) && i < limit; i++ {
+		result = append(result, items[i].WardrobeItem)
+	}
+	return result
+}
+
+// lastWornBefore orders never-worn items first, then by last wear
+func lastWornBefore(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b != nil
+	}
+	return a.Before(*b)
+}
+
+// shannonEntropy is the entropy in nats of the distribution given by counts
+func shannonEntropy(counts map[string]int) float64 {
+	var total int
+	for _, count := range counts {
+		total += count
+	}
+	var entropy float64
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log(p)
+	}
+	return entropy
+}
+
+// normalizeColor groups colors that differ only in case or spacing
+func normalizeColor(color string) string {
+	return strings.ToLower(strings.TrimSpace(color))
+}
+
+// joinWords joins words as "a, b or c"
+func joinWords(words []string) string {
+	if len(words) == 1 {
+		return words[0]
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " or " + words[len(words)-1]
+}
+
+// roundTo rounds value to the given number of decimals
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/7ftrends/api/internal/models"
+	"github.com/google/uuid"
+)
+
+var analyticsNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// analyticsItem is a wardrobe item added the given number of days before analyticsNow
+func analyticsItem(category, color, condition string, addedDaysAgo int) WardrobeAnalyticsItem {
+	item := WardrobeAnalyticsItem{}
+	item.ID = uuid.New()
+	item.Name = category
+	item.Category = category
+	item.Color = color
+	item.Condition = condition
+	item.CreatedAt = analyticsNow.AddDate(0, 0, -addedDaysAgo)
+	return item
+}
+
+func wornDaysAgo(item WardrobeAnalyticsItem, days, wearCount int) WardrobeAnalyticsItem {
+	lastWorn := analyticsNow.AddDate(0, 0, -days)
+	item.LastWorn = &lastWorn
+	item.WearCount = wearCount
+	return item
+}
+
+func rated(item WardrobeAnalyticsItem, quality int, sustainability *int) WardrobeAnalyticsItem {
+	item.QualityScore = quality
+	item.SustainabilityScore = sustainability
+	return item
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestComputeWardrobeHealth(t *testing.T) {
+	// Ten colors used evenly and every category present
+	categories := append(append([]string{}, GarmentCategories...), GarmentCategories[:3]...)
+	var varied []WardrobeAnalyticsItem
+	for i, category := range categories {
+		varied = append(varied, analyticsItem(category, fmt.Sprintf("color-%d", i), "good", 5))
+	}
+
+	tests := []struct {
+		name  string
+		items []WardrobeAnalyticsItem
+		want  models.WardrobeHealthMetrics
+	}{
+		{
+			name: "single unrated item",
+			items: []WardrobeAnalyticsItem{
+				analyticsItem("top", "Black", "good", 5),
+			},
+			// Variety 0.6 × 1/7; new items leave nothing to utilize; condition alone for quality
+			want: models.WardrobeHealthMetrics{VarietyScore: 8.6, UtilizationScore: 100, QualityScore: 75, SustainabilityScore: 0},
+		},
+		{
+			name:  "full variety",
+			items: varied,
+			want:  models.WardrobeHealthMetrics{VarietyScore: 100, UtilizationScore: 100, QualityScore: 75, SustainabilityScore: 0},
+		},
+		{
+			name: "colors differing only in case count once",
+			items: []WardrobeAnalyticsItem{
+				analyticsItem("top", "Black", "good", 5),
+				analyticsItem("bottom", " black ", "good", 5),
+			},
+			want: models.WardrobeHealthMetrics{VarietyScore: 17.1, UtilizationScore: 100, QualityScore: 75, SustainabilityScore: 0},
+		},
+		{
+			name: "utilization skips items in the grace period",
+			items: []WardrobeAnalyticsItem{
+				wornDaysAgo(analyticsItem("top", "black", "good", 200), 10, 1),
+				wornDaysAgo(analyticsItem("bottom", "black", "good", 200), 120, 1),
+				analyticsItem("shoes", "black", "good", 200),
+				analyticsItem("dress", "black", "good", 10),
+			},
+			want: models.WardrobeHealthMetrics{VarietyScore: 34.3, UtilizationScore: 33.3, QualityScore: 75, SustainabilityScore: 0},
+		},
+		{
+			name: "logged wears count as recent use",
+			items: []WardrobeAnalyticsItem{
+				func() WardrobeAnalyticsItem {
+					item := wornDaysAgo(analyticsItem("top", "black", "good", 200), 120, 3)
+					item.RecentWears = 1
+					return item
+				}(),
+			},
+			want: models.WardrobeHealthMetrics{VarietyScore: 8.6, UtilizationScore: 100, QualityScore: 75, SustainabilityScore: 0},
+		},
+		{
+			name: "quality averages over rated items only",
+			items: []WardrobeAnalyticsItem{
+				rated(analyticsItem("top", "black", "new", 5), 8, nil),
+				analyticsItem("bottom", "black", "new", 5),
+			},
+			// 0.5 × 8/10 + 0.5 × 1
+			want: models.WardrobeHealthMetrics{VarietyScore: 17.1, UtilizationScore: 100, QualityScore: 90, SustainabilityScore: 0},
+		},
+		{
+			name: "unknown condition counts as good",
+			items: []WardrobeAnalyticsItem{
+				analyticsItem("top", "black", "fair", 5),
+				analyticsItem("bottom", "black", "vintage", 5),
+			},
+			// (0.5 + 0.75) / 2
+			want: models.WardrobeHealthMetrics{VarietyScore: 17.1, UtilizationScore: 100, QualityScore: 62.5, SustainabilityScore: 0},
+		},
+		{
+			name: "sustainability combines ratings and re-wear",
+			items: []WardrobeAnalyticsItem{
+				rated(wornDaysAgo(analyticsItem("top", "black", "good", 200), 1, 12), 0, intPtr(6)),
+				wornDaysAgo(analyticsItem("bottom", "black", "good", 200), 1, 2),
+			},
+			// 0.5 × 6/10 + 0.5 × 1/2
+			want: models.WardrobeHealthMetrics{VarietyScore: 17.1, UtilizationScore: 100, QualityScore: 75, SustainabilityScore: 55},
+		},
+		{
+			name: "sustainability without ratings is the re-wear share",
+			items: []WardrobeAnalyticsItem{
+				wornDaysAgo(analyticsItem("top", "black", "good", 200), 1, 5),
+				wornDaysAgo(analyticsItem("bottom", "black", "good", 200), 1, 4),
+				rated(wornDaysAgo(analyticsItem("shoes", "black", "good", 200), 1, 6), 0, intPtr(0)),
+				wornDaysAgo(analyticsItem("dress", "black", "good", 200), 1, 0),
+			},
+			want: models.WardrobeHealthMetrics{VarietyScore: 34.3, UtilizationScore: 100, QualityScore: 75, SustainabilityScore: 50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComputeWardrobeStats(tt.items, analyticsNow).WardrobeHealth
+
+			scores := []struct {
+				name      string
+				got, want float64
+			}{
+				{"variety", got.VarietyScore, tt.want.VarietyScore},
+				{"utilization", got.UtilizationScore, tt.want.UtilizationScore},
+				{"quality", got.QualityScore, tt.want.QualityScore},
+				{"sustainability", got.SustainabilityScore, tt.want.SustainabilityScore},
+			}
+			var sum float64
+			for _, score := range scores {
+				if math.Abs(score.got-score.want) > 0.05 {
+					t.Errorf("%s score = %v, want %v", score.name, score.got, score.want)
+				}
+				sum += score.want
+			}
+			if overall := sum / 4; math.Abs(got.OverallScore-overall) > 0.1 {
+				t.Errorf("overall score = %v, want %v", got.OverallScore, overall)
+			}
+			if len(got.Recommendations) == 0 || len(got.Recommendations) > maxRecommendations {
+				t.Errorf("got %d recommendations, want 1 to %d", len(got.Recommendations), maxRecommendations)
+			}
+		})
+	}
+}
+
+func TestComputeWardrobeStatsEmpty(t *testing.T) {
+	stats := ComputeWardrobeStats(nil, analyticsNow)
+	if stats.TotalItems != 0 || stats.TotalValue != 0 || stats.WardrobeHealth.OverallScore != 0 {
+		t.Errorf("stats = %+v, want zero totals and scores", stats)
+	}
+	if len(stats.WardrobeHealth.Recommendations) != 1 {
+		t.Errorf("recommendations = %v, want the add-items hint", stats.WardrobeHealth.Recommendations)
+	}
+}
+
+func TestComputeWardrobeStatsCostPerWear(t *testing.T) {
+	price := func(item WardrobeAnalyticsItem, price float64) WardrobeAnalyticsItem {
+		item.Price = &price
+		return item
+	}
+
+	worn := price(wornDaysAgo(analyticsItem("top", "black", "good", 100), 1, 4), 100)
+	unworn := price(analyticsItem("bottom", "black", "good", 100), 59.99)
+	free := price(analyticsItem("shoes", "black", "good", 100), 0)
+	unpriced := analyticsItem("dress", "black", "good", 100)
+
+	stats := ComputeWardrobeStats([]WardrobeAnalyticsItem{worn, unworn, free, unpriced}, analyticsNow)
+
+	if stats.TotalValue != 159.99 {
+		t.Errorf("TotalValue = %v, want 159.99", stats.TotalValue)
+	}
+
+	tests := []struct {
+		name   string
+		item   WardrobeAnalyticsItem
+		want   float64
+		priced bool
+	}{
+		{name: "divided by wears", item: worn, want: 25, priced: true},
+		{name: "unworn counts as one wear", item: unworn, want: 59.99, priced: true},
+		{name: "free item", item: free},
+		{name: "no price", item: unpriced},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := stats.CostPerWear[tt.item.ID.String()]
+			if ok != tt.priced || got != tt.want {
+				t.Errorf("CostPerWear = %v (present %v), want %v (present %v)", got, ok, tt.want, tt.priced)
+			}
+		})
+	}
+}
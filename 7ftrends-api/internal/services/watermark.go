@@ -0,0 +1,73 @@
+package services
+
+import (
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultWatermarkText labels composites when no watermark text is configured
+const DefaultWatermarkText = "AI generated"
+
+// Watermark label layout, relative to the image's shorter side
+const (
+	watermarkHeightPercent = 4
+	watermarkMarginPercent = 2
+	watermarkPadding       = 3 // pixels around the text at the font's native size
+)
+
+// WatermarkImage stamps a visible label in the bottom-right corner of an image and
+// re-encodes it like NormalizeImage
+func WatermarkImage(data []byte, text string, quality int) (*ProcessedImage, error) {
+	decoded, err := DecodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+	if text == "" {
+		text = DefaultWatermarkText
+	}
+
+	img := toNRGBA(decoded.Image)
+	stampWatermark(img, text)
+	return encodeImage(img, quality)
+}
+
+// stampWatermark draws white text on a translucent dark band. The label is rendered
+// with a fixed bitmap font and scaled to the image so it stays legible at any size.
+func stampWatermark(img *image.NRGBA, text string) {
+	face := basicfont.Face7x13
+	label := image.NewNRGBA(image.Rect(0, 0,
+		font.MeasureString(face, text).Ceil()+2*watermarkPadding,
+		face.Height+2*watermarkPadding,
+	))
+	draw.Draw(label, label.Bounds(), image.NewUniform(color.NRGBA{A: 140}), image.Point{}, draw.Src)
+	drawer := font.Drawer{
+		Dst:  label,
+		Src:  image.NewUniform(color.NRGBA{R: 255, G: 255, B: 255, A: 230}),
+		Face: face,
+		Dot:  fixed.P(watermarkPadding, watermarkPadding+face.Ascent),
+	}
+	drawer.DrawString(text)
+
+	bounds := img.Bounds()
+	shortSide := min(bounds.Dx(), bounds.Dy())
+	margin := shortSide * watermarkMarginPercent / 100
+
+	// Scale to the target height, but never wider than the image allows
+	scale := max(1, float64(shortSide*watermarkHeightPercent/100)/float64(label.Bounds().Dy()))
+	if maxWidth := bounds.Dx() - 2*margin; float64(label.Bounds().Dx())*scale > float64(maxWidth) {
+		scale = float64(maxWidth) / float64(label.Bounds().Dx())
+	}
+	width := int(float64(label.Bounds().Dx()) * scale)
+	height := int(float64(label.Bounds().Dy()) * scale)
+	if width < 1 || height < 1 {
+		return
+	}
+
+	dst := image.Rect(bounds.Max.X-margin-width, bounds.Max.Y-margin-height, bounds.Max.X-margin, bounds.Max.Y-margin)
+	draw.ApproxBiLinear.Scale(img, dst, label, label.Bounds(), draw.Over, nil)
+}
@@ -27,6 +27,7 @@ var (
 	ErrInvalidInput       = errors.New("invalid input")
 	ErrMissingRequired    = errors.New("missing required field")
 	ErrInvalidFormat      = errors.New("invalid format")
+	ErrQuotaExceeded      = errors.New("quota exceeded")
 )
 
 // APIError represents an API error with additional context